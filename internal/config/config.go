@@ -4,9 +4,14 @@ import (
 	"fmt"
 	"gin-admin/pkg/components/jwt"
 	"gin-admin/pkg/components/logger"
+	"gin-admin/pkg/components/mailer"
+	"gin-admin/pkg/components/oidc"
 	"gin-admin/pkg/components/orm"
+	"gin-admin/pkg/components/password"
 	"gin-admin/pkg/components/redis"
+	"gin-admin/pkg/components/tenant"
 	"gin-admin/pkg/components/uploader"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -24,11 +29,22 @@ type AppConfig struct {
 	Database *orm.Config      `mapstructure:"database" validate:"omitempty"`
 	Cache    *redis.Config    `mapstructure:"cache" validate:"omitempty"`
 	Upload   *uploader.Config `mapstructure:"upload" validate:"omitempty"`
+	Mail     *mailer.Config   `mapstructure:"mail" validate:"omitempty"`
+	SSO      *SSOConfig       `mapstructure:"sso" validate:"omitempty"`
+	Security SecurityConfig   `mapstructure:"security" validate:"omitempty"`
+	Tenant   tenant.Config    `mapstructure:"tenant" validate:"omitempty"`
 }
 
 func (a AppConfig) validate() error {
 	validate := validator.New()
-	return validate.Struct(a)
+	if err := validate.Struct(a); err != nil {
+		return err
+	}
+	// 验证链接与 JWT 使用各自的密钥，泄露其一不影响另一个
+	if ev := a.Security.EmailVerification; ev.Secret != "" && a.Jwt != nil && ev.Secret == a.Jwt.Secret {
+		return fmt.Errorf("security.email_verification.secret must differ from jwt.secret")
+	}
+	return nil
 }
 
 // App 应用基本信息
@@ -87,6 +103,118 @@ type AdminRoleConfig struct {
 	Description string `mapstructure:"description" validate:"omitempty"`
 }
 
+// SecurityConfig 账号安全配置，未配置的项使用默认值
+type SecurityConfig struct {
+	// 登录防暴力破解
+	LoginGuard LoginGuardConfig `mapstructure:"login_guard" validate:"omitempty"`
+	// 两步验证
+	MFA MFAConfig `mapstructure:"mfa" validate:"omitempty"`
+	// 找回密码
+	PasswordReset PasswordResetConfig `mapstructure:"password_reset" validate:"omitempty"`
+	// 密码哈希算法及参数
+	PasswordHash password.Config `mapstructure:"password_hash" validate:"omitempty"`
+	// 密码策略
+	PasswordPolicy password.PolicyConfig `mapstructure:"password_policy" validate:"omitempty"`
+	// 模拟登录
+	Impersonation ImpersonationConfig `mapstructure:"impersonation" validate:"omitempty"`
+	// 注册邮箱验证
+	EmailVerification EmailVerificationConfig `mapstructure:"email_verification" validate:"omitempty"`
+	// 刷新令牌 Cookie 及 CSRF 防护
+	Cookie CookieConfig `mapstructure:"cookie" validate:"omitempty"`
+	// 可调用令牌内省 / 撤销接口的客户端（网关、其他服务）
+	TokenClients []TokenClientConfig `mapstructure:"token_clients" validate:"omitempty,dive"`
+}
+
+// LoginGuardConfig 登录失败限制配置
+type LoginGuardConfig struct {
+	MaxAccountFailures int           `mapstructure:"max_account_failures" validate:"omitempty,min=1"` // 同一账号连续失败多少次后临时锁定，默认 5
+	MaxIPFailures      int           `mapstructure:"max_ip_failures" validate:"omitempty,min=1"`      // 同一 IP 在统计窗口内失败多少次后拒绝登录，默认 20
+	FailureWindow      time.Duration `mapstructure:"failure_window" validate:"omitempty,min=1m"`      // 失败次数统计窗口，默认 15m
+	LockDuration       time.Duration `mapstructure:"lock_duration" validate:"omitempty,min=1m"`       // 账号临时锁定时长，默认 30m
+	DelayAfter         int           `mapstructure:"delay_after" validate:"omitempty,min=1"`          // 连续失败多少次后开始要求等待，默认 3
+	BaseDelay          time.Duration `mapstructure:"base_delay" validate:"omitempty"`                 // 首次等待时长，之后每次失败翻倍，默认 1s
+	MaxDelay           time.Duration `mapstructure:"max_delay" validate:"omitempty"`                  // 最长等待时长，默认 30s
+}
+
+// MFAConfig 两步验证配置
+type MFAConfig struct {
+	Issuer        string        `mapstructure:"issuer"`                                    // 验证器中显示的发行方名称，默认使用 jwt.issuer
+	ChallengeTTL  time.Duration `mapstructure:"challenge_ttl" validate:"omitempty,min=1m"` // 密码验证通过后完成两步验证的时限，默认 5m
+	MaxAttempts   int           `mapstructure:"max_attempts" validate:"omitempty,min=1"`   // 每次登录允许输错验证码的次数，默认 5
+	RecoveryCodes int           `mapstructure:"recovery_codes" validate:"omitempty,min=1"` // 生成的恢复码数量，默认 10
+	SecretKey     string        `mapstructure:"secret_key" validate:"omitempty,min=32"`    // TOTP 密钥的加密密钥，dev 以外的环境必须配置
+}
+
+// PasswordResetConfig 找回密码配置
+type PasswordResetConfig struct {
+	TokenTTL time.Duration `mapstructure:"token_ttl" validate:"omitempty,min=5m"` // 重置链接有效期，默认 30m
+	Cooldown time.Duration `mapstructure:"cooldown" validate:"omitempty"`         // 同一账号两次发送重置邮件的最小间隔，默认 1m
+	// 前端重置密码页面地址，邮件中的链接为 ResetURL?token=xxx；未配置时邮件中只包含重置令牌
+	ResetURL string `mapstructure:"reset_url" validate:"omitempty,url"`
+}
+
+// ImpersonationConfig 模拟登录配置
+type ImpersonationConfig struct {
+	TTL time.Duration `mapstructure:"ttl" validate:"omitempty,min=1m"` // 模拟会话的绝对有效期，到期后不能刷新，默认 30m
+}
+
+// EmailVerificationConfig 注册邮箱验证配置
+type EmailVerificationConfig struct {
+	Enabled bool `mapstructure:"enabled"` // 开启后注册的用户为待验证状态，验证邮箱后才能登录
+	// 验证链接的签名密钥，开启时必须配置，不能与 jwt.secret 相同
+	Secret     string        `mapstructure:"secret" validate:"required_if=Enabled true,omitempty,min=32"`
+	TokenTTL   time.Duration `mapstructure:"token_ttl" validate:"omitempty,min=10m"` // 验证链接有效期，默认 24h
+	Cooldown   time.Duration `mapstructure:"cooldown" validate:"omitempty"`          // 同一邮箱两次发送验证邮件的最小间隔，默认 1m
+	DailyLimit int           `mapstructure:"daily_limit" validate:"omitempty,min=1"` // 同一邮箱每天最多发送次数，默认 5
+	VerifyURL  string        `mapstructure:"verify_url" validate:"omitempty,url"`    // 前端验证页面地址，邮件中的链接为 VerifyURL?token=xxx；未配置时邮件中只包含验证令牌
+}
+
+// CookieConfig 刷新令牌 Cookie 配置
+// 通过 Cookie 刷新令牌时需要在 X-CSRF-Token 请求头中回传同名 Cookie 的值（双重提交），防止跨站请求伪造
+type CookieConfig struct {
+	Secure   bool   `mapstructure:"secure" validate:"required_if=SameSite none"`          // 只在 HTTPS 下发送，SameSite=none 时必须开启
+	SameSite string `mapstructure:"same_site" validate:"omitempty,oneof=lax strict none"` // lax | strict | none，默认 lax
+	Domain   string `mapstructure:"domain"`                                               // 默认为当前域名
+	Path     string `mapstructure:"path"`                                                 // 默认 /
+	// 关闭 CSRF 校验，仅用于不依赖浏览器 Cookie 的客户端
+	DisableCSRF bool `mapstructure:"disable_csrf"`
+	// 关闭 JWT 中间件在 access token 过期时自动刷新，关闭后需由前端调用 POST /users/refresh
+	DisableImplicitRefresh bool `mapstructure:"disable_implicit_refresh"`
+}
+
+// SameSiteMode 转换为 http.SameSite，默认 Lax
+func (c CookieConfig) SameSiteMode() http.SameSite {
+	switch c.SameSite {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+// CookiePath Cookie 路径，默认 /
+func (c CookieConfig) CookiePath() string {
+	if c.Path == "" {
+		return "/"
+	}
+	return c.Path
+}
+
+// TokenClientConfig 令牌内省 / 撤销接口的客户端凭证
+type TokenClientConfig struct {
+	ClientID     string `mapstructure:"client_id" validate:"required"`
+	ClientSecret string `mapstructure:"client_secret" validate:"required,min=16"`
+}
+
+// SSOConfig 第三方登录（OIDC / OAuth2）配置
+type SSOConfig struct {
+	// StateTTL 跳转到提供方登录后返回的时限，默认 10m
+	StateTTL  time.Duration         `mapstructure:"state_ttl" validate:"omitempty,min=1m"`
+	Providers []oidc.ProviderConfig `mapstructure:"providers" validate:"omitempty,dive"`
+}
+
 // Init 初始化配置
 func Init() (*AppConfig, error) {
 	// 初始化Viper
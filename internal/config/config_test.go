@@ -3,6 +3,7 @@ package config
 import (
 	"testing"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -81,3 +82,24 @@ func TestAdminRoleConfig(t *testing.T) {
 	assert.Equal(t, "System Administrator", adminRole.Name)
 	assert.Equal(t, "Full system access", adminRole.Description)
 }
+
+func TestEmailVerificationSecret(t *testing.T) {
+	validate := validator.New()
+	secret := "email-verify-secret-32-chars-min"
+	tests := []struct {
+		name    string
+		cfg     EmailVerificationConfig
+		wantErr bool
+	}{
+		{"未开启时可不配置", EmailVerificationConfig{}, false},
+		{"开启时必须配置", EmailVerificationConfig{Enabled: true}, true},
+		{"密钥过短", EmailVerificationConfig{Enabled: true, Secret: "short"}, true},
+		{"配置完整", EmailVerificationConfig{Enabled: true, Secret: secret}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate.Struct(tt.cfg)
+			assert.Equal(t, tt.wantErr, err != nil, "err: %v", err)
+		})
+	}
+}
@@ -16,28 +16,67 @@ func RegisterRBACRoutes(ctx *services.ServiceContext, api *routegroup.RouterGrou
 		// 公共接口（不需要权限，也不需要登录的jwt）
 		userGroup.Public().POST("/register", rbac.Register(ctx))
 		userGroup.Public().POST("/login", rbac.Login(ctx))
+		userGroup.Public().POST("/login/mfa", rbac.LoginMFA(ctx))
+		userGroup.Public().POST("/login/mfa/setup", rbac.LoginMFASetup(ctx))
+		userGroup.Public().POST("/refresh", rbac.RefreshToken(ctx))
+		userGroup.Public().POST("/email/verify", rbac.VerifyEmail(ctx))
+		userGroup.Public().POST("/email/resend", rbac.ResendVerificationEmail(ctx))
+		userGroup.Public().POST("/password/forgot", rbac.ForgotPassword(ctx))
+		userGroup.Public().POST("/password/reset", rbac.ResetPassword(ctx))
+		userGroup.Public().POST("/password/expired", rbac.ChangeExpiredPassword(ctx))
+		userGroup.Public().GET("/password/policy", rbac.GetPasswordPolicy(ctx))
+		userGroup.Public().GET("/oauth/providers", rbac.ListOAuthProviders(ctx))
+		userGroup.Public().GET("/oauth/:provider/login", rbac.OAuthRedirect(ctx))
+		userGroup.Public().GET("/oauth/:provider/callback", rbac.OAuthCallback(ctx))
 		authGroup := userGroup.Group("")
 		authGroup.Use(middleware.JWT(ctx))
 		{
 			// 需要登录但是不需要权限控制
 			authGroup.POST("/logout", rbac.Logout(ctx))
 			authGroup.GET("/options", rbac.UserOptions(ctx))
+			// 我的菜单，按权限过滤
+			authGroup.GET("/menus", rbac.GetMyMenus(ctx))
+			// 我的账号，模拟登录的会话不能修改密码和邮箱
+			authGroup.PUT("/password", middleware.ForbidImpersonation(), rbac.ChangeMyPassword(ctx))
+			authGroup.PUT("/profile", middleware.ForbidImpersonation(), rbac.UpdateMyProfile(ctx))
+			authGroup.POST("/avatar", rbac.UploadMyAvatar(ctx))
+			// 我的在线设备
+			authGroup.GET("/sessions", rbac.ListMySessions(ctx))
+			authGroup.DELETE("/sessions/:sid", rbac.RevokeMySession(ctx))
+			// 我的两步验证
+			authGroup.GET("/mfa", rbac.GetMFAStatus(ctx))
+			// 模拟登录的会话不能修改被模拟用户的安全设置
+			authGroup.POST("/mfa/setup", middleware.ForbidImpersonation(), rbac.SetupMFA(ctx))
+			authGroup.POST("/mfa/enable", middleware.ForbidImpersonation(), rbac.EnableMFA(ctx))
+			authGroup.POST("/mfa/disable", middleware.ForbidImpersonation(), rbac.DisableMFA(ctx))
+			authGroup.POST("/mfa/recovery-codes", middleware.ForbidImpersonation(), rbac.RegenerateMFARecoveryCodes(ctx))
+			// 我的 API Key（只能通过登录态管理，API Key 不能再创建 API Key）
+			authGroup.GET("/api-keys", rbac.ListMyAPIKeys(ctx))
+			authGroup.POST("/api-keys", middleware.ForbidImpersonation(), rbac.CreateMyAPIKey(ctx))
+			authGroup.DELETE("/api-keys/:id", middleware.ForbidImpersonation(), rbac.RevokeMyAPIKey(ctx))
 		}
-		// 需要认证和权限 - 声明权限组
+		// 需要认证和权限 - 声明权限组，支持 JWT 和 API Key
 		authUserGroup := userGroup.WithMeta("user:manage", "用户管理")
-		authUserGroup.Use(middleware.JWT(ctx), middleware.PermissionMiddleware(ctx))
+		authUserGroup.Use(middleware.Authenticate(ctx), middleware.PermissionMiddleware(ctx))
 		{
 			authUserGroup.GET("/profile", rbac.GetProfile(ctx)).WithMeta("profile", "查询当前用户信息")
 			authUserGroup.GET("", rbac.ListUser(ctx)).WithMeta("list", "查询用户列表")
 			authUserGroup.POST("", rbac.CreateUser(ctx)).WithMeta("add", "创建用户")
 			authUserGroup.PUT("/:id", rbac.UpdateUser(ctx)).WithMeta("update", "编辑用户")
 			authUserGroup.DELETE("/:id", rbac.DeleteUser(ctx)).WithMeta("delete", "删除用户")
+			authUserGroup.PUT("/:id/unlock", rbac.UnlockUser(ctx)).WithMeta("unlock", "解锁用户")
+			authUserGroup.DELETE("/:id/mfa", rbac.ResetUserMFA(ctx)).WithMeta("reset-mfa", "重置用户两步验证")
+			authUserGroup.GET("/:id/sessions", rbac.ListUserSessions(ctx)).WithMeta("sessions", "查询用户在线设备")
+			authUserGroup.DELETE("/:id/sessions/:sid", rbac.KickUserSession(ctx)).WithMeta("kick", "踢下线用户设备")
+			authUserGroup.DELETE("/:id/sessions", rbac.KickUserAllSessions(ctx)).WithMeta("kick-all", "踢下线用户所有设备")
+			// 专用权限 user:manage:impersonate，模拟会话不能再次模拟
+			authUserGroup.POST("/:id/impersonate", middleware.ForbidImpersonation(), rbac.Impersonate(ctx)).WithMeta("impersonate", "模拟登录用户")
 		}
 	}
 
 	// 角色模块 - 声明权限组
 	roleGroup := api.Group("/roles").WithMeta("role:manage", "角色管理")
-	roleGroup.Use(middleware.JWT(ctx), middleware.PermissionMiddleware(ctx))
+	roleGroup.Use(middleware.Authenticate(ctx), middleware.PermissionMiddleware(ctx))
 	{
 		roleGroup.GET("", rbac.GetRoles(ctx)).WithMeta("list", "查询角色列表")
 		roleGroup.POST("", rbac.CreateRole(ctx)).WithMeta("add", "创建角色")
@@ -45,11 +84,54 @@ func RegisterRBACRoutes(ctx *services.ServiceContext, api *routegroup.RouterGrou
 		roleGroup.PUT("/:id", rbac.UpdateRole(ctx)).WithMeta("update", "编辑角色")
 		roleGroup.DELETE("/:id", rbac.DeleteRole(ctx)).WithMeta("delete", "删除角色")
 		roleGroup.PUT("/:id/assign-resource", rbac.AssignRoleResources(ctx)).WithMeta("assign-perm", "绑定资源权限")
+		roleGroup.PUT("/:id/assign-parent", rbac.AssignRoleParents(ctx)).WithMeta("assign-parent", "设置上级角色")
+		roleGroup.PUT("/:id/assign-deny", rbac.AssignRoleDenies(ctx)).WithMeta("assign-deny", "设置禁止访问的资源")
+	}
+
+	// 菜单模块 - 声明权限组，菜单由所有租户共用，开启多租户后只有平台超级管理员可以修改
+	menuGroup := api.Group("/menus").WithMeta("menu:manage", "菜单管理")
+	menuGroup.Use(middleware.Authenticate(ctx), middleware.PermissionMiddleware(ctx))
+	{
+		menuGroup.GET("/tree", rbac.GetMenuTree(ctx)).WithMeta("tree", "查询菜单树")
+		menuGroup.POST("", middleware.PlatformManaged(ctx), rbac.CreateMenu(ctx)).WithMeta("add", "创建菜单")
+		menuGroup.GET("/:id", rbac.GetMenu(ctx)).WithMeta("detail", "查询菜单详情")
+		menuGroup.PUT("/:id", middleware.PlatformManaged(ctx), rbac.UpdateMenu(ctx)).WithMeta("update", "编辑菜单")
+		menuGroup.DELETE("/:id", middleware.PlatformManaged(ctx), rbac.DeleteMenu(ctx)).WithMeta("delete", "删除菜单")
+	}
+
+	// 部门模块 - 声明权限组
+	deptGroup := api.Group("/depts").WithMeta("dept:manage", "部门管理")
+	deptGroup.Use(middleware.Authenticate(ctx), middleware.PermissionMiddleware(ctx))
+	{
+		deptGroup.GET("/tree", rbac.GetDeptTree(ctx)).WithMeta("tree", "查询部门树")
+		deptGroup.GET("", rbac.GetDepts(ctx)).WithMeta("list", "查询部门列表")
+		deptGroup.POST("", rbac.CreateDept(ctx)).WithMeta("add", "创建部门")
+		deptGroup.GET("/:id", rbac.GetDept(ctx)).WithMeta("detail", "查询部门详情")
+		deptGroup.PUT("/:id", rbac.UpdateDept(ctx)).WithMeta("update", "编辑部门")
+		deptGroup.DELETE("/:id", rbac.DeleteDept(ctx)).WithMeta("delete", "删除部门")
+		deptGroup.PUT("/:id/move", rbac.MoveDept(ctx)).WithMeta("move", "移动部门")
+	}
+
+	// 租户管理 - 只有平台超级管理员可以访问
+	tenantGroup := api.Group("/tenants").WithMeta("tenant:manage", "租户管理")
+	tenantGroup.Use(middleware.Authenticate(ctx), middleware.PlatformOnly(ctx), middleware.PermissionMiddleware(ctx))
+	{
+		tenantGroup.GET("", rbac.GetTenants(ctx)).WithMeta("list", "查询租户列表")
+		tenantGroup.POST("", rbac.CreateTenant(ctx)).WithMeta("add", "创建租户")
+		tenantGroup.GET("/:id", rbac.GetTenant(ctx)).WithMeta("detail", "查询租户详情")
+		tenantGroup.PUT("/:id", rbac.UpdateTenant(ctx)).WithMeta("update", "编辑租户")
+	}
+
+	// 安全审计 - 声明权限组
+	securityGroup := api.Group("/security-events").WithMeta("security:audit", "安全审计")
+	securityGroup.Use(middleware.Authenticate(ctx), middleware.PermissionMiddleware(ctx))
+	{
+		securityGroup.GET("", rbac.ListSecurityEvents(ctx)).WithMeta("list", "查询安全事件")
 	}
 
 	// 权限模块 - 声明权限组
 	permissionGroup := api.Group("/permissions").WithMeta("permission:manage", "权限管理")
-	permissionGroup.Use(middleware.JWT(ctx), middleware.PermissionMiddleware(ctx))
+	permissionGroup.Use(middleware.Authenticate(ctx), middleware.PermissionMiddleware(ctx))
 	{
 		permissionGroup.GET("", rbac.GetPermissions(ctx)).WithMeta("list", "获取权限列表")
 	}
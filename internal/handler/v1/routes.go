@@ -4,6 +4,7 @@ import (
 	_ "gin-admin/docs"
 	"gin-admin/internal/handler/v1/rbac"
 	v1 "gin-admin/internal/logic/v1"
+	"gin-admin/internal/middleware"
 	"gin-admin/internal/routegroup"
 	"gin-admin/internal/services"
 
@@ -46,6 +47,9 @@ import (
 // @name                        Authorization
 // @description                 JWT Token 认证，格式：Bearer {token}
 
+// @tag.name            OAuth
+// @tag.description     令牌内省（RFC 7662）与撤销（RFC 7009）
+
 // @tag.name            RBAC-用户管理
 // @tag.description     用户注册、登录、个人信息管理
 
@@ -72,9 +76,15 @@ import (
 func RegisterRoutes(ctx *services.ServiceContext, r *gin.Engine) {
 	// API版本v1 使用authGroup 自动维护权限管理
 	apiV1 := routegroup.WrapGroup(r.Group("/api/v1"))
+	// 多租户：按请求头确定租户，认证后切换到用户所属租户
+	apiV1.Use(middleware.Tenant(ctx))
 
 	// 注册各个模块的路由
 	registerHealthRoutes(ctx, apiV1)
+	// JWKS 等标准发现接口挂载在根路径
+	registerWellKnownRoutes(ctx, routegroup.WrapGroup(r.Group("/.well-known")))
+	// 令牌内省与撤销，供网关和其他服务调用
+	registerOAuthRoutes(ctx, apiV1)
 	// 用户管理已整合到RBAC系统中
 	rbac.RegisterRBACRoutes(ctx, apiV1)
 	if ctx.Config.App.EnableSwagger {
@@ -87,3 +97,17 @@ func registerHealthRoutes(ctx *services.ServiceContext, api *routegroup.RouterGr
 	// 健康检查
 	api.Public().GET("/health", v1.HealthCheck(ctx))
 }
+
+// registerWellKnownRoutes 注册 /.well-known 下的公开路由
+func registerWellKnownRoutes(ctx *services.ServiceContext, wellKnown *routegroup.RouterGroup) {
+	// JWT 验签公钥
+	wellKnown.Public().GET("/jwks.json", v1.JWKS(ctx))
+}
+
+// registerOAuthRoutes 注册令牌内省、撤销路由，使用客户端凭证认证
+func registerOAuthRoutes(ctx *services.ServiceContext, api *routegroup.RouterGroup) {
+	oauth := api.Group("/oauth").Public()
+	oauth.Use(middleware.ClientAuth(ctx))
+	oauth.POST("/introspect", v1.IntrospectToken(ctx))
+	oauth.POST("/revoke", v1.RevokeToken(ctx))
+}
@@ -0,0 +1,28 @@
+package v1
+
+import (
+	"gin-admin/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+/*
+* @Author: zouyx
+* @Package: JWKS - 对外公开 JWT 验签公钥
+ */
+
+// JWKS godoc
+// @Summary JWT 验签公钥
+// @Description 返回当前可用于验签的公钥集合（RFC 7517），其他服务可据此独立校验 Access Token
+// @Tags 系统监控
+// @Produce json
+// @Success 200 {object} jwt.JSONWebKeySet "公钥集合"
+// @Router /.well-known/jwks.json [get]
+func JWKS(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 公钥会轮换，允许客户端短时间缓存
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, svcCtx.Jwt.JWKS(c.Request.Context()))
+	}
+}
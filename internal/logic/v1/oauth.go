@@ -0,0 +1,132 @@
+package v1
+
+import (
+	"gin-admin/internal/services"
+	types "gin-admin/internal/types/rbac"
+	"gin-admin/pkg/components/jwt"
+	"gin-admin/pkg/components/tenant"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/13 下午3:40
+* @Package: 令牌内省（RFC 7662）与撤销（RFC 7009），供网关和其他服务校验本系统签发的令牌
+ */
+
+// IntrospectToken godoc
+// @Summary 令牌内省
+// @Description 检查本系统签发的 access token / refresh token 是否有效（RFC 7662），令牌无效时只返回 active=false
+// @Description 需使用 security.token_clients 中配置的客户端凭证认证（HTTP Basic 或表单参数）
+// @Tags OAuth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "待检查的令牌"
+// @Param token_type_hint formData string false "令牌类型提示：access_token | refresh_token"
+// @Success 200 {object} types.TokenIntrospectionResponse "内省结果"
+// @Failure 400 {object} types.OAuthErrorResponse "请求参数错误"
+// @Failure 401 {object} types.OAuthErrorResponse "客户端认证失败"
+// @Router /oauth/introspect [post]
+func IntrospectToken(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		request := types.TokenIntrospectionRequest{}
+		if !bindTokenRequest(c, &request) {
+			return
+		}
+		ctx := c.Request.Context()
+		claims, err := svcCtx.ParseToken(ctx, request.Token, request.TokenTypeHint)
+		if err != nil {
+			logrus.Debugf("introspect inactive token :%s", err.Error())
+			c.JSON(http.StatusOK, types.TokenIntrospectionResponse{Active: false})
+			return
+		}
+		// 调用方不携带租户，按令牌所属租户查询用户
+		ctx = tenant.WithID(ctx, claims.TenantID)
+		// 禁用、锁定或已删除的用户，令牌同样视为无效
+		if err = svcCtx.Rbac.UserService.CheckUserStatus(ctx, claims.UserID); err != nil {
+			c.JSON(http.StatusOK, types.TokenIntrospectionResponse{Active: false})
+			return
+		}
+		scope, err := svcCtx.TokenScope(ctx, claims.UserID)
+		if err != nil {
+			logrus.Error("failed to get token scope :" + err.Error())
+			c.JSON(http.StatusInternalServerError, types.OAuthErrorResponse{Error: "server_error"})
+			return
+		}
+		resp := types.TokenIntrospectionResponse{
+			Active:    true,
+			Scope:     scope,
+			Username:  claims.Username,
+			TokenType: services.TokenTypeHintAccess,
+			Sub:       claims.Subject,
+			Iss:       claims.Issuer,
+			Jti:       claims.ID,
+			Sid:       claims.SessionID,
+			TenantID:  claims.TenantID,
+		}
+		if claims.TokenType == jwt.TokenTypeRefresh {
+			resp.TokenType = services.TokenTypeHintRefresh
+		}
+		if claims.ExpiresAt != nil {
+			resp.Exp = claims.ExpiresAt.Unix()
+		}
+		if claims.IssuedAt != nil {
+			resp.Iat = claims.IssuedAt.Unix()
+		}
+		if claims.ImpersonatorID != 0 {
+			resp.Act = &types.TokenActorResponse{Sub: strconv.FormatUint(uint64(claims.ImpersonatorID), 10)}
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// RevokeToken godoc
+// @Summary 令牌撤销
+// @Description 撤销本系统签发的 access token / refresh token 所属的会话（RFC 7009），令牌无效或已撤销时同样返回 200
+// @Description 需使用 security.token_clients 中配置的客户端凭证认证（HTTP Basic 或表单参数）
+// @Tags OAuth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "待撤销的令牌"
+// @Param token_type_hint formData string false "令牌类型提示：access_token | refresh_token"
+// @Success 200 "撤销成功"
+// @Failure 400 {object} types.OAuthErrorResponse "请求参数错误"
+// @Failure 401 {object} types.OAuthErrorResponse "客户端认证失败"
+// @Router /oauth/revoke [post]
+func RevokeToken(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		request := types.TokenIntrospectionRequest{}
+		if !bindTokenRequest(c, &request) {
+			return
+		}
+		ctx := c.Request.Context()
+		claims, err := svcCtx.ParseToken(ctx, request.Token, request.TokenTypeHint)
+		if err != nil {
+			logrus.Debugf("revoke invalid token :%s", err.Error())
+			c.Status(http.StatusOK)
+			return
+		}
+		if err = svcCtx.Jwt.RevokeSession(ctx, claims.SessionID); err != nil {
+			logrus.Error("failed to revoke session :" + err.Error())
+			c.JSON(http.StatusServiceUnavailable, types.OAuthErrorResponse{Error: "temporarily_unavailable"})
+			return
+		}
+		logrus.Infof("session %s of user %d revoked by client %s", claims.SessionID, claims.UserID, c.GetString("clientId"))
+		c.Status(http.StatusOK)
+	}
+}
+
+// bindTokenRequest 解析表单参数，缺少 token 时返回 invalid_request
+func bindTokenRequest(c *gin.Context, request *types.TokenIntrospectionRequest) bool {
+	if err := c.ShouldBind(request); err != nil {
+		c.JSON(http.StatusBadRequest, types.OAuthErrorResponse{Error: "invalid_request", ErrorDescription: "缺少 token 参数"})
+		return false
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+	return true
+}
@@ -0,0 +1,236 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"gin-admin/internal/model/rbac"
+	"gin-admin/internal/services"
+	types "gin-admin/internal/types/rbac"
+	"gin-admin/pkg/components/uploader"
+	"gin-admin/pkg/errcode"
+	_interface "gin-admin/pkg/interface"
+	"gin-admin/pkg/response"
+	"gin-admin/pkg/validator"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/15 上午10:10
+* @Package: 个人账号：修改密码、资料和头像
+ */
+
+// ChangeMyPassword godoc
+// @Summary 修改我的密码
+// @Description 校验原密码后设置新密码，当前设备保持登录，其他设备全部下线
+// @Tags RBAC-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param data body types.ChangePasswordRequest true "原密码和新密码"
+// @Success 200 {object} response.Response "修改成功"
+// @Failure 400 {object} response.Response "请求参数错误、原密码错误或密码不符合策略"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "模拟登录状态下不允许修改"
+// @Failure 429 {object} response.Response "验证失败次数过多"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /users/password [put]
+func ChangeMyPassword(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.ChangePasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, validator.ErrorMessage(err))
+			return
+		}
+		ctx := c.Request.Context()
+		userID := c.GetUint("uid")
+		user, err := svcCtx.Rbac.UserService.FindByID(ctx, userID)
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		if !verifyPassword(c, svcCtx, user, req.OldPassword, "原密码错误") {
+			return
+		}
+		if err = svcCtx.ChangePassword(ctx, userID, req.Password); err != nil {
+			handlePasswordError(c, err)
+			return
+		}
+		if err = revokeOtherSessions(ctx, svcCtx, userID, c.GetString("sessionId")); err != nil {
+			response.Fail(c, 500, "密码已修改，但下线其他设备失败: "+err.Error())
+			return
+		}
+		response.Success(c, "密码已修改，其他设备需要重新登录")
+	}
+}
+
+// UpdateMyProfile godoc
+// @Summary 修改我的资料
+// @Description 修改当前用户的性别和邮箱；修改邮箱需校验当前密码，新邮箱验证后才生效，同时通知原邮箱
+// @Tags RBAC-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param data body types.UpdateProfileRequest true "资料"
+// @Success 200 {object} response.Response "修改成功"
+// @Failure 400 {object} response.Response "请求参数错误或密码错误"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "模拟登录状态下不允许修改"
+// @Failure 409 {object} response.Response "邮箱已存在"
+// @Failure 429 {object} response.Response "验证失败次数过多或发送过于频繁"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /users/profile [put]
+func UpdateMyProfile(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.UpdateProfileRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, validator.ErrorMessage(err))
+			return
+		}
+		ctx := c.Request.Context()
+		userID := c.GetUint("uid")
+		user, err := svcCtx.Rbac.UserService.FindByID(ctx, userID)
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		// 先完成全部校验再写入，校验失败时不修改任何资料
+		emailChanged := !strings.EqualFold(req.Email, user.Email)
+		if emailChanged && !checkEmailChange(c, svcCtx, user, req.Email, req.Password) {
+			return
+		}
+		if err = svcCtx.Rbac.UserService.UpdateByID(ctx, userID, map[string]interface{}{"gender": req.Gender}); err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		if !emailChanged {
+			response.Success(c, "修改成功")
+			return
+		}
+		token, err := svcCtx.RequestEmailChange(ctx, userID, req.Email)
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		// 保留请求上下文中的租户等信息，但不随请求结束而取消
+		mailCtx := context.WithoutCancel(ctx)
+		go func() {
+			ctx, cancel := context.WithTimeout(mailCtx, 30*time.Second)
+			defer cancel()
+			if err := svcCtx.SendEmailChangeMail(ctx, user, req.Email, token); err != nil {
+				logrus.Errorf("failed to send email change mail to user %d :%s", user.ID, err.Error())
+			}
+		}()
+		response.Success(c, "修改成功，请前往新邮箱完成验证，验证后新邮箱生效")
+	}
+}
+
+// checkEmailChange 修改邮箱前的校验：当前密码、新邮箱是否被占用、发送频率，返回 false 时已写入响应
+func checkEmailChange(c *gin.Context, svcCtx *services.ServiceContext, user *rbac.User, email, password string) bool {
+	ctx := c.Request.Context()
+	// 修改邮箱可用于找回密码接管账号，需再次校验密码
+	if password == "" {
+		response.BadRequest(c, "修改邮箱需要输入当前密码")
+		return false
+	}
+	if !verifyPassword(c, svcCtx, user, password, "密码错误") {
+		return false
+	}
+	exist, err := svcCtx.Rbac.UserService.Exists(ctx, _interface.WithScopes(func(db *gorm.DB) *gorm.DB {
+		return db.Where("email = ? AND id <> ?", email, user.ID)
+	}))
+	if err != nil {
+		response.Fail(c, 500, err.Error())
+		return false
+	}
+	if exist {
+		response.Fail(c, http.StatusConflict, "邮箱已存在")
+		return false
+	}
+	// 新邮箱同样计入发送次数
+	wait, err := svcCtx.AllowVerificationMail(ctx, email)
+	if err != nil {
+		response.Fail(c, 500, err.Error())
+		return false
+	}
+	if wait > 0 {
+		seconds := int(math.Ceil(wait.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		response.FailWithStatus(c, http.StatusTooManyRequests, errcode.TooManyRequests, fmt.Sprintf("发送过于频繁，请 %d 秒后重试", seconds))
+		return false
+	}
+	return true
+}
+
+// verifyPassword 已登录用户再次校验密码，与登录共用失败次数限制，返回 false 时已写入响应
+func verifyPassword(c *gin.Context, svcCtx *services.ServiceContext, user *rbac.User, plain, message string) bool {
+	if !allowCredentialCheck(c, svcCtx, user) {
+		return false
+	}
+	if matched, _ := user.CheckPassword(plain); !matched {
+		failCredentialCheck(c, svcCtx, user, errcode.PasswordWrong, message)
+		return false
+	}
+	svcCtx.LoginGuard.Reset(c.Request.Context(), loginGuardKey(user, ""))
+	return true
+}
+
+// UploadMyAvatar godoc
+// @Summary 上传我的头像
+// @Description 上传图片作为当前用户头像，文件格式和大小受 upload 配置限制
+// @Tags RBAC-用户管理
+// @Accept multipart/form-data
+// @Produce json
+// @Security ApiKeyAuth
+// @Param file formData file true "头像图片"
+// @Success 200 {object} response.Response{data=types.AvatarResponse} "上传成功"
+// @Failure 400 {object} response.Response "未选择文件或文件不符合要求"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /users/avatar [post]
+func UploadMyAvatar(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		file, err := c.FormFile("file")
+		if err != nil {
+			response.BadRequest(c, "请选择要上传的头像")
+			return
+		}
+		ctx := c.Request.Context()
+		result, err := svcCtx.Uploader.Upload(ctx, file)
+		if err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		avatar := uploader.FilePath(svcCtx.Uploader.ParseUrl(result.Url))
+		if err = svcCtx.Rbac.UserService.UpdateByID(ctx, c.GetUint("uid"), map[string]interface{}{"avatar": avatar}); err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		response.Success(c, types.AvatarResponse{Avatar: avatar})
+	}
+}
+
+// revokeOtherSessions 下线用户除当前会话以外的所有会话
+func revokeOtherSessions(ctx context.Context, svcCtx *services.ServiceContext, userID uint, currentSessionId string) error {
+	sessions, err := svcCtx.Jwt.ListUserSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, s := range sessions {
+		if s.SessionID == currentSessionId {
+			continue
+		}
+		if err = svcCtx.Jwt.RevokeSession(ctx, s.SessionID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
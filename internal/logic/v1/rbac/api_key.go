@@ -0,0 +1,144 @@
+package rbac
+
+import (
+	"errors"
+	"gin-admin/internal/model/rbac"
+	"gin-admin/internal/services"
+	types "gin-admin/internal/types/rbac"
+	"gin-admin/pkg/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/13 上午10:00
+* @Package: API Key
+ */
+
+// ListMyAPIKeys godoc
+// @Summary 我的 API Key
+// @Description 获取当前用户创建的 API Key 列表，不包含明文
+// @Tags RBAC-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{data=[]rbac.APIKey} "成功返回列表"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /users/api-keys [get]
+func ListMyAPIKeys(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keys, err := svcCtx.Rbac.APIKeyService.ListByUser(c.Request.Context(), c.GetUint("uid"))
+		if err != nil {
+			response.Fail(c, 500, "获取 API Key 列表失败: "+err.Error())
+			return
+		}
+		response.Success(c, keys)
+	}
+}
+
+// CreateMyAPIKey godoc
+// @Summary 创建 API Key
+// @Description 为当前用户创建 API Key，授权范围只能是自己拥有的资源；明文只在创建时返回一次
+// @Tags RBAC-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param data body types.CreateAPIKeyRequest true "名称、授权范围和过期时间"
+// @Success 200 {object} response.Response{data=types.APIKeyCreatedResponse} "创建成功"
+// @Failure 400 {object} response.Response "请求参数错误或超出自身权限"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /users/api-keys [post]
+func CreateMyAPIKey(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.CreateAPIKeyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+			response.BadRequest(c, "过期时间必须晚于当前时间")
+			return
+		}
+		ctx := c.Request.Context()
+		userID := c.GetUint("uid")
+		resources, err := svcCtx.Rbac.ResourceService.GetUserResources(ctx, userID)
+		if err != nil {
+			response.Fail(c, 500, "获取用户资源失败: "+err.Error())
+			return
+		}
+		owned := make(map[string]struct{}, len(resources))
+		for _, r := range resources {
+			owned[r.Code] = struct{}{}
+		}
+		scopes := make([]string, 0, len(req.Scopes))
+		seen := make(map[string]struct{}, len(req.Scopes))
+		var invalid []string
+		for _, code := range req.Scopes {
+			if _, ok := seen[code]; ok {
+				continue
+			}
+			seen[code] = struct{}{}
+			if _, ok := owned[code]; !ok {
+				invalid = append(invalid, code)
+				continue
+			}
+			scopes = append(scopes, code)
+		}
+		if len(invalid) > 0 {
+			response.BadRequest(c, "没有以下资源的权限: "+strings.Join(invalid, ", "))
+			return
+		}
+		key := &rbac.APIKey{
+			UserID:    userID,
+			Name:      req.Name,
+			Scopes:    scopes,
+			ExpiresAt: req.ExpiresAt,
+		}
+		raw, err := svcCtx.Rbac.APIKeyService.Generate(ctx, key)
+		if err != nil {
+			response.Fail(c, 500, "创建 API Key 失败: "+err.Error())
+			return
+		}
+		response.Success(c, types.APIKeyCreatedResponse{APIKey: *key, Key: raw})
+	}
+}
+
+// RevokeMyAPIKey godoc
+// @Summary 删除 API Key
+// @Description 删除当前用户的 API Key，删除后立即失效
+// @Tags RBAC-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "API Key ID"
+// @Success 200 {object} response.Response "删除成功"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 404 {object} response.Response "API Key 不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /users/api-keys/{id} [delete]
+func RevokeMyAPIKey(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			response.BadRequest(c, "无效的ID")
+			return
+		}
+		err = svcCtx.Rbac.APIKeyService.Revoke(c.Request.Context(), c.GetUint("uid"), uint(id))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c, "API Key 不存在")
+			return
+		}
+		if err != nil {
+			response.Fail(c, 500, "删除 API Key 失败: "+err.Error())
+			return
+		}
+		response.Success(c, nil)
+	}
+}
@@ -0,0 +1,343 @@
+package rbac
+
+import (
+	"errors"
+	rbac2 "gin-admin/internal/model/rbac"
+	"gin-admin/internal/services"
+	rbac3 "gin-admin/internal/services/rbac"
+	types "gin-admin/internal/types/rbac"
+	"gin-admin/pkg/consts"
+	_interface "gin-admin/pkg/interface"
+	"gin-admin/pkg/response"
+	"gin-admin/pkg/validator"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"strconv"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/16 上午10:20
+* @Package: 部门管理
+ */
+
+// GetDeptTree godoc
+// @Summary 获取部门树
+// @Description 返回全部部门的树形结构，同级部门按排序字段从小到大排列
+// @Tags RBAC-部门管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{data=[]rbac.Department} "成功获取部门树"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /depts/tree [get]
+func GetDeptTree(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tree, err := svcCtx.Rbac.DeptService.Tree(c.Request.Context())
+		if err != nil {
+			response.Fail(c, 500, "获取部门树失败: "+err.Error())
+			return
+		}
+		response.Success(c, tree)
+	}
+}
+
+// GetDepts godoc
+// @Summary 获取部门列表
+// @Description 分页获取部门列表（平铺结构）
+// @Tags RBAC-部门管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request query types.ListDeptRequest true "查询参数"
+// @Success 200 {object} response.PaginatedResponse{data=[]rbac.Department} "成功获取部门列表"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /depts [get]
+func GetDepts(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		request := types.ListDeptRequest{}
+		if err := c.ShouldBindQuery(&request); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		result, err := svcCtx.Rbac.DeptService.FindPage(c.Request.Context(),
+			_interface.WithPagination(request.Page, request.PageSize),
+			_interface.WithOrderBy("sort ASC, id ASC"),
+			_interface.WithScopes(func(db *gorm.DB) *gorm.DB {
+				if request.Name != "" {
+					db = db.Where("name LIKE ?", request.Name+"%")
+				}
+				if request.Status > 0 {
+					db = db.Where("status = ?", request.Status)
+				}
+				return db
+			}))
+		if err != nil {
+			response.Fail(c, 500, "获取部门列表失败")
+			return
+		}
+		response.SuccessPage(c, result.List, result.Page, result.PageSize, result.Total)
+	}
+}
+
+// GetDept godoc
+// @Summary 获取部门详情
+// @Description 根据ID获取部门详细信息
+// @Tags RBAC-部门管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "部门ID"
+// @Success 200 {object} response.Response{data=rbac.Department} "成功获取部门详情"
+// @Failure 400 {object} response.Response "无效的部门ID"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 404 {object} response.Response "部门不存在"
+// @Router /depts/{id} [get]
+func GetDept(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			response.BadRequest(c, "无效的部门ID")
+			return
+		}
+		dept, err := svcCtx.Rbac.DeptService.FindByID(c.Request.Context(), uint(id))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c, "部门不存在")
+			return
+		}
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		response.Success(c, dept)
+	}
+}
+
+// CreateDept godoc
+// @Summary 创建部门
+// @Description 创建部门，上级部门为 0 时创建为顶级部门
+// @Tags RBAC-部门管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param data body types.UpsertDeptRequest true "部门信息"
+// @Success 201 {object} response.Response{data=rbac.Department} "成功创建部门"
+// @Failure 400 {object} response.Response "请求参数错误或负责人不存在"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 404 {object} response.Response "上级部门不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /depts [post]
+func CreateDept(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request types.UpsertDeptRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			response.BadRequest(c, validator.ErrorMessage(err))
+			return
+		}
+		ctx := c.Request.Context()
+		if !checkDeptRequest(c, svcCtx, &request) {
+			return
+		}
+		dept := &rbac2.Department{
+			Name:     request.Name,
+			ParentID: request.ParentID,
+			Sort:     request.Sort,
+			LeaderID: request.LeaderID,
+			Status:   request.Status,
+		}
+		if dept.Status == consts.DeptStatusUnknown {
+			dept.Status = consts.DeptStatusActive
+		}
+		if err := svcCtx.Rbac.DeptService.Create(ctx, dept); err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		response.Created(c, dept)
+	}
+}
+
+// UpdateDept godoc
+// @Summary 更新部门
+// @Description 根据ID更新部门信息，上级部门不能是自身或其下级部门
+// @Tags RBAC-部门管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "部门ID"
+// @Param data body types.UpsertDeptRequest true "部门信息"
+// @Success 200 {object} response.Response "成功更新部门"
+// @Failure 400 {object} response.Response "请求参数错误、负责人不存在或上级部门形成循环"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 404 {object} response.Response "部门或上级部门不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /depts/{id} [put]
+func UpdateDept(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			response.BadRequest(c, "无效的部门ID")
+			return
+		}
+		var request types.UpsertDeptRequest
+		if err = c.ShouldBindJSON(&request); err != nil {
+			response.BadRequest(c, validator.ErrorMessage(err))
+			return
+		}
+		ctx := c.Request.Context()
+		exist, err := svcCtx.Rbac.DeptService.ExistsByID(ctx, uint(id))
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		if !exist {
+			response.NotFound(c, "部门不存在")
+			return
+		}
+		if !checkDeptLeader(c, svcCtx, request.LeaderID) {
+			return
+		}
+		updates := map[string]interface{}{
+			"name":      request.Name,
+			"parent_id": request.ParentID,
+			"sort":      request.Sort,
+			"leader_id": request.LeaderID,
+		}
+		if request.Status != consts.DeptStatusUnknown {
+			updates["status"] = request.Status
+		}
+		if handleDeptParentError(c, svcCtx.Rbac.DeptService.UpdateDept(ctx, uint(id), updates)) {
+			response.Success(c, nil)
+		}
+	}
+}
+
+// MoveDept godoc
+// @Summary 移动部门
+// @Description 调整部门的上级部门和排序，下级部门随之移动；不能移动到自身或其下级部门之下
+// @Tags RBAC-部门管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "部门ID"
+// @Param data body types.MoveDeptRequest true "新的上级部门和排序"
+// @Success 200 {object} response.Response "移动成功"
+// @Failure 400 {object} response.Response "无效的部门ID或形成循环"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 404 {object} response.Response "部门或上级部门不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /depts/{id}/move [put]
+func MoveDept(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			response.BadRequest(c, "无效的部门ID")
+			return
+		}
+		var request types.MoveDeptRequest
+		if err = c.ShouldBindJSON(&request); err != nil {
+			response.BadRequest(c, validator.ErrorMessage(err))
+			return
+		}
+		ctx := c.Request.Context()
+		exist, err := svcCtx.Rbac.DeptService.ExistsByID(ctx, uint(id))
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		if !exist {
+			response.NotFound(c, "部门不存在")
+			return
+		}
+		if handleDeptParentError(c, svcCtx.Rbac.DeptService.Move(ctx, uint(id), request.ParentID, request.Sort)) {
+			response.Success(c, nil)
+		}
+	}
+}
+
+// DeleteDept godoc
+// @Summary 删除部门
+// @Description 根据ID删除部门，存在下级部门或用户时不能删除
+// @Tags RBAC-部门管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "部门ID"
+// @Success 200 {object} response.Response "成功删除部门"
+// @Failure 400 {object} response.Response "无效的部门ID或部门下存在下级部门、用户"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /depts/{id} [delete]
+func DeleteDept(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			response.BadRequest(c, "无效的部门ID")
+			return
+		}
+		err = svcCtx.Rbac.DeptService.DeleteDept(c.Request.Context(), uint(id))
+		switch {
+		case errors.Is(err, rbac3.ErrDeptHasChildren), errors.Is(err, rbac3.ErrDeptHasUsers):
+			response.BadRequest(c, err.Error())
+		case err != nil:
+			response.Fail(c, 500, err.Error())
+		default:
+			response.Success(c, nil)
+		}
+	}
+}
+
+// checkDeptRequest 校验新建部门的上级部门和负责人，失败时已写入响应
+func checkDeptRequest(c *gin.Context, svcCtx *services.ServiceContext, request *types.UpsertDeptRequest) bool {
+	err := svcCtx.Rbac.DeptService.CheckParent(c.Request.Context(), 0, request.ParentID)
+	return handleDeptParentError(c, err) && checkDeptLeader(c, svcCtx, request.LeaderID)
+}
+
+// handleDeptParentError 上级部门校验失败时写入响应，返回 err 是否为 nil
+func handleDeptParentError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, rbac3.ErrDeptCycle):
+		response.BadRequest(c, err.Error())
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.NotFound(c, "上级部门不存在")
+	case err != nil:
+		response.Fail(c, 500, err.Error())
+	}
+	return err == nil
+}
+
+// checkDeptLeader 校验部门负责人，未设置时跳过，失败时已写入响应
+func checkDeptLeader(c *gin.Context, svcCtx *services.ServiceContext, leaderID *uint) bool {
+	if leaderID == nil {
+		return true
+	}
+	exist, err := svcCtx.Rbac.UserService.ExistsByID(c.Request.Context(), *leaderID)
+	if err != nil {
+		response.Fail(c, 500, err.Error())
+		return false
+	}
+	if !exist {
+		response.BadRequest(c, "负责人不存在")
+		return false
+	}
+	return true
+}
+
+// checkUserDept 校验用户所属部门，0 表示未分配，失败时已写入响应
+func checkUserDept(c *gin.Context, svcCtx *services.ServiceContext, deptID uint) bool {
+	if deptID == 0 {
+		return true
+	}
+	exist, err := svcCtx.Rbac.DeptService.ExistsByID(c.Request.Context(), deptID)
+	if err != nil {
+		response.Fail(c, 500, err.Error())
+		return false
+	}
+	if !exist {
+		response.BadRequest(c, "所属部门不存在")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gin-admin/internal/model/rbac"
+	"gin-admin/internal/services"
+	rbac2 "gin-admin/internal/services/rbac"
+	types "gin-admin/internal/types/rbac"
+	"gin-admin/pkg/consts"
+	"gin-admin/pkg/errcode"
+	_interface "gin-admin/pkg/interface"
+	"gin-admin/pkg/response"
+	"gin-admin/pkg/validator"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/14 下午3:10
+* @Package: 注册邮箱验证
+ */
+
+// VerifyEmail godoc
+// @Summary 验证邮箱
+// @Description 使用验证邮件中的令牌验证邮箱，待验证的用户验证后即可登录；修改邮箱后旧的验证链接失效
+// @Description 修改邮箱的确认令牌同样使用此接口，验证后新邮箱生效
+// @Tags RBAC-用户管理
+// @Accept json
+// @Produce json
+// @Param data body types.VerifyEmailRequest true "验证令牌"
+// @Success 200 {object} response.Response "验证成功"
+// @Failure 400 {object} response.Response "请求参数错误或令牌无效"
+// @Failure 409 {object} response.Response "新邮箱已被其他账号使用"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /users/email/verify [post]
+func VerifyEmail(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.VerifyEmailRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, validator.ErrorMessage(err))
+			return
+		}
+		user, err := svcCtx.VerifyEmail(c.Request.Context(), req.Token)
+		if errors.Is(err, services.ErrEmailVerifyTokenInvalid) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		if errors.Is(err, rbac2.ErrEmailTaken) {
+			response.Fail(c, http.StatusConflict, err.Error())
+			return
+		}
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		logrus.Infof("email of user %d verified", user.ID)
+		response.Success(c, "邮箱验证成功")
+	}
+}
+
+// ResendVerificationEmail godoc
+// @Summary 重新发送验证邮件
+// @Description 向未验证的注册邮箱重新发送验证邮件；无论邮箱是否注册都返回成功，避免被用来探测账号
+// @Description 同一邮箱有发送冷却期和每日次数上限（security.email_verification）
+// @Tags RBAC-用户管理
+// @Accept json
+// @Produce json
+// @Param data body types.ResendVerificationRequest true "注册邮箱"
+// @Success 200 {object} response.Response "已受理"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 429 {object} response.Response "发送过于频繁"
+// @Router /users/email/resend [post]
+func ResendVerificationEmail(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.ResendVerificationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, validator.ErrorMessage(err))
+			return
+		}
+		ctx := c.Request.Context()
+		// 先按邮箱限流，是否注册都计数，响应不因邮箱是否存在而不同
+		wait, err := svcCtx.AllowVerificationMail(ctx, req.Email)
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		if wait > 0 {
+			seconds := int(math.Ceil(wait.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			response.FailWithStatus(c, http.StatusTooManyRequests, errcode.TooManyRequests, fmt.Sprintf("发送过于频繁，请 %d 秒后重试", seconds))
+			return
+		}
+		user, err := svcCtx.Rbac.UserService.FindOne(ctx, _interface.WithConditions(map[string]interface{}{"email": req.Email}))
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		if user != nil && user.EmailVerifiedAt == nil && user.Status != consts.UserStatusDisabled {
+			sendVerificationMail(c, svcCtx, user)
+		}
+		response.Success(c, "如果该邮箱已注册且尚未验证，您将收到验证邮件")
+	}
+}
+
+// sendVerificationMail 异步发送验证邮件，发送失败只记录日志
+// 保留请求上下文中的租户等信息，但不随请求结束而取消
+func sendVerificationMail(c *gin.Context, svcCtx *services.ServiceContext, user *rbac.User) {
+	ctx := context.WithoutCancel(c.Request.Context())
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+		if err := svcCtx.SendVerificationMail(ctx, user); err != nil {
+			logrus.Errorf("failed to send verification mail to user %d :%s", user.ID, err.Error())
+		}
+	}()
+}
@@ -0,0 +1,102 @@
+package rbac
+
+import (
+	"errors"
+	"gin-admin/internal/middleware"
+	"gin-admin/internal/services"
+	rbac2 "gin-admin/internal/services/rbac"
+	types "gin-admin/internal/types/rbac"
+	_interface "gin-admin/pkg/interface"
+	"gin-admin/pkg/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"strconv"
+	"time"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/13 上午10:30
+* @Package: 模拟登录
+ */
+
+// Impersonate godoc
+// @Summary 模拟登录
+// @Description 以目标用户身份登录排查问题，返回短期有效的令牌（不写入刷新令牌 Cookie，避免覆盖管理员自己的登录态）
+// @Description 模拟会话不能修改密码、管理两步验证和 API Key，也不能再次模拟；期间的每个请求都会记录安全事件
+// @Tags RBAC-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} response.Response{data=types.ImpersonationResponse} "模拟登录成功"
+// @Failure 400 {object} response.Response "请求格式错误"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "没有权限"
+// @Failure 404 {object} response.Response "用户不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /users/{id}/impersonate [post]
+func Impersonate(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			response.BadRequest(c, "无效的用户ID")
+			return
+		}
+		// 只能通过管理员本人的登录态发起，API Key 不能模拟登录
+		if _, ok := c.Get("apiKey"); ok {
+			response.Forbidden(c, "API Key 不能模拟登录")
+			return
+		}
+		ctx := c.Request.Context()
+		// 平台管理员可能已切换到被模拟用户的租户，管理员本人按所属租户查询
+		impersonator, err := svcCtx.Rbac.UserService.FindByID(middleware.HomeContext(c), c.GetUint("uid"))
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		target, err := svcCtx.Rbac.UserService.FindByID(ctx, uint(userID), _interface.WithPreloads("Roles"))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c, "用户不存在")
+			return
+		}
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		if e := rbac2.UserStatusError(target, time.Now()); e != nil {
+			response.Fail(c, e.Code, e.Message)
+			return
+		}
+		tokenPair, expiresAt, err := svcCtx.Impersonate(ctx, services.ImpersonationRequest{
+			Impersonator: impersonator,
+			Target:       target,
+			IP:           c.ClientIP(),
+			UserAgent:    c.Request.UserAgent(),
+		})
+		switch {
+		case errors.Is(err, services.ErrImpersonateSelf):
+			response.BadRequest(c, err.Error())
+			return
+		case errors.Is(err, services.ErrImpersonationEscalate):
+			response.Forbidden(c, err.Error())
+			return
+		case err != nil:
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		response.Success(c, types.ImpersonationResponse{
+			TokenResponse: types.TokenResponse{
+				AccessToken:  tokenPair.AccessToken,
+				RefreshToken: tokenPair.RefreshToken,
+				TokenType:    tokenPair.TokenType,
+				ExpiresIn:    tokenPair.ExpiresIn,
+			},
+			UserID:           target.ID,
+			Username:         target.Username,
+			ImpersonatorID:   impersonator.ID,
+			SessionExpiresAt: expiresAt,
+		})
+	}
+}
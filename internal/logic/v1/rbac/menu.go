@@ -0,0 +1,283 @@
+package rbac
+
+import (
+	"errors"
+	rbac2 "gin-admin/internal/model/rbac"
+	"gin-admin/internal/services"
+	rbac3 "gin-admin/internal/services/rbac"
+	types "gin-admin/internal/types/rbac"
+	"gin-admin/pkg/consts"
+	_interface "gin-admin/pkg/interface"
+	"gin-admin/pkg/response"
+	"gin-admin/pkg/validator"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"strconv"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/18 上午10:00
+* @Package: 菜单管理
+ */
+
+// GetMyMenus godoc
+// @Summary 获取我的菜单
+// @Description 返回当前用户可见的菜单树：禁用的菜单、所需权限未授权（或被禁止访问）的菜单连同下级菜单一起排除
+// @Description 隐藏的菜单同样返回，由前端注册路由但不在导航中显示
+// @Tags RBAC-菜单管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{data=[]rbac.Menu} "成功获取菜单树"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /users/menus [get]
+func GetMyMenus(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		perms, err := svcCtx.Rbac.UserService.GetUserPerms(ctx, c.GetUint("uid"))
+		if err != nil {
+			response.Fail(c, 500, "获取用户权限失败: "+err.Error())
+			return
+		}
+		tree, err := svcCtx.Rbac.MenuService.UserTree(ctx, perms)
+		if err != nil {
+			response.Fail(c, 500, "获取菜单失败: "+err.Error())
+			return
+		}
+		response.Success(c, tree)
+	}
+}
+
+// GetMenuTree godoc
+// @Summary 获取菜单树
+// @Description 返回全部菜单（含禁用和隐藏的菜单）的树形结构，同级菜单按排序字段从小到大排列
+// @Tags RBAC-菜单管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{data=[]rbac.Menu} "成功获取菜单树"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /menus/tree [get]
+func GetMenuTree(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tree, err := svcCtx.Rbac.MenuService.Tree(c.Request.Context())
+		if err != nil {
+			response.Fail(c, 500, "获取菜单树失败: "+err.Error())
+			return
+		}
+		response.Success(c, tree)
+	}
+}
+
+// GetMenu godoc
+// @Summary 获取菜单详情
+// @Description 根据ID获取菜单详细信息
+// @Tags RBAC-菜单管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "菜单ID"
+// @Success 200 {object} response.Response{data=rbac.Menu} "成功获取菜单详情"
+// @Failure 400 {object} response.Response "无效的菜单ID"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 404 {object} response.Response "菜单不存在"
+// @Router /menus/{id} [get]
+func GetMenu(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			response.BadRequest(c, "无效的菜单ID")
+			return
+		}
+		menu, err := svcCtx.Rbac.MenuService.FindByID(c.Request.Context(), uint(id))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c, "菜单不存在")
+			return
+		}
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		response.Success(c, menu)
+	}
+}
+
+// CreateMenu godoc
+// @Summary 创建菜单
+// @Description 创建菜单，上级菜单为 0 时创建为顶级菜单；所需权限必须是已有的权限分组编码或资源编码
+// @Tags RBAC-菜单管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param data body types.UpsertMenuRequest true "菜单信息"
+// @Success 201 {object} response.Response{data=rbac.Menu} "成功创建菜单"
+// @Failure 400 {object} response.Response "请求参数错误或权限编码不存在"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "开启多租户后只有平台超级管理员可以修改菜单"
+// @Failure 404 {object} response.Response "上级菜单不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /menus [post]
+func CreateMenu(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request types.UpsertMenuRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			response.BadRequest(c, validator.ErrorMessage(err))
+			return
+		}
+		if !checkMenuRequest(c, svcCtx, 0, &request) {
+			return
+		}
+		menu := &rbac2.Menu{
+			Name:       request.Name,
+			ParentID:   request.ParentID,
+			Icon:       request.Icon,
+			Path:       request.Path,
+			Component:  request.Component,
+			Sort:       request.Sort,
+			Hidden:     request.Hidden,
+			Permission: request.Permission,
+			Status:     request.Status,
+		}
+		if menu.Status == consts.MenuStatusUnknown {
+			menu.Status = consts.MenuStatusActive
+		}
+		if err := svcCtx.Rbac.MenuService.Create(c.Request.Context(), menu); err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		response.Created(c, menu)
+	}
+}
+
+// UpdateMenu godoc
+// @Summary 更新菜单
+// @Description 根据ID更新菜单信息，上级菜单不能是自身或其下级菜单
+// @Tags RBAC-菜单管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "菜单ID"
+// @Param data body types.UpsertMenuRequest true "菜单信息"
+// @Success 200 {object} response.Response "成功更新菜单"
+// @Failure 400 {object} response.Response "请求参数错误、权限编码不存在或上级菜单形成循环"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "开启多租户后只有平台超级管理员可以修改菜单"
+// @Failure 404 {object} response.Response "菜单或上级菜单不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /menus/{id} [put]
+func UpdateMenu(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			response.BadRequest(c, "无效的菜单ID")
+			return
+		}
+		var request types.UpsertMenuRequest
+		if err = c.ShouldBindJSON(&request); err != nil {
+			response.BadRequest(c, validator.ErrorMessage(err))
+			return
+		}
+		ctx := c.Request.Context()
+		exist, err := svcCtx.Rbac.MenuService.ExistsByID(ctx, uint(id))
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		if !exist {
+			response.NotFound(c, "菜单不存在")
+			return
+		}
+		if !checkMenuRequest(c, svcCtx, uint(id), &request) {
+			return
+		}
+		updates := map[string]interface{}{
+			"name":       request.Name,
+			"parent_id":  request.ParentID,
+			"icon":       request.Icon,
+			"path":       request.Path,
+			"component":  request.Component,
+			"sort":       request.Sort,
+			"hidden":     request.Hidden,
+			"permission": request.Permission,
+		}
+		if request.Status != consts.MenuStatusUnknown {
+			updates["status"] = request.Status
+		}
+		if err = svcCtx.Rbac.MenuService.UpdateByID(ctx, uint(id), updates); err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		response.Success(c, nil)
+	}
+}
+
+// DeleteMenu godoc
+// @Summary 删除菜单
+// @Description 根据ID删除菜单，存在下级菜单时不能删除
+// @Tags RBAC-菜单管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "菜单ID"
+// @Success 200 {object} response.Response "成功删除菜单"
+// @Failure 400 {object} response.Response "无效的菜单ID或菜单下存在下级菜单"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "开启多租户后只有平台超级管理员可以修改菜单"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /menus/{id} [delete]
+func DeleteMenu(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			response.BadRequest(c, "无效的菜单ID")
+			return
+		}
+		err = svcCtx.Rbac.MenuService.DeleteMenu(c.Request.Context(), uint(id))
+		switch {
+		case errors.Is(err, rbac3.ErrMenuHasChildren):
+			response.BadRequest(c, err.Error())
+		case err != nil:
+			response.Fail(c, 500, err.Error())
+		default:
+			response.Success(c, nil)
+		}
+	}
+}
+
+// checkMenuRequest 校验上级菜单和所需权限，失败时已写入响应
+func checkMenuRequest(c *gin.Context, svcCtx *services.ServiceContext, menuID uint, request *types.UpsertMenuRequest) bool {
+	ctx := c.Request.Context()
+	err := svcCtx.Rbac.MenuService.CheckParent(ctx, menuID, request.ParentID)
+	switch {
+	case errors.Is(err, rbac3.ErrMenuCycle):
+		response.BadRequest(c, err.Error())
+		return false
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.NotFound(c, "上级菜单不存在")
+		return false
+	case err != nil:
+		response.Fail(c, 500, err.Error())
+		return false
+	}
+	if request.Permission == "" {
+		return true
+	}
+	code := _interface.WithConditions(map[string]interface{}{"code": request.Permission})
+	exist, err := svcCtx.Rbac.PermissionService.Exists(ctx, code)
+	if err == nil && !exist {
+		exist, err = svcCtx.Rbac.ResourceService.Exists(ctx, code)
+	}
+	if err != nil {
+		response.Fail(c, 500, err.Error())
+		return false
+	}
+	if !exist {
+		response.BadRequest(c, "所需权限不存在，应为权限分组编码或资源编码")
+		return false
+	}
+	return true
+}
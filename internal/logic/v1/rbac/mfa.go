@@ -0,0 +1,399 @@
+package rbac
+
+import (
+	"errors"
+	"gin-admin/internal/services"
+	rbac2 "gin-admin/internal/services/rbac"
+	types "gin-admin/internal/types/rbac"
+	"gin-admin/pkg/errcode"
+	_interface "gin-admin/pkg/interface"
+	"gin-admin/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/11 下午2:30
+* @Package: 两步验证
+ */
+
+// LoginMFA godoc
+// @Summary 两步验证登录
+// @Description 使用登录返回的两步验证凭证和验证器口令（或恢复码）换取令牌；首次绑定时同时返回恢复码
+// @Tags RBAC-两步验证
+// @Accept json
+// @Produce json
+// @Param data body types.MFALoginRequest true "两步验证凭证和口令"
+// @Success 200 {object} response.Response{data=types.MFALoginResponse} "验证通过返回令牌对"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 401 {object} response.Response "凭证已失效"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /users/login/mfa [post]
+func LoginMFA(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.MFALoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		ctx := c.Request.Context()
+		challenge, ok := getMFAChallenge(c, svcCtx, req.MFAToken)
+		if !ok {
+			return
+		}
+		user, err := svcCtx.Rbac.UserService.FindByID(ctx, challenge.UserID, _interface.WithPreloads("Roles"))
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		// 密码校验之后账号可能被禁用或锁定
+		if e := rbac2.UserStatusError(user, time.Now()); e != nil {
+			svcCtx.MFAChallenges.Delete(ctx, req.MFAToken)
+			response.Fail(c, e.Code, e.Message)
+			return
+		}
+		if !allowCredentialCheck(c, svcCtx, user) {
+			return
+		}
+		var recoveryCodes []string
+		if challenge.Setup {
+			recoveryCodes, err = svcCtx.Rbac.MFAService.Enable(ctx, user.ID, req.Code, svcCtx.MFAChallenges.RecoveryCodes())
+		} else {
+			err = svcCtx.Rbac.MFAService.Verify(ctx, user.ID, req.Code)
+		}
+		if errors.Is(err, rbac2.ErrMFAInvalidCode) {
+			// 口令错误同样计入账号的失败次数，避免凭密码反复换取新凭证无限猜测口令
+			exhausted := svcCtx.MFAChallenges.Fail(ctx, req.MFAToken)
+			if recordCredentialFailure(c, svcCtx, user) {
+				svcCtx.MFAChallenges.Delete(ctx, req.MFAToken)
+				return
+			}
+			if exhausted {
+				response.FailWithStatus(c, http.StatusUnauthorized, errcode.MFAChallengeExpired, "验证码错误次数过多，请重新登录")
+				return
+			}
+			response.Fail(c, errcode.MFACodeInvalid, errcode.GetMessage(errcode.MFACodeInvalid))
+			return
+		}
+		if errors.Is(err, rbac2.ErrMFANotSetup) || errors.Is(err, rbac2.ErrMFANotEnabled) || errors.Is(err, rbac2.ErrMFAAlreadyEnabled) {
+			// 凭证签发后两步验证状态发生了变化
+			svcCtx.MFAChallenges.Delete(ctx, req.MFAToken)
+			response.FailWithStatus(c, http.StatusUnauthorized, errcode.MFAChallengeExpired, errcode.GetMessage(errcode.MFAChallengeExpired))
+			return
+		}
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		svcCtx.MFAChallenges.Delete(ctx, req.MFAToken)
+		svcCtx.LoginGuard.Reset(ctx, loginGuardKey(user, ""))
+		tokenResponse, ok := issueTokenPair(c, svcCtx, user)
+		if !ok {
+			return
+		}
+		response.Success(c, types.MFALoginResponse{
+			TokenResponse: *tokenResponse,
+			RecoveryCodes: recoveryCodes,
+		})
+	}
+}
+
+// LoginMFASetup godoc
+// @Summary 登录时绑定验证器
+// @Description 角色要求开启两步验证但用户尚未绑定时，凭登录返回的两步验证凭证获取待绑定的密钥，绑定后调用 /users/login/mfa 完成登录
+// @Tags RBAC-两步验证
+// @Accept json
+// @Produce json
+// @Param data body types.MFALoginSetupRequest true "两步验证凭证"
+// @Success 200 {object} response.Response{data=types.MFASetupResponse} "待绑定的密钥"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 401 {object} response.Response "凭证已失效"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /users/login/mfa/setup [post]
+func LoginMFASetup(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.MFALoginSetupRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		challenge, ok := getMFAChallenge(c, svcCtx, req.MFAToken)
+		if !ok {
+			return
+		}
+		if !challenge.Setup {
+			response.BadRequest(c, "已绑定验证器，请直接输入验证码")
+			return
+		}
+		setupMFA(c, svcCtx, challenge.UserID)
+	}
+}
+
+// GetMFAStatus godoc
+// @Summary 查询两步验证状态
+// @Description 查询当前用户是否开启两步验证、所属角色是否要求开启以及剩余恢复码数量
+// @Tags RBAC-两步验证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{data=types.MFAStatusResponse} "两步验证状态"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /users/mfa [get]
+func GetMFAStatus(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		userID := c.GetUint("uid")
+		enabled, required, err := svcCtx.Rbac.MFAService.State(ctx, userID)
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		resp := types.MFAStatusResponse{Enabled: enabled, Required: required}
+		if enabled {
+			if resp.RecoveryCodesRemaining, err = svcCtx.Rbac.MFAService.RemainingRecoveryCodes(ctx, userID); err != nil {
+				response.Fail(c, 500, err.Error())
+				return
+			}
+		}
+		response.Success(c, resp)
+	}
+}
+
+// SetupMFA godoc
+// @Summary 获取验证器密钥
+// @Description 生成待绑定的 TOTP 密钥和二维码内容，调用 /users/mfa/enable 校验口令后才会开启
+// @Tags RBAC-两步验证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{data=types.MFASetupResponse} "待绑定的密钥"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 409 {object} response.Response "已开启两步验证"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /users/mfa/setup [post]
+func SetupMFA(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setupMFA(c, svcCtx, c.GetUint("uid"))
+	}
+}
+
+// EnableMFA godoc
+// @Summary 开启两步验证
+// @Description 校验验证器生成的口令后开启两步验证，返回的恢复码只显示这一次
+// @Tags RBAC-两步验证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param data body types.MFACodeRequest true "验证器口令"
+// @Success 200 {object} response.Response{data=types.MFARecoveryCodesResponse} "恢复码"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /users/mfa/enable [post]
+func EnableMFA(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.MFACodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		codes, err := svcCtx.Rbac.MFAService.Enable(c.Request.Context(), c.GetUint("uid"), req.Code, svcCtx.MFAChallenges.RecoveryCodes())
+		if !handleMFAError(c, err) {
+			return
+		}
+		response.Success(c, types.MFARecoveryCodesResponse{RecoveryCodes: codes})
+	}
+}
+
+// DisableMFA godoc
+// @Summary 关闭两步验证
+// @Description 校验口令（或恢复码）后关闭两步验证；所属角色要求开启时不允许关闭
+// @Tags RBAC-两步验证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param data body types.MFACodeRequest true "验证器口令或恢复码"
+// @Success 200 {object} response.Response "关闭成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "角色要求开启两步验证"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Failure 429 {object} response.Response "验证失败次数过多"
+// @Router /users/mfa/disable [post]
+func DisableMFA(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.MFACodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		ctx := c.Request.Context()
+		userID := c.GetUint("uid")
+		_, required, err := svcCtx.Rbac.MFAService.State(ctx, userID)
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		if required {
+			response.Forbidden(c, "所属角色要求开启两步验证，无法关闭")
+			return
+		}
+		if !verifyMFACode(c, svcCtx, userID, req.Code) {
+			return
+		}
+		if err = svcCtx.Rbac.MFAService.Disable(ctx, userID); err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		response.Success(c, "已关闭两步验证")
+	}
+}
+
+// RegenerateMFARecoveryCodes godoc
+// @Summary 重新生成恢复码
+// @Description 校验口令后重新生成恢复码，旧的恢复码全部作废
+// @Tags RBAC-两步验证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param data body types.MFACodeRequest true "验证器口令或恢复码"
+// @Success 200 {object} response.Response{data=types.MFARecoveryCodesResponse} "新的恢复码"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Failure 429 {object} response.Response "验证失败次数过多"
+// @Router /users/mfa/recovery-codes [post]
+func RegenerateMFARecoveryCodes(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.MFACodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		ctx := c.Request.Context()
+		userID := c.GetUint("uid")
+		if !verifyMFACode(c, svcCtx, userID, req.Code) {
+			return
+		}
+		codes, err := svcCtx.Rbac.MFAService.RegenerateRecoveryCodes(ctx, userID, svcCtx.MFAChallenges.RecoveryCodes())
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		response.Success(c, types.MFARecoveryCodesResponse{RecoveryCodes: codes})
+	}
+}
+
+// ResetUserMFA godoc
+// @Summary 重置用户两步验证
+// @Description 用户丢失验证器且没有可用恢复码时，由管理员清除其两步验证；角色要求开启的用户下次登录需重新绑定
+// @Tags RBAC-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} response.Response "重置成功"
+// @Failure 400 {object} response.Response "无效的用户ID"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /users/{id}/mfa [delete]
+func ResetUserMFA(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			response.BadRequest(c, "无效的用户ID")
+			return
+		}
+		if err = svcCtx.Rbac.MFAService.Disable(c.Request.Context(), uint(userID)); err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		response.Success(c, "重置成功")
+	}
+}
+
+// getMFAChallenge 获取两步验证凭证，失效时已写入错误响应
+func getMFAChallenge(c *gin.Context, svcCtx *services.ServiceContext, token string) (*services.MFAChallenge, bool) {
+	challenge, err := svcCtx.MFAChallenges.Get(c.Request.Context(), token)
+	if errors.Is(err, services.ErrMFAChallengeInvalid) {
+		response.FailWithStatus(c, http.StatusUnauthorized, errcode.MFAChallengeExpired, errcode.GetMessage(errcode.MFAChallengeExpired))
+		return nil, false
+	}
+	if err != nil {
+		response.Fail(c, 500, err.Error())
+		return nil, false
+	}
+	return challenge, true
+}
+
+// setupMFA 生成待绑定密钥并返回二维码内容
+func setupMFA(c *gin.Context, svcCtx *services.ServiceContext, userID uint) {
+	ctx := c.Request.Context()
+	user, err := svcCtx.Rbac.UserService.FindByID(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.NotFound(c, "用户不存在")
+		return
+	}
+	if err != nil {
+		response.Fail(c, 500, err.Error())
+		return
+	}
+	secret, err := svcCtx.Rbac.MFAService.Setup(ctx, userID)
+	if !handleMFAError(c, err) {
+		return
+	}
+	issuer := svcCtx.MFAChallenges.Issuer(svcCtx.Config.Jwt.Issuer)
+	response.Success(c, types.MFASetupResponse{
+		Secret:     secret,
+		OtpauthURI: svcCtx.Rbac.MFAService.URI(issuer, user.Username, secret),
+	})
+}
+
+// verifyMFACode 已登录用户校验口令或恢复码，错误次数与登录失败共用计数，用尽时锁定账号
+// 校验失败时已写入响应
+func verifyMFACode(c *gin.Context, svcCtx *services.ServiceContext, userID uint, code string) bool {
+	ctx := c.Request.Context()
+	user, err := svcCtx.Rbac.UserService.FindByID(ctx, userID)
+	if err != nil {
+		response.Fail(c, 500, err.Error())
+		return false
+	}
+	if !allowCredentialCheck(c, svcCtx, user) {
+		return false
+	}
+	err = svcCtx.Rbac.MFAService.Verify(ctx, userID, code)
+	if errors.Is(err, rbac2.ErrMFAInvalidCode) {
+		failCredentialCheck(c, svcCtx, user, errcode.MFACodeInvalid, errcode.GetMessage(errcode.MFACodeInvalid))
+		return false
+	}
+	if !handleMFAError(c, err) {
+		return false
+	}
+	svcCtx.LoginGuard.Reset(ctx, loginGuardKey(user, ""))
+	return true
+}
+
+// handleMFAError 将两步验证的业务错误转换为响应，err 为 nil 时返回 true
+func handleMFAError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, rbac2.ErrMFAInvalidCode):
+		response.Fail(c, errcode.MFACodeInvalid, errcode.GetMessage(errcode.MFACodeInvalid))
+	case errors.Is(err, rbac2.ErrMFAAlreadyEnabled):
+		response.Fail(c, http.StatusConflict, err.Error())
+	case errors.Is(err, rbac2.ErrMFANotSetup), errors.Is(err, rbac2.ErrMFANotEnabled):
+		response.BadRequest(c, err.Error())
+	default:
+		logrus.Errorf("mfa operation failed :%s", err.Error())
+		response.Fail(c, 500, err.Error())
+	}
+	return false
+}
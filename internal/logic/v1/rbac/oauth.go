@@ -0,0 +1,134 @@
+package rbac
+
+import (
+	"errors"
+	"gin-admin/internal/middleware"
+	"gin-admin/internal/services"
+	rbac2 "gin-admin/internal/services/rbac"
+	types "gin-admin/internal/types/rbac"
+	"gin-admin/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/12 下午3:10
+* @Package: 第三方登录
+ */
+
+// ListOAuthProviders godoc
+// @Summary 第三方登录方式
+// @Description 获取已配置的第三方登录方式，用于登录页展示
+// @Tags RBAC-用户管理
+// @Produce json
+// @Success 200 {object} response.Response{data=[]types.OAuthProviderResponse} "获取成功"
+// @Router /users/oauth/providers [get]
+func ListOAuthProviders(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		providers := svcCtx.OAuth.List()
+		list := make([]types.OAuthProviderResponse, 0, len(providers))
+		for _, p := range providers {
+			name := p.DisplayName
+			if name == "" {
+				name = p.Name
+			}
+			list = append(list, types.OAuthProviderResponse{Name: p.Name, DisplayName: name})
+		}
+		response.Success(c, list)
+	}
+}
+
+// OAuthRedirect godoc
+// @Summary 第三方登录
+// @Description 跳转到第三方的授权页面；redirect=false 时返回授权地址，由前端自行跳转
+// @Tags RBAC-用户管理
+// @Produce json
+// @Param provider path string true "提供方标识"
+// @Param redirect query bool false "是否直接跳转，默认 true"
+// @Success 200 {object} response.Response{data=types.OAuthLoginURLResponse} "授权地址"
+// @Success 302 "跳转到授权页面"
+// @Failure 404 {object} response.Response "不支持的登录方式"
+// @Router /users/oauth/{provider}/login [get]
+func OAuthRedirect(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authURL, state, err := svcCtx.OAuth.Begin(c.Request.Context(), c.Param("provider"))
+		if err != nil {
+			handleOAuthError(c, err)
+			return
+		}
+		// 回调时比对，保证回调来自发起登录的浏览器
+		middleware.SetOAuthStateCookie(c, svcCtx, state)
+		if c.Query("redirect") == "false" {
+			response.Success(c, types.OAuthLoginURLResponse{AuthURL: authURL})
+			return
+		}
+		c.Redirect(http.StatusFound, authURL)
+	}
+}
+
+// OAuthCallback godoc
+// @Summary 第三方登录回调
+// @Description 校验 state 及发起登录时写入的 state Cookie 后用授权码换取第三方账号，关联或创建本地用户并完成登录，开启两步验证时返回两步验证凭证
+// @Tags RBAC-用户管理
+// @Produce json
+// @Param provider path string true "提供方标识"
+// @Param code query string true "授权码"
+// @Param state query string true "登录请求的 state"
+// @Success 200 {object} response.Response{data=types.TokenResponse} "登录成功"
+// @Failure 400 {object} response.Response "请求参数错误或登录请求已失效"
+// @Failure 401 {object} response.Response "第三方登录失败"
+// @Router /users/oauth/{provider}/callback [get]
+func OAuthCallback(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.OAuthCallbackRequest
+		if err := c.ShouldBindQuery(&req); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		if req.Error != "" || req.Code == "" {
+			response.Unauthorized(c, "第三方登录已取消或失败："+req.Error+" "+req.ErrorDescription)
+			return
+		}
+		if !middleware.CheckOAuthStateCookie(c, svcCtx, req.State) {
+			handleOAuthError(c, services.ErrOAuthStateInvalid)
+			return
+		}
+		provider := c.Param("provider")
+		ctx, identity, err := svcCtx.OAuth.Finish(c.Request.Context(), provider, req.Code, req.State)
+		if err != nil {
+			handleOAuthError(c, err)
+			return
+		}
+		// 回到发起登录的租户完成登录
+		c.Request = c.Request.WithContext(ctx)
+		user, err := svcCtx.OAuthLoginUser(ctx, provider, identity)
+		if err != nil {
+			handleOAuthError(c, err)
+			return
+		}
+		if e := rbac2.UserStatusError(user, time.Now()); e != nil {
+			response.Fail(c, e.Code, e.Message)
+			return
+		}
+		completeLogin(c, svcCtx, user)
+	}
+}
+
+func handleOAuthError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrOAuthProviderNotFound):
+		response.NotFound(c, err.Error())
+	case errors.Is(err, services.ErrOAuthStateInvalid), errors.Is(err, services.ErrOAuthEmailRequired):
+		response.BadRequest(c, err.Error())
+	case errors.Is(err, services.ErrOAuthEmailTaken):
+		response.FailWithStatus(c, http.StatusConflict, http.StatusConflict, err.Error())
+	default:
+		// 提供方返回的错误可能包含敏感信息，只记录日志
+		logrus.Errorf("oauth login failed :%s", err.Error())
+		response.Unauthorized(c, "第三方登录失败")
+	}
+}
@@ -0,0 +1,220 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"gin-admin/internal/services"
+	rbac2 "gin-admin/internal/services/rbac"
+	types "gin-admin/internal/types/rbac"
+	"gin-admin/pkg/components/password"
+	"gin-admin/pkg/consts"
+	_interface "gin-admin/pkg/interface"
+	"gin-admin/pkg/response"
+	"gin-admin/pkg/validator"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"time"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/12 上午10:20
+* @Package: 找回密码、密码策略
+ */
+
+// ForgotPassword godoc
+// @Summary 找回密码
+// @Description 向注册邮箱发送重置密码邮件；无论邮箱是否注册都返回成功，避免被用来探测账号
+// @Tags RBAC-用户管理
+// @Accept json
+// @Produce json
+// @Param data body types.ForgotPasswordRequest true "注册邮箱"
+// @Success 200 {object} response.Response "已受理"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Router /users/password/forgot [post]
+func ForgotPassword(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.ForgotPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		user, err := svcCtx.Rbac.UserService.FindOne(c.Request.Context(), _interface.WithConditions(map[string]interface{}{"email": req.Email}))
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		// 开启邮箱验证时只向已验证的邮箱发送，未验证的邮箱不能用于找回密码
+		if user != nil && user.Status != consts.UserStatusDisabled && (user.EmailVerifiedAt != nil || !svcCtx.EmailVerificationEnabled()) {
+			ip := c.ClientIP()
+			// 异步发送，响应时间不因邮箱是否存在而不同；保留请求上下文中的租户等信息，但不随请求结束而取消
+			ctx := context.WithoutCancel(c.Request.Context())
+			go func() {
+				ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+				defer cancel()
+				if err := svcCtx.SendPasswordResetMail(ctx, user, ip); err != nil {
+					logrus.Errorf("failed to send password reset mail to user %d :%s", user.ID, err.Error())
+				}
+			}()
+		}
+		response.Success(c, "如果该邮箱已注册，您将收到重置密码的邮件")
+	}
+}
+
+// ResetPassword godoc
+// @Summary 重置密码
+// @Description 使用重置邮件中的令牌设置新密码，令牌只能使用一次；重置后所有设备需要重新登录
+// @Tags RBAC-用户管理
+// @Accept json
+// @Produce json
+// @Param data body types.ResetPasswordRequest true "重置令牌和新密码"
+// @Success 200 {object} response.Response "重置成功"
+// @Failure 400 {object} response.Response "请求参数错误或令牌无效"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /users/password/reset [post]
+func ResetPassword(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.ResetPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, validator.ErrorMessage(err))
+			return
+		}
+		ctx := c.Request.Context()
+		userID, err := svcCtx.Rbac.PasswordResetService.Consume(ctx, req.Token)
+		if errors.Is(err, rbac2.ErrResetTokenInvalid) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		// 重置链接不携带租户，进入令牌所属用户的租户
+		ctx, err = svcCtx.Rbac.UserService.TenantContext(ctx, userID)
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		user, err := svcCtx.Rbac.UserService.FindByID(ctx, userID)
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		if err = svcCtx.ChangePassword(ctx, userID, req.Password); err != nil {
+			// 令牌已使用，需重新申请
+			if errors.Is(err, rbac2.ErrPasswordReused) {
+				response.BadRequest(c, err.Error()+"，请重新申请重置")
+				return
+			}
+			handlePasswordError(c, err)
+			return
+		}
+		// 证明了邮箱所有权，解除因密码错误造成的临时锁定（安全封禁仍需管理员解锁）
+		if user.Status == consts.UserStatusLocked && user.LockedUntil != nil {
+			if err = svcCtx.Rbac.UserService.UnlockUser(ctx, userID); err != nil {
+				logrus.Errorf("failed to unlock user %d after password reset :%s", userID, err.Error())
+			}
+		}
+		svcCtx.LoginGuard.Reset(ctx, loginGuardKey(user, user.Username))
+		if err = svcCtx.Jwt.RevokeUserAllSessions(ctx, userID); err != nil {
+			response.Fail(c, 500, "密码已重置，但退出已登录设备失败: "+err.Error())
+			return
+		}
+		response.Success(c, "密码已重置，请重新登录")
+	}
+}
+
+// GetPasswordPolicy godoc
+// @Summary 密码策略
+// @Description 获取密码策略，用于注册、修改密码页面提示和前端校验
+// @Tags RBAC-用户管理
+// @Produce json
+// @Success 200 {object} response.Response{data=types.PasswordPolicyResponse} "获取成功"
+// @Router /users/password/policy [get]
+func GetPasswordPolicy(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := svcCtx.PasswordPolicy.Config()
+		response.Success(c, types.PasswordPolicyResponse{
+			MinLength:     cfg.MinLength,
+			MaxLength:     cfg.MaxLength,
+			RequireUpper:  cfg.RequireUpper,
+			RequireLower:  cfg.RequireLower,
+			RequireDigit:  cfg.RequireDigit,
+			RequireSymbol: cfg.RequireSymbol,
+			HistorySize:   cfg.HistorySize,
+			MaxAgeDays:    int(cfg.MaxAge.Hours() / 24),
+		})
+	}
+}
+
+// ChangeExpiredPassword godoc
+// @Summary 修改过期密码
+// @Description 密码超过有效期时登录只返回改密凭证，使用凭证设置新密码后完成登录；开启两步验证时返回两步验证凭证
+// @Tags RBAC-用户管理
+// @Accept json
+// @Produce json
+// @Param data body types.ChangeExpiredPasswordRequest true "改密凭证和新密码"
+// @Success 200 {object} response.Response{data=types.TokenResponse} "修改成功并登录"
+// @Failure 400 {object} response.Response "请求参数错误、凭证失效或密码不符合策略"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /users/password/expired [post]
+func ChangeExpiredPassword(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.ChangeExpiredPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, validator.ErrorMessage(err))
+			return
+		}
+		ctx := c.Request.Context()
+		userID, err := svcCtx.PasswordChangeTokenUser(ctx, req.ChangeToken)
+		if errors.Is(err, services.ErrPasswordChangeTokenInvalid) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		// 改密凭证不携带租户，进入凭证所属用户的租户
+		ctx, err = svcCtx.Rbac.UserService.TenantContext(ctx, userID)
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		c.Request = c.Request.WithContext(ctx)
+		if err = svcCtx.ChangePassword(ctx, userID, req.Password); err != nil {
+			handlePasswordError(c, err)
+			return
+		}
+		if err = svcCtx.RevokePasswordChangeToken(ctx, req.ChangeToken); err != nil {
+			logrus.Errorf("failed to revoke password change token of user %d :%s", userID, err.Error())
+		}
+		user, err := svcCtx.Rbac.UserService.FindByID(ctx, userID, _interface.WithPreloads("Roles"))
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		if e := rbac2.UserStatusError(user, time.Now()); e != nil {
+			response.Fail(c, e.Code, e.Message)
+			return
+		}
+		completeLogin(c, svcCtx, user)
+	}
+}
+
+// handlePasswordError 设置密码失败：不符合策略或与历史密码重复时提示用户，其他错误为服务器错误
+func handlePasswordError(c *gin.Context, err error) {
+	if isPasswordError(err) {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Fail(c, 500, "设置密码失败: "+err.Error())
+}
+
+// isPasswordError 新密码不符合策略或与历史密码重复
+func isPasswordError(err error) bool {
+	var policyErr *password.PolicyError
+	return errors.As(err, &policyErr) || errors.Is(err, rbac2.ErrPasswordReused)
+}
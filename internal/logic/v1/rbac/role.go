@@ -1,16 +1,20 @@
 package rbac
 
 import (
+	"errors"
 	"fmt"
 	rbac2 "gin-admin/internal/model/rbac"
 	"gin-admin/internal/services"
+	rbac3 "gin-admin/internal/services/rbac"
 	types "gin-admin/internal/types/rbac"
+	"gin-admin/pkg/consts"
 	_interface "gin-admin/pkg/interface"
 	"gin-admin/pkg/response"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 )
@@ -48,6 +52,11 @@ func CreateRole(svcCtx *services.ServiceContext) gin.HandlerFunc {
 			Name:        request.Name,
 			Description: request.Description,
 			Status:      request.Status,
+			RequireMFA:  request.RequireMFA,
+			DataScope:   request.DataScope,
+		}
+		if request.DataScope == consts.DataScopeCustom {
+			role.DataScopeDeptIDs = request.DataScopeDeptIDs
 		}
 		if err = svcCtx.Rbac.RoleService.Create(c.Request.Context(), role); err != nil {
 			response.Fail(c, 500, err.Error())
@@ -106,11 +115,19 @@ func GetRole(svcCtx *services.ServiceContext) gin.HandlerFunc {
 			response.BadRequest(c, "无效的角色ID")
 			return
 		}
-		role, err := svcCtx.Rbac.RoleService.FindByID(c.Request.Context(), uint(id), _interface.WithPreloads("Resources"))
+		role, err := svcCtx.Rbac.RoleService.FindByID(c.Request.Context(), uint(id), _interface.WithPreloads("Resources", "DeniedResources", "Parents"))
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		conditions, err := svcCtx.Rbac.RoleService.ResourceConditions(c.Request.Context(), role.ID)
 		if err != nil {
 			response.Fail(c, 500, err.Error())
 			return
 		}
+		for i := range role.Resources {
+			role.Resources[i].Condition = conditions[role.Resources[i].ID]
+		}
 		response.Success(c, role)
 	}
 }
@@ -167,7 +184,11 @@ func UpdateRole(svcCtx *services.ServiceContext) gin.HandlerFunc {
 			"name":        request.Name,
 			"description": request.Description,
 			"status":      request.Status,
+			"require_mfa": request.RequireMFA,
 		})
+		if err == nil && request.DataScope != consts.DataScopeUnknown {
+			err = svcCtx.Rbac.RoleService.UpdateDataScope(c.Request.Context(), uint(id), request.DataScope, request.DataScopeDeptIDs)
+		}
 		if err != nil {
 			response.Fail(c, 500, err.Error())
 			return
@@ -178,7 +199,7 @@ func UpdateRole(svcCtx *services.ServiceContext) gin.HandlerFunc {
 
 // DeleteRole godoc
 // @Summary 删除角色
-// @Description 根据ID删除角色
+// @Description 根据ID删除角色，下级角色不再继承该角色的资源
 // @Tags RBAC-角色管理
 // @Accept json
 // @Produce json
@@ -196,7 +217,10 @@ func DeleteRole(svcCtx *services.ServiceContext) gin.HandlerFunc {
 			response.BadRequest(c, "无效的角色ID")
 			return
 		}
-		if err = svcCtx.Rbac.RoleService.DeleteByID(c.Request.Context(), uint(id)); err != nil {
+		err = svcCtx.CacheService.ClearRoleUsersPermissions(c.Request.Context(), uint(id), time.Millisecond*50, svcCtx.Rbac.RoleService.ListRoleTreeUsers, func() error {
+			return svcCtx.Rbac.RoleService.DeleteRole(c.Request.Context(), uint(id))
+		})
+		if err != nil {
 			response.Fail(c, 500, err.Error())
 			return
 		}
@@ -206,14 +230,16 @@ func DeleteRole(svcCtx *services.ServiceContext) gin.HandlerFunc {
 
 // AssignRoleResources godoc
 // @Summary 绑定资源权限
-// @Description 根据角色ID，为角色绑定资源权限
+// @Description 根据角色ID，为角色绑定资源权限，下级角色同时继承这些资源
+// @Description 可以为资源设置授权条件，只有条件成立时才授权，条件在保存时校验
 // @Tags RBAC-角色管理
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
 // @Param id path int true "角色ID"
+// @Param data body types.AssignResource true "资源和授权条件"
 // @Success 204 {object} response.Response "成功绑定资源"
-// @Failure 400 {object} response.Response "无效的角色ID"
+// @Failure 400 {object} response.Response "无效的角色ID或授权条件"
 // @Failure 401 {object} response.Response "未授权"
 // @Failure 500 {object} response.Response "服务器内部错误"
 // @Router /roles/{id} [delete]
@@ -229,6 +255,19 @@ func AssignRoleResources(svcCtx *services.ServiceContext) gin.HandlerFunc {
 			response.BadRequest(c, err.Error())
 			return
 		}
+		for resourceId, cond := range request.Conditions {
+			if !slices.Contains(request.ResourceIds, resourceId) {
+				response.BadRequest(c, fmt.Sprintf("资源 %d 未授权，不能设置条件", resourceId))
+				return
+			}
+			if cond == "" {
+				continue
+			}
+			if _, err = rbac3.CompileCondition(cond); err != nil {
+				response.BadRequest(c, fmt.Sprintf("资源 %d 的授权条件无效：%s", resourceId, err.Error()))
+				return
+			}
+		}
 		role, err := svcCtx.Rbac.RoleService.FindByID(c.Request.Context(), uint(id))
 		if err != nil {
 			logrus.Errorf("failed to find role by id %d, %v", id, err)
@@ -241,20 +280,122 @@ func AssignRoleResources(svcCtx *services.ServiceContext) gin.HandlerFunc {
 			response.Fail(c, 500, err.Error())
 			return
 		}
-		userIds, err := svcCtx.Rbac.RoleService.ListRoleUsers(uint(id))
+		// 相关用户（含下级角色的用户）的权限缓存要清理
+		err = svcCtx.CacheService.ClearRoleUsersPermissions(c.Request.Context(), uint(id), time.Millisecond*50, svcCtx.Rbac.RoleService.ListRoleTreeUsers, func() error {
+			return svcCtx.Rbac.RoleService.SetResources(c.Request.Context(), role, resources, request.Conditions)
+		})
 		if err != nil {
-			logrus.Errorf("failed to list role users by id %d, %v", id, err)
 			response.Fail(c, 500, err.Error())
 			return
 		}
-		// 相关用户的权限缓存要清理
-		err = svcCtx.CacheService.ClearMultipleUsersPermissions(c.Request.Context(), userIds, time.Millisecond*50, func() error {
-			return svcCtx.Rbac.RoleService.ReplaceAssociation(c.Request.Context(), role, "Resources", resources)
+		response.Success(c, nil)
+	}
+}
+
+// AssignRoleParents godoc
+// @Summary 设置上级角色
+// @Description 根据角色ID设置上级角色，角色继承所有上级角色（含间接上级）的资源；上级角色不能是自身或自身的下级角色
+// @Tags RBAC-角色管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "角色ID"
+// @Param data body types.AssignParent true "上级角色"
+// @Success 200 {object} response.Response "设置成功"
+// @Failure 400 {object} response.Response "无效的角色ID或存在循环继承"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 404 {object} response.Response "角色不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /roles/{id}/assign-parent [put]
+func AssignRoleParents(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			response.BadRequest(c, "无效的角色ID")
+			return
+		}
+		request := types.AssignParent{}
+		if err = c.ShouldBindJSON(&request); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		ctx := c.Request.Context()
+		role, err := svcCtx.Rbac.RoleService.FindByID(ctx, uint(id))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c, "角色不存在")
+			return
+		}
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		// 该角色及其下级角色的用户继承的资源都会变化
+		err = svcCtx.CacheService.ClearRoleUsersPermissions(ctx, role.ID, time.Millisecond*50, svcCtx.Rbac.RoleService.ListRoleTreeUsers, func() error {
+			return svcCtx.Rbac.RoleService.SetParents(ctx, role, request.ParentIds)
 		})
+		switch {
+		case errors.Is(err, rbac3.ErrRoleCycle):
+			response.BadRequest(c, err.Error())
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			response.NotFound(c, "上级角色不存在")
+		case err != nil:
+			response.Fail(c, 500, err.Error())
+		default:
+			response.Success(c, nil)
+		}
+	}
+}
+
+// AssignRoleDenies godoc
+// @Summary 设置禁止访问的资源
+// @Description 根据角色ID设置禁止访问的资源，下级角色同时继承；用户的任一角色禁止访问后，其他角色对该资源的授权都不生效
+// @Tags RBAC-角色管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "角色ID"
+// @Param data body types.AssignDeny true "禁止访问的资源"
+// @Success 200 {object} response.Response "设置成功"
+// @Failure 400 {object} response.Response "无效的角色ID或内置角色"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 404 {object} response.Response "角色或资源不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /roles/{id}/assign-deny [put]
+func AssignRoleDenies(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			response.BadRequest(c, "无效的角色ID")
+			return
+		}
+		request := types.AssignDeny{}
+		if err = c.ShouldBindJSON(&request); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		ctx := c.Request.Context()
+		role, err := svcCtx.Rbac.RoleService.FindByID(ctx, uint(id))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c, "角色不存在")
+			return
+		}
 		if err != nil {
 			response.Fail(c, 500, err.Error())
 			return
 		}
-		response.Success(c, nil)
+		// 该角色及其下级角色的用户的权限都会变化
+		err = svcCtx.CacheService.ClearRoleUsersPermissions(ctx, role.ID, time.Millisecond*50, svcCtx.Rbac.RoleService.ListRoleTreeUsers, func() error {
+			return svcCtx.Rbac.RoleService.SetDeniedResources(ctx, role, request.ResourceIds)
+		})
+		switch {
+		case errors.Is(err, rbac3.ErrRoleBuiltInDeny):
+			response.BadRequest(c, err.Error())
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			response.NotFound(c, "资源不存在")
+		case err != nil:
+			response.Fail(c, 500, err.Error())
+		default:
+			response.Success(c, nil)
+		}
 	}
 }
@@ -0,0 +1,57 @@
+package rbac
+
+import (
+	"gin-admin/internal/services"
+	types "gin-admin/internal/types/rbac"
+	_interface "gin-admin/pkg/interface"
+	"gin-admin/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/10 上午10:05
+* @Package: 安全事件审计
+ */
+
+// ListSecurityEvents godoc
+// @Summary 安全事件列表
+// @Description 分页查询安全事件（如 refresh token 重用），按时间倒序
+// @Tags RBAC-安全审计
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request query types.ListSecurityEventRequest true "查询参数"
+// @Success 200 {object} response.PaginatedResponse{data=[]rbac.SecurityEvent} "成功返回事件列表"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /security-events [get]
+func ListSecurityEvents(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		request := types.ListSecurityEventRequest{}
+		if err := c.ShouldBindQuery(&request); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		conditions := map[string]interface{}{}
+		if request.UserID > 0 {
+			conditions["user_id"] = request.UserID
+		}
+		if request.Type != "" {
+			conditions["type"] = request.Type
+		}
+		if request.ImpersonatorID > 0 {
+			conditions["impersonator_id"] = request.ImpersonatorID
+		}
+		pr, err := svcCtx.Rbac.SecurityEventService.FindPage(c.Request.Context(),
+			_interface.WithPagination(request.Page, request.PageSize),
+			_interface.WithConditions(conditions),
+			_interface.WithOrderBy("id DESC"))
+		if err != nil {
+			response.Fail(c, 500, "获取安全事件失败: "+err.Error())
+			return
+		}
+		response.SuccessPage(c, pr.List, pr.Page, pr.PageSize, pr.Total)
+	}
+}
@@ -0,0 +1,194 @@
+package rbac
+
+import (
+	"gin-admin/internal/services"
+	types "gin-admin/internal/types/rbac"
+	"gin-admin/pkg/components/jwt"
+	"gin-admin/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"time"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/8 下午3:20
+* @Package: 在线会话（设备）管理
+ */
+
+// toSessionItems 转换会话列表，标记当前会话
+func toSessionItems(sessions []*jwt.SessionInfo, currentSessionId string) []types.SessionItem {
+	items := make([]types.SessionItem, 0, len(sessions))
+	for _, s := range sessions {
+		items = append(items, types.SessionItem{
+			SessionID:  s.SessionID,
+			DeviceID:   s.DeviceID,
+			IP:         s.IP,
+			UserAgent:  s.UserAgent,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			ExpiresAt:  s.ExpiresAt,
+			Current:    s.SessionID == currentSessionId,
+			// 模拟登录会话
+			ImpersonatorID: s.ImpersonatorID,
+		})
+	}
+	return items
+}
+
+// ListMySessions godoc
+// @Summary 我的在线设备
+// @Description 获取当前用户所有在线会话（设备）列表
+// @Tags RBAC-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{data=[]types.SessionItem} "成功返回会话列表"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /users/sessions [get]
+func ListMySessions(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessions, err := svcCtx.Jwt.ListUserSessions(c.Request.Context(), c.GetUint("uid"))
+		if err != nil {
+			response.Fail(c, 500, "获取会话列表失败: "+err.Error())
+			return
+		}
+		response.Success(c, toSessionItems(sessions, c.GetString("sessionId")))
+	}
+}
+
+// RevokeMySession godoc
+// @Summary 下线我的其他设备
+// @Description 撤销当前用户的某个其他会话，当前会话请使用登出接口
+// @Tags RBAC-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param sid path string true "会话ID"
+// @Success 200 {object} response.Response "下线成功"
+// @Failure 400 {object} response.Response "不能下线当前会话"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 404 {object} response.Response "会话不存在"
+// @Router /users/sessions/{sid} [delete]
+func RevokeMySession(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sid := c.Param("sid")
+		if sid == c.GetString("sessionId") {
+			response.BadRequest(c, "不能下线当前会话，请使用登出")
+			return
+		}
+		ctx := c.Request.Context()
+		// 只能下线属于自己的会话
+		session := svcCtx.Jwt.GetSession(ctx, sid)
+		if session == nil || session.UserID != c.GetUint("uid") {
+			response.NotFound(c, "会话不存在")
+			return
+		}
+		if err := svcCtx.Jwt.RevokeSession(ctx, sid); err != nil {
+			response.Fail(c, 500, "下线会话失败: "+err.Error())
+			return
+		}
+		response.Success(c, nil)
+	}
+}
+
+// ListUserSessions godoc
+// @Summary 查询用户在线设备
+// @Description 管理员查看指定用户的在线会话（设备）列表
+// @Tags RBAC-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} response.Response{data=[]types.SessionItem} "成功返回会话列表"
+// @Failure 400 {object} response.Response "无效的用户ID"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /users/{id}/sessions [get]
+func ListUserSessions(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			response.BadRequest(c, "无效的用户ID")
+			return
+		}
+		sessions, err := svcCtx.Jwt.ListUserSessions(c.Request.Context(), uint(userID))
+		if err != nil {
+			response.Fail(c, 500, "获取会话列表失败: "+err.Error())
+			return
+		}
+		response.Success(c, toSessionItems(sessions, c.GetString("sessionId")))
+	}
+}
+
+// KickUserSession godoc
+// @Summary 踢下线用户设备
+// @Description 管理员撤销指定用户的某个会话
+// @Tags RBAC-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Param sid path string true "会话ID"
+// @Success 200 {object} response.Response "下线成功"
+// @Failure 400 {object} response.Response "无效的用户ID"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 404 {object} response.Response "会话不存在"
+// @Router /users/{id}/sessions/{sid} [delete]
+func KickUserSession(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			response.BadRequest(c, "无效的用户ID")
+			return
+		}
+		ctx := c.Request.Context()
+		sid := c.Param("sid")
+		session := svcCtx.Jwt.GetSession(ctx, sid)
+		if session == nil || session.UserID != uint(userID) {
+			response.NotFound(c, "会话不存在")
+			return
+		}
+		if err = svcCtx.Jwt.RevokeSession(ctx, sid); err != nil {
+			response.Fail(c, 500, "下线会话失败: "+err.Error())
+			return
+		}
+		logrus.Infof("user %d kicked session %s of user %d", c.GetUint("uid"), sid, userID)
+		response.Success(c, nil)
+	}
+}
+
+// KickUserAllSessions godoc
+// @Summary 踢下线用户所有设备
+// @Description 管理员撤销指定用户的全部会话
+// @Tags RBAC-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} response.Response "下线成功"
+// @Failure 400 {object} response.Response "无效的用户ID"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /users/{id}/sessions [delete]
+func KickUserAllSessions(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			response.BadRequest(c, "无效的用户ID")
+			return
+		}
+		ctx := c.Request.Context()
+		if err = svcCtx.CacheService.ClearUserPermissions(ctx, uint(userID), time.Millisecond*5, func() error {
+			return svcCtx.Jwt.RevokeUserAllSessions(ctx, uint(userID))
+		}); err != nil {
+			response.Fail(c, 500, "下线会话失败: "+err.Error())
+			return
+		}
+		logrus.Infof("user %d kicked all sessions of user %d", c.GetUint("uid"), userID)
+		response.Success(c, nil)
+	}
+}
@@ -0,0 +1,201 @@
+package rbac
+
+import (
+	"errors"
+	rbac2 "gin-admin/internal/model/rbac"
+	"gin-admin/internal/services"
+	types "gin-admin/internal/types/rbac"
+	_interface "gin-admin/pkg/interface"
+	"gin-admin/pkg/response"
+	"gin-admin/pkg/validator"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/17 上午9:30
+* @Package: 租户管理，仅平台超级管理员可用
+ */
+
+// GetTenants godoc
+// @Summary 获取租户列表
+// @Description 分页获取租户列表，平台租户（ID 0）不在列表中
+// @Tags RBAC-租户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request query types.ListTenantRequest true "查询参数"
+// @Success 200 {object} response.PaginatedResponse{data=[]rbac.Tenant} "成功获取租户列表"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "不是平台超级管理员"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /tenants [get]
+func GetTenants(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		request := types.ListTenantRequest{}
+		if err := c.ShouldBindQuery(&request); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		result, err := svcCtx.Rbac.TenantService.FindPage(c.Request.Context(),
+			_interface.WithPagination(request.Page, request.PageSize),
+			_interface.WithScopes(func(db *gorm.DB) *gorm.DB {
+				if request.Name != "" {
+					db = db.Where("name LIKE ?", request.Name+"%")
+				}
+				if request.Code != "" {
+					db = db.Where("code = ?", request.Code)
+				}
+				if request.Status > 0 {
+					db = db.Where("status = ?", request.Status)
+				}
+				return db
+			}))
+		if err != nil {
+			response.Fail(c, 500, "获取租户列表失败")
+			return
+		}
+		response.SuccessPage(c, result.List, result.Page, result.PageSize, result.Total)
+	}
+}
+
+// GetTenant godoc
+// @Summary 获取租户详情
+// @Description 根据ID获取租户详细信息
+// @Tags RBAC-租户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "租户ID"
+// @Success 200 {object} response.Response{data=rbac.Tenant} "成功获取租户详情"
+// @Failure 400 {object} response.Response "无效的租户ID"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "不是平台超级管理员"
+// @Failure 404 {object} response.Response "租户不存在"
+// @Router /tenants/{id} [get]
+func GetTenant(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			response.BadRequest(c, "无效的租户ID")
+			return
+		}
+		t, err := svcCtx.Rbac.TenantService.FindByID(c.Request.Context(), uint(id))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c, "租户不存在")
+			return
+		}
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		response.Success(c, t)
+	}
+}
+
+// CreateTenant godoc
+// @Summary 创建租户
+// @Description 创建租户，同时在租户内创建拥有全部资源的超级管理员角色和管理员账号
+// @Tags RBAC-租户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param data body types.CreateTenantRequest true "租户和管理员信息"
+// @Success 201 {object} response.Response{data=rbac.Tenant} "成功创建租户"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "不是平台超级管理员"
+// @Failure 409 {object} response.Response "租户编码已存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /tenants [post]
+func CreateTenant(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request types.CreateTenantRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			response.BadRequest(c, validator.ErrorMessage(err))
+			return
+		}
+		ctx := c.Request.Context()
+		exist, err := svcCtx.Rbac.TenantService.Exists(ctx, _interface.WithConditions(map[string]interface{}{"code": request.Code}))
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		if exist {
+			response.Fail(c, http.StatusConflict, "租户编码已存在")
+			return
+		}
+		t := &rbac2.Tenant{Name: request.Name, Code: request.Code}
+		err = svcCtx.CreateTenant(ctx, t, services.TenantAdmin{
+			Username: request.AdminUsername,
+			Email:    request.AdminEmail,
+			Password: request.AdminPassword,
+		})
+		if err != nil {
+			response.Fail(c, 500, "创建租户失败: "+err.Error())
+			return
+		}
+		response.Created(c, t)
+	}
+}
+
+// UpdateTenant godoc
+// @Summary 更新租户
+// @Description 修改租户名称和状态，禁用后租户下的所有账号（含已登录的）都无法访问
+// @Tags RBAC-租户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "租户ID"
+// @Param data body types.UpdateTenantRequest true "租户信息"
+// @Success 200 {object} response.Response "成功更新租户"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "不是平台超级管理员"
+// @Failure 404 {object} response.Response "租户不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /tenants/{id} [put]
+func UpdateTenant(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			response.BadRequest(c, "无效的租户ID")
+			return
+		}
+		var request types.UpdateTenantRequest
+		if err = c.ShouldBindJSON(&request); err != nil {
+			response.BadRequest(c, validator.ErrorMessage(err))
+			return
+		}
+		ctx := c.Request.Context()
+		exist, err := svcCtx.Rbac.TenantService.ExistsByID(ctx, uint(id))
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		if !exist {
+			response.NotFound(c, "租户不存在")
+			return
+		}
+		updates := map[string]interface{}{}
+		if request.Name != "" {
+			updates["name"] = request.Name
+		}
+		if request.Status > 0 {
+			updates["status"] = request.Status
+		}
+		if len(updates) == 0 {
+			response.Success(c, "更新成功")
+			return
+		}
+		if err = svcCtx.Rbac.TenantService.UpdateByID(ctx, uint(id), updates); err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		response.Success(c, "更新成功")
+	}
+}
@@ -2,25 +2,35 @@ package rbac
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"gin-admin/internal/middleware"
 	"gin-admin/internal/model/rbac"
 	"gin-admin/internal/services"
+	rbac2 "gin-admin/internal/services/rbac"
 	types "gin-admin/internal/types/rbac"
+	"gin-admin/pkg/components/jwt"
 	"gin-admin/pkg/consts"
+	"gin-admin/pkg/errcode"
 	_interface "gin-admin/pkg/interface"
 	"gin-admin/pkg/response"
+	"gin-admin/pkg/validator"
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// DeviceIDHeader 客户端可通过该请求头上报设备ID，未上报时自动生成
+const DeviceIDHeader = "X-Device-ID"
+
 // Register godoc
 // @Summary 用户注册
-// @Description 创建新用户账号
+// @Description 创建新用户账号；开启邮箱验证时用户为待验证状态，需通过验证邮件中的链接验证后才能登录
 // @Tags RBAC-用户管理
 // @Accept json
 // @Produce json
@@ -33,7 +43,7 @@ func Register(svcCtx *services.ServiceContext) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req types.UserRegisterRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
-			response.BadRequest(c, err.Error())
+			response.BadRequest(c, validator.ErrorMessage(err))
 			return
 		}
 		if err := svcCtx.Rbac.UserService.CheckAccountExist(c.Request.Context(), req.Username, req.Email); nil != err {
@@ -42,20 +52,32 @@ func Register(svcCtx *services.ServiceContext) gin.HandlerFunc {
 		}
 		user := &rbac.User{
 			Username: req.Username,
-			Password: req.Password,
 			Email:    req.Email,
 		}
+		verify := svcCtx.EmailVerificationEnabled()
+		if verify {
+			user.Status = consts.UserStatusPending
+		}
+		user.SetPassword(req.Password)
 		if err := svcCtx.Rbac.UserService.Create(c, user); err != nil {
 			response.Fail(c, 400, err.Error())
 			return
 		}
+		// 注册同样计入该邮箱的发送次数，被限制时用户可稍后重新发送
+		if verify {
+			if wait, err := svcCtx.AllowVerificationMail(c.Request.Context(), user.Email); err != nil {
+				logrus.Errorf("failed to check verification mail limit of user %d :%s", user.ID, err.Error())
+			} else if wait == 0 {
+				sendVerificationMail(c, svcCtx, user)
+			}
+		}
 		response.Success(c, user)
 	}
 }
 
 // Login godoc
 // @Summary 用户登录
-// @Description 用户登录并获取JWT令牌（Access Token + Refresh Token）
+// @Description 用户登录并获取JWT令牌（Access Token + Refresh Token）；开启两步验证的用户返回 types.MFAChallengeResponse，需调用 /users/login/mfa 换取令牌；密码超过有效期时返回 types.PasswordExpiredResponse，需调用 /users/password/expired 修改密码
 // @Tags RBAC-用户管理
 // @Accept json
 // @Produce json
@@ -63,6 +85,7 @@ func Register(svcCtx *services.ServiceContext) gin.HandlerFunc {
 // @Success 200 {object} response.Response{data=types.TokenResponse} "登录成功返回令牌对"
 // @Failure 400 {object} response.Response "请求参数错误"
 // @Failure 401 {object} response.Response "用户名或密码错误"
+// @Failure 429 {object} response.Response "登录失败次数过多"
 // @Failure 500 {object} response.Response "服务器内部错误"
 // @Router /users/login [post]
 func Login(svcCtx *services.ServiceContext) gin.HandlerFunc {
@@ -72,40 +95,194 @@ func Login(svcCtx *services.ServiceContext) gin.HandlerFunc {
 			response.BadRequest(c, err.Error())
 			return
 		}
-		user, err := svcCtx.Rbac.UserService.FindOne(c, _interface.WithScopes(func(db *gorm.DB) *gorm.DB {
+		ctx := c.Request.Context()
+		ip := c.ClientIP()
+		user, err := svcCtx.Rbac.UserService.FindOne(ctx, _interface.WithScopes(func(db *gorm.DB) *gorm.DB {
 			return db.Where("username = ? OR email = ?", req.Account, req.Account)
-		}))
-		if err != nil {
+		}), _interface.WithPreloads("Roles"))
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 			response.Fail(c, 500, err.Error())
 			return
 		}
-		if err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-			response.Fail(c, http.StatusForbidden, "密码错误")
+		guardKey := loginGuardKey(user, req.Account)
+		// 失败次数过多，需要等待后再试
+		if wait := svcCtx.LoginGuard.Allow(ctx, guardKey, ip); wait > 0 {
+			seconds := int(math.Ceil(wait.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			response.FailWithStatus(c, http.StatusTooManyRequests, errcode.TooManyRequests, fmt.Sprintf("登录失败次数过多，请 %d 秒后重试", seconds))
 			return
 		}
-		// 生成JWT令牌对
-		tokenPair, err := svcCtx.Jwt.GenerateTokenPair(c, user.ID, user.Username, user.Email)
+		if user == nil {
+			svcCtx.LoginGuard.Fail(ctx, guardKey, ip)
+			response.Fail(c, errcode.LoginFailed, "账号或密码错误")
+			return
+		}
+		// 锁定中的账号不再校验密码，避免锁定期间继续被猜测
+		now := time.Now()
+		if user.Locked(now) {
+			e := rbac2.UserStatusError(user, now)
+			response.Fail(c, e.Code, e.Message)
+			return
+		}
+		if user.Status == consts.UserStatusLocked {
+			// 临时锁定已到期
+			if err = svcCtx.Rbac.UserService.UnlockUser(ctx, user.ID); err != nil {
+				response.Fail(c, 500, err.Error())
+				return
+			}
+			user.Status = consts.UserStatusActive
+		}
+		matched, rehash := user.CheckPassword(req.Password)
+		if !matched {
+			if _, lock := svcCtx.LoginGuard.Fail(ctx, guardKey, ip); lock {
+				until := now.Add(svcCtx.LoginGuard.LockDuration())
+				if err = svcCtx.Rbac.UserService.LockUserUntil(ctx, user.ID, until); err != nil {
+					response.Fail(c, 500, err.Error())
+					return
+				}
+				response.Fail(c, errcode.UserLocked, fmt.Sprintf("密码错误次数过多，账号已被锁定，请于 %s 后重试", until.Format("2006-01-02 15:04:05")))
+				return
+			}
+			response.Fail(c, errcode.LoginFailed, "账号或密码错误")
+			return
+		}
+		if e := rbac2.UserStatusError(user, now); e != nil {
+			response.Fail(c, e.Code, e.Message)
+			return
+		}
+		// 哈希算法或参数已调整，借登录时拿到的明文重新计算，失败不影响本次登录
+		if rehash {
+			if err = svcCtx.Rbac.UserService.RehashPassword(ctx, user, req.Password); err != nil {
+				logrus.Errorf("failed to rehash password of user %d :%s", user.ID, err.Error())
+			}
+		}
+		// 密码已过期，先修改密码再继续登录
+		if svcCtx.PasswordPolicy.Expired(user.PasswordSetAt(), now) {
+			changeToken, ttl, err := svcCtx.IssuePasswordChangeToken(ctx, user.ID)
+			if err != nil {
+				response.Fail(c, 500, err.Error())
+				return
+			}
+			response.Success(c, types.PasswordExpiredResponse{
+				PasswordExpired: true,
+				ChangeToken:     changeToken,
+				ExpiresIn:       int64(ttl.Seconds()),
+			})
+			return
+		}
+		completeLogin(c, svcCtx, user)
+	}
+}
+
+// completeLogin 身份校验通过后的收尾：开启了两步验证（或角色要求开启）时先返回两步验证凭证，否则直接签发令牌
+// 失败计数在签发令牌时才清零，两步验证的口令错误与密码错误共用计数
+func completeLogin(c *gin.Context, svcCtx *services.ServiceContext, user *rbac.User) {
+	ctx := c.Request.Context()
+	mfaEnabled, mfaRequired, err := svcCtx.Rbac.MFAService.State(ctx, user.ID)
+	if err != nil {
+		response.Fail(c, 500, err.Error())
+		return
+	}
+	if mfaEnabled || mfaRequired {
+		mfaToken, err := svcCtx.MFAChallenges.Create(ctx, services.MFAChallenge{UserID: user.ID, Setup: !mfaEnabled})
 		if err != nil {
 			response.Fail(c, 500, err.Error())
 			return
 		}
-		// 返回令牌对
-		tokenResponse := types.TokenResponse{
-			AccessToken:  tokenPair.AccessToken,
-			RefreshToken: tokenPair.RefreshToken,
-			TokenType:    tokenPair.TokenType,
-			ExpiresIn:    tokenPair.ExpiresIn,
-		}
-		// 设置刷新token
-		c.SetCookie("X-Refresh-Token",
-			tokenPair.RefreshToken,
-			int(svcCtx.Config.Jwt.RefreshTokenExpire.Seconds()),
-			"/",
-			"",
-			false,
-			true)
-		response.Success(c, tokenResponse)
+		response.Success(c, types.MFAChallengeResponse{
+			MFARequired:   true,
+			SetupRequired: !mfaEnabled,
+			MFAToken:      mfaToken,
+			ExpiresIn:     int64(svcCtx.MFAChallenges.TTL().Seconds()),
+		})
+		return
+	}
+	svcCtx.LoginGuard.Reset(ctx, loginGuardKey(user, ""))
+	tokenResponse, ok := issueTokenPair(c, svcCtx, user)
+	if !ok {
+		return
+	}
+	response.Success(c, tokenResponse)
+}
+
+// issueTokenPair 生成JWT令牌对并写入刷新token的cookie，记录设备信息用于在线设备管理
+// 失败时已写入错误响应，返回 false
+func issueTokenPair(c *gin.Context, svcCtx *services.ServiceContext, user *rbac.User) (*types.TokenResponse, bool) {
+	roles := make([]string, 0, len(user.Roles))
+	for _, role := range user.Roles {
+		roles = append(roles, role.Name)
+	}
+	tokenOpts := []jwt.TokenOption{
+		jwt.WithClientInfo(c.ClientIP(), c.Request.UserAgent()),
+		jwt.WithRoles(roles...),
+		jwt.WithTenant(user.TenantID),
+	}
+	if deviceID := c.GetHeader(DeviceIDHeader); deviceID != "" {
+		tokenOpts = append(tokenOpts, jwt.WithDeviceID(deviceID))
+	}
+	tokenPair, err := svcCtx.Jwt.GenerateTokenPair(c.Request.Context(), user.ID, user.Username, user.Email, tokenOpts...)
+	if errors.Is(err, jwt.ErrSessionLimitExceeded) {
+		response.Fail(c, errcode.SessionLimitExceeded, errcode.GetMessage(errcode.SessionLimitExceeded))
+		return nil, false
+	}
+	if err != nil {
+		response.Fail(c, 500, err.Error())
+		return nil, false
+	}
+	// 设置刷新token
+	middleware.SetRefreshCookie(c, svcCtx, tokenPair.RefreshToken)
+	return &types.TokenResponse{
+		AccessToken:  tokenPair.AccessToken,
+		RefreshToken: tokenPair.RefreshToken,
+		TokenType:    tokenPair.TokenType,
+		ExpiresIn:    tokenPair.ExpiresIn,
+	}, true
+}
+
+// loginGuardKey 登录失败计数的账号维度：已存在的用户按ID统计，避免用户名、邮箱交替尝试绕过限制
+func loginGuardKey(user *rbac.User, account string) string {
+	if user != nil {
+		return "user:" + strconv.FormatUint(uint64(user.ID), 10)
 	}
+	return "account:" + strings.ToLower(account)
+}
+
+// allowCredentialCheck 已登录用户再次校验凭证（密码、两步验证码）前检查失败限制，与登录共用失败计数
+// 失败次数过多时需等待后再试，返回 false 时已写入响应
+func allowCredentialCheck(c *gin.Context, svcCtx *services.ServiceContext, user *rbac.User) bool {
+	wait := svcCtx.LoginGuard.Allow(c.Request.Context(), loginGuardKey(user, ""), c.ClientIP())
+	if wait <= 0 {
+		return true
+	}
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	response.FailWithStatus(c, http.StatusTooManyRequests, errcode.TooManyRequests, fmt.Sprintf("验证失败次数过多，请 %d 秒后重试", seconds))
+	return false
+}
+
+// failCredentialCheck 记录一次凭证校验失败并写入错误响应
+// 连续失败次数用尽时临时锁定账号，锁定后已登录的会话同样无法继续访问，避免被盗用的会话用于猜测凭证
+func failCredentialCheck(c *gin.Context, svcCtx *services.ServiceContext, user *rbac.User, code int, message string) {
+	if recordCredentialFailure(c, svcCtx, user) {
+		return
+	}
+	response.Fail(c, code, message)
+}
+
+// recordCredentialFailure 记录一次凭证校验失败，次数用尽时临时锁定账号
+// 返回 true 时账号已锁定（或锁定失败）且已写入响应
+func recordCredentialFailure(c *gin.Context, svcCtx *services.ServiceContext, user *rbac.User) bool {
+	ctx := c.Request.Context()
+	if _, lock := svcCtx.LoginGuard.Fail(ctx, loginGuardKey(user, ""), c.ClientIP()); !lock {
+		return false
+	}
+	until := time.Now().Add(svcCtx.LoginGuard.LockDuration())
+	if err := svcCtx.Rbac.UserService.LockUserUntil(ctx, user.ID, until); err != nil {
+		response.Fail(c, 500, err.Error())
+		return true
+	}
+	response.Fail(c, errcode.UserLocked, fmt.Sprintf("验证失败次数过多，账号已被锁定，请于 %s 后重试", until.Format("2006-01-02 15:04:05")))
+	return true
 }
 
 // Logout godoc
@@ -122,16 +299,66 @@ func Logout(svcCtx *services.ServiceContext) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID := c.GetUint("uid")
 		sessionId := c.GetString("sessionId")
-		if err := svcCtx.CacheService.ClearUserPermissions(c, userID, time.Millisecond*5, func() error {
+		if err := svcCtx.CacheService.ClearUserPermissions(c.Request.Context(), userID, time.Millisecond*5, func() error {
 			return svcCtx.Jwt.RevokeSession(c, sessionId)
 		}); err != nil {
 			response.Fail(c, 200, err.Error())
 			return
 		}
+		middleware.ClearRefreshCookie(c, svcCtx)
 		response.Success(c, "登出成功")
 	}
 }
 
+// RefreshToken godoc
+// @Summary 刷新令牌
+// @Description 用刷新令牌换取新的令牌对，旧的刷新令牌随即失效
+// @Description 浏览器使用 Cookie 中的刷新令牌时，需要在 X-CSRF-Token 请求头中回传同名 Cookie 的值；其他客户端可在请求体中传 refresh_token
+// @Tags RBAC-用户管理
+// @Accept json
+// @Produce json
+// @Param data body types.RefreshTokenRequest false "刷新令牌，不传时使用 Cookie"
+// @Success 200 {object} response.Response{data=types.TokenResponse} "刷新成功"
+// @Failure 401 {object} response.Response "刷新令牌无效或已过期"
+// @Failure 403 {object} response.Response "CSRF 校验失败"
+// @Router /users/refresh [post]
+func RefreshToken(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		request := types.RefreshTokenRequest{}
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&request); err != nil {
+				response.BadRequest(c, validator.ErrorMessage(err))
+				return
+			}
+		}
+		// 请求体中的刷新令牌不写 Cookie，避免覆盖浏览器中其他会话（如模拟登录）的 Cookie
+		fromCookie := request.RefreshToken == ""
+		refreshToken := request.RefreshToken
+		if fromCookie {
+			var ok bool
+			if refreshToken, ok = middleware.RefreshTokenFromCookie(c, svcCtx); !ok {
+				if !c.IsAborted() {
+					response.FailWithStatus(c, http.StatusUnauthorized, errcode.TokenMissing, "请先登录")
+				}
+				return
+			}
+		}
+		tokenPair, _, ok := middleware.RefreshSession(c, svcCtx, refreshToken)
+		if !ok {
+			return
+		}
+		if fromCookie {
+			middleware.SetRefreshCookie(c, svcCtx, tokenPair.RefreshToken)
+		}
+		response.Success(c, types.TokenResponse{
+			AccessToken:  tokenPair.AccessToken,
+			RefreshToken: tokenPair.RefreshToken,
+			TokenType:    tokenPair.TokenType,
+			ExpiresIn:    tokenPair.ExpiresIn,
+		})
+	}
+}
+
 // GetProfile godoc
 // @Summary 获取用户个人资料
 // @Description 获取当前登录用户的完整资料（包括角色、权限和可访问资源）
@@ -201,9 +428,52 @@ func DeleteUser(svcCtx *services.ServiceContext) gin.HandlerFunc {
 	}
 }
 
+// UnlockUser godoc
+// @Summary 解锁用户
+// @Description 解除用户锁定（登录失败锁定、安全封禁），并清除登录失败计数
+// @Tags RBAC-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} response.Response "解锁成功"
+// @Failure 400 {object} response.Response "请求格式错误"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /users/{id}/unlock [put]
+func UnlockUser(svcCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			response.BadRequest(c, "无效的用户ID")
+			return
+		}
+		ctx := c.Request.Context()
+		user, err := svcCtx.Rbac.UserService.FindByID(ctx, uint(userID))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c, "用户不存在")
+			return
+		}
+		if err != nil {
+			response.Fail(c, 500, err.Error())
+			return
+		}
+		if user.Status != consts.UserStatusLocked {
+			response.Fail(c, http.StatusBadRequest, "用户未被锁定")
+			return
+		}
+		if err = svcCtx.Rbac.UserService.UnlockUser(ctx, user.ID); err != nil {
+			response.Fail(c, 500, "解锁用户失败: "+err.Error())
+			return
+		}
+		svcCtx.LoginGuard.Reset(ctx, loginGuardKey(user, user.Username))
+		response.Success(c, "解锁成功")
+	}
+}
+
 // CreateUser godoc
 // @Summary 创建用户
-// @Description 系统内部管理员创建用户，密码默认就是邮箱号
+// @Description 系统内部管理员创建用户，初始密码必填且需符合密码策略
 // @Tags RBAC-用户管理
 // @Accept json
 // @Produce json
@@ -217,13 +487,21 @@ func CreateUser(svcCtx *services.ServiceContext) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		request := types.UpsertUserRequest{}
 		if err := c.ShouldBindJSON(&request); err != nil {
-			response.BadRequest(c, err.Error())
+			response.BadRequest(c, validator.ErrorMessage(err))
+			return
+		}
+		// 初始密码必填，避免使用邮箱前缀等可猜测的默认密码
+		if request.Password == "" {
+			response.BadRequest(c, "请设置初始密码")
 			return
 		}
 		if err := svcCtx.Rbac.UserService.CheckAccountExist(c.Request.Context(), request.Username, request.Email); nil != err {
 			response.Fail(c, 500, err.Error())
 			return
 		}
+		if !checkUserDept(c, svcCtx, request.DeptID) {
+			return
+		}
 		roles, err := svcCtx.Rbac.RoleService.List(c.Request.Context())
 		if err != nil {
 			response.Fail(c, 500, err.Error())
@@ -232,12 +510,13 @@ func CreateUser(svcCtx *services.ServiceContext) gin.HandlerFunc {
 		// ==== 创建 ====
 		user := rbac.User{
 			Username: request.Username,
-			Password: strings.Split(request.Email, "@")[0],
 			Email:    request.Email,
 			Gender:   request.Gender,
 			Roles:    roles,
 			Status:   consts.UserStatusActive,
+			DeptID:   request.DeptID,
 		}
+		user.SetPassword(request.Password)
 		if err = svcCtx.Rbac.UserService.Create(c.Request.Context(), &user); err != nil {
 			response.Fail(c, 500, err.Error())
 			return
@@ -270,7 +549,7 @@ func UpdateUser(svcCtx *services.ServiceContext) gin.HandlerFunc {
 		}
 		request := types.UpsertUserRequest{}
 		if err := c.ShouldBindJSON(&request); err != nil {
-			response.BadRequest(c, err.Error())
+			response.BadRequest(c, validator.ErrorMessage(err))
 			return
 		}
 
@@ -297,15 +576,30 @@ func UpdateUser(svcCtx *services.ServiceContext) gin.HandlerFunc {
 			response.Fail(c, http.StatusConflict, "邮箱已存在")
 			return
 		}
+		if !checkUserDept(c, svcCtx, request.DeptID) {
+			return
+		}
+		// 模拟登录状态下不能修改任何人的密码
+		if request.Password != "" && c.GetUint("impersonatorId") != 0 {
+			response.Forbidden(c, services.ErrImpersonationNested.Error())
+			return
+		}
 		err = svcCtx.Rbac.UserService.Transaction(c.Request.Context(), func(ctx context.Context, tx *gorm.DB, txRepo _interface.IRepo[rbac.User]) error {
 			err = txRepo.UpdateByID(ctx, request.Id, map[string]interface{}{
 				"username": request.Username,
 				"email":    request.Email,
 				"gender":   request.Gender,
+				"dept_id":  request.DeptID,
 			})
 			if err != nil {
 				return err
 			}
+			// 密码与其他信息一起提交，密码不符合策略时整体回滚
+			if request.Password != "" {
+				if err = svcCtx.ChangePasswordTx(tx, request.Id, request.Password); err != nil {
+					return err
+				}
+			}
 			var roles []rbac.Role
 			// 获取所有角色列表
 			if len(request.Roles) != 0 {
@@ -320,17 +614,27 @@ func UpdateUser(svcCtx *services.ServiceContext) gin.HandlerFunc {
 			}
 			return nil
 		})
+		if isPasswordError(err) {
+			response.BadRequest(c, err.Error())
+			return
+		}
 		if err != nil {
 			response.Fail(c, 500, err.Error())
 			return
 		}
+		// 管理员重设密码后，该用户已登录的设备全部下线
+		if request.Password != "" {
+			if err = svcCtx.Jwt.RevokeUserAllSessions(c.Request.Context(), request.Id); err != nil {
+				logrus.Errorf("failed to revoke sessions of user %d after password change :%s", request.Id, err.Error())
+			}
+		}
 		response.Success(c, "更新成功")
 	}
 }
 
 // ListUser godoc
 // @Summary 获取用户列表
-// @Description 获取所有用户信息列表（支持分页）
+// @Description 获取用户信息列表（支持分页），只返回当前用户数据权限范围内的用户
 // @Tags RBAC-用户管理
 // @Accept json
 // @Produce json
@@ -348,7 +652,21 @@ func ListUser(svcCtx *services.ServiceContext) gin.HandlerFunc {
 			response.BadRequest(c, err.Error())
 			return
 		}
-		pr, err := svcCtx.Rbac.UserService.FindPage(c.Request.Context(), _interface.WithPagination(request.Page, request.PageSize),
+		ctx := c.Request.Context()
+		// 数据权限按当前用户所属租户的角色计算
+		dataScope, err := svcCtx.Rbac.RoleService.UserDataScope(middleware.HomeContext(c), c.GetUint("uid"))
+		if err != nil {
+			response.Fail(c, 500, "获取数据权限失败: "+err.Error())
+			return
+		}
+		var deptIds []uint
+		if request.DeptID > 0 {
+			if deptIds, err = svcCtx.Rbac.DeptService.SubDeptIDs(ctx, request.DeptID); err != nil {
+				response.Fail(c, 500, "获取下级部门失败: "+err.Error())
+				return
+			}
+		}
+		pr, err := svcCtx.Rbac.UserService.FindPage(ctx, _interface.WithPagination(request.Page, request.PageSize),
 			_interface.WithScopes(func(db *gorm.DB) *gorm.DB {
 				if request.Username != "" {
 					db = db.Where("username LIKE ?", request.Username+"%")
@@ -362,8 +680,11 @@ func ListUser(svcCtx *services.ServiceContext) gin.HandlerFunc {
 				if request.Gender > 0 {
 					db = db.Where("gender = ?", request.Gender)
 				}
+				if len(deptIds) > 0 {
+					db = db.Where("dept_id IN ?", deptIds)
+				}
 				return db.Preload("Roles")
-			}))
+			}, dataScope.Scope("dept_id", "id")))
 		if err != nil {
 			response.Fail(c, 500, "获取用户列表失败: "+err.Error())
 			return
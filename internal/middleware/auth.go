@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"errors"
+	"gin-admin/internal/services"
+	rbac2 "gin-admin/internal/services/rbac"
+	"gin-admin/pkg/components/jwt"
+	"gin-admin/pkg/errcode"
+	"gin-admin/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+)
+
+const (
+	APIKEY_KEY = "X-API-Key"
+	// APIKEY_CTX 通过 API Key 认证时保存在上下文中的 *rbac.APIKey
+	APIKEY_CTX = "apiKey"
+)
+
+// Authenticate 认证中间件，同时支持 JWT 和 API Key
+// API Key 可以放在 X-API-Key 请求头，或以 Bearer ga_xxx 的形式放在 Authorization 请求头
+func Authenticate(svrCtx *services.ServiceContext) gin.HandlerFunc {
+	jwtAuth := JWT(svrCtx)
+	return func(c *gin.Context) {
+		key, ok := apiKeyFromRequest(c)
+		if !ok {
+			jwtAuth(c)
+			return
+		}
+		apiKey, err := svrCtx.Rbac.APIKeyService.Authenticate(c.Request.Context(), key, c.ClientIP())
+		if errors.Is(err, rbac2.ErrAPIKeyInvalid) {
+			response.FailWithStatus(c, http.StatusUnauthorized, errcode.APIKeyInvalid, errcode.GetMessage(errcode.APIKeyInvalid))
+			c.Abort()
+			return
+		}
+		if err != nil {
+			logrus.Error("failed to authenticate api key :" + err.Error())
+			response.Fail(c, errcode.ServerError, errcode.GetMessage(errcode.ServerError))
+			c.Abort()
+			return
+		}
+		if !enterTenant(c, svrCtx, apiKey.TenantID) || !checkUserStatus(c, svrCtx, apiKey.UserID) {
+			return
+		}
+		user, err := svrCtx.Rbac.UserService.FindByID(c.Request.Context(), apiKey.UserID)
+		if err != nil {
+			logrus.Error("failed to find api key owner :" + err.Error())
+			response.Fail(c, errcode.ServerError, errcode.GetMessage(errcode.ServerError))
+			c.Abort()
+			return
+		}
+		c.Set("uid", apiKey.UserID)
+		c.Set("username", user.Username)
+		c.Set(APIKEY_CTX, apiKey)
+		c.Next()
+	}
+}
+
+// apiKeyFromRequest 从请求头中取出 API Key
+func apiKeyFromRequest(c *gin.Context) (string, bool) {
+	if key := c.GetHeader(APIKEY_KEY); key != "" {
+		return key, true
+	}
+	parts := strings.SplitN(c.GetHeader(ACCESSTOKEN_KEY), " ", 2)
+	if len(parts) == 2 && parts[0] == jwt.TokenPrefix && rbac2.IsAPIKey(parts[1]) {
+		return parts[1], true
+	}
+	return "", false
+}
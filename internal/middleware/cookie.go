@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"gin-admin/internal/services"
+	"gin-admin/pkg/components/jwt"
+	"gin-admin/pkg/errcode"
+	"gin-admin/pkg/response"
+	"github.com/gin-gonic/gin"
+	jwtv4 "github.com/golang-jwt/jwt/v4"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/14 上午9:40
+* @Package: 刷新令牌 Cookie 与双重提交 CSRF Token
+ */
+
+// CSRF_KEY CSRF Token 的 Cookie 名和请求头名，前端读取 Cookie 后放在同名请求头中回传
+const CSRF_KEY = "X-CSRF-Token"
+
+// OAUTH_STATE_KEY 第三方登录 state 的 Cookie 名，将 state 绑定到发起登录的浏览器
+const OAUTH_STATE_KEY = "X-OAuth-State"
+
+// SetRefreshCookie 写入刷新令牌 Cookie（HttpOnly），同时轮换 CSRF Token Cookie（前端可读）
+func SetRefreshCookie(c *gin.Context, svrCtx *services.ServiceContext, refreshToken string) {
+	maxAge := int(svrCtx.Config.Jwt.RefreshTokenExpire.Seconds())
+	setCookie(c, svrCtx, REFRESHTOKEN_KEY, refreshToken, maxAge, true)
+	if svrCtx.Config.Security.Cookie.DisableCSRF {
+		return
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		logrus.Error("failed to generate csrf token :" + err.Error())
+		return
+	}
+	setCookie(c, svrCtx, CSRF_KEY, hex.EncodeToString(buf), maxAge, false)
+}
+
+// ClearRefreshCookie 退出登录时清除刷新令牌和 CSRF Token
+func ClearRefreshCookie(c *gin.Context, svrCtx *services.ServiceContext) {
+	setCookie(c, svrCtx, REFRESHTOKEN_KEY, "", -1, true)
+	setCookie(c, svrCtx, CSRF_KEY, "", -1, false)
+}
+
+func setCookie(c *gin.Context, svrCtx *services.ServiceContext, name, value string, maxAge int, httpOnly bool) {
+	cfg := svrCtx.Config.Security.Cookie
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		MaxAge:   maxAge,
+		Path:     cfg.CookiePath(),
+		Domain:   cfg.Domain,
+		Secure:   cfg.Secure,
+		HttpOnly: httpOnly,
+		SameSite: cfg.SameSiteMode(),
+	})
+}
+
+// SetOAuthStateCookie 跳转到第三方前写入 state Cookie（HttpOnly）
+// 回调由第三方跨站重定向而来，strict 模式下 Cookie 不会被携带，因此至少使用 Lax
+func SetOAuthStateCookie(c *gin.Context, svrCtx *services.ServiceContext, state string) {
+	setOAuthStateCookie(c, svrCtx, state, int(svrCtx.OAuth.StateTTL().Seconds()))
+}
+
+// CheckOAuthStateCookie 回调时比对 state 与 Cookie，并清除 Cookie
+// 不一致说明回调不是由当前浏览器发起的登录，防止攻击者诱导受害者登录到攻击者的账号
+func CheckOAuthStateCookie(c *gin.Context, svrCtx *services.ServiceContext, state string) bool {
+	cookie, err := c.Cookie(OAUTH_STATE_KEY)
+	setOAuthStateCookie(c, svrCtx, "", -1)
+	if err != nil || cookie == "" || state == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie), []byte(state)) == 1
+}
+
+func setOAuthStateCookie(c *gin.Context, svrCtx *services.ServiceContext, value string, maxAge int) {
+	cfg := svrCtx.Config.Security.Cookie
+	sameSite := cfg.SameSiteMode()
+	if sameSite == http.SameSiteStrictMode {
+		sameSite = http.SameSiteLaxMode
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     OAUTH_STATE_KEY,
+		Value:    value,
+		MaxAge:   maxAge,
+		Path:     cfg.CookiePath(),
+		Domain:   cfg.Domain,
+		Secure:   cfg.Secure,
+		HttpOnly: true,
+		SameSite: sameSite,
+	})
+}
+
+// RefreshTokenFromCookie 读取 Cookie 中的刷新令牌，并校验双重提交的 CSRF Token
+// 没有 Cookie 时返回 ok=false 且不写响应；CSRF 校验失败时写入 403 响应
+func RefreshTokenFromCookie(c *gin.Context, svrCtx *services.ServiceContext) (token string, ok bool) {
+	token, err := c.Cookie(REFRESHTOKEN_KEY)
+	if err != nil || token == "" {
+		return "", false
+	}
+	if !svrCtx.Config.Security.Cookie.DisableCSRF && !validCSRFToken(c) {
+		response.FailWithStatus(c, http.StatusForbidden, errcode.CSRFTokenInvalid, errcode.GetMessage(errcode.CSRFTokenInvalid))
+		c.Abort()
+		return "", false
+	}
+	return token, true
+}
+
+// validCSRFToken 请求头中的 CSRF Token 与 Cookie 一致，跨站请求无法读取 Cookie 也就无法伪造请求头
+func validCSRFToken(c *gin.Context) bool {
+	cookie, err := c.Cookie(CSRF_KEY)
+	header := c.GetHeader(CSRF_KEY)
+	if err != nil || cookie == "" || header == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) == 1
+}
+
+// RefreshSession 用刷新令牌换取新的令牌对，并检查账号状态
+// 失败时已写入响应并中止请求，返回 ok=false
+func RefreshSession(c *gin.Context, svrCtx *services.ServiceContext, refreshToken string) (*jwt.TokenPair, *jwt.CustomClaims, bool) {
+	ctx := c.Request.Context()
+	tokenPair, err := svrCtx.Jwt.RefreshToken(ctx, refreshToken, jwt.WithClientInfo(c.ClientIP(), c.Request.UserAgent()))
+	if code, ok := sessionErrorCode(err); ok {
+		response.FailWithStatus(c, http.StatusUnauthorized, code, errcode.GetMessage(code))
+		c.Abort()
+		return nil, nil, false
+	}
+	// refresh token 过期（含超过会话绝对有效期）
+	if errors.Is(err, jwtv4.ErrTokenExpired) {
+		response.FailWithStatus(c, http.StatusUnauthorized, errcode.TokenExpired, "登录已过期，请重新登录")
+		c.Abort()
+		return nil, nil, false
+	}
+	if err != nil {
+		logrus.Error("failed to refresh jwt token :" + err.Error())
+		response.Unauthorized(c, err.Error())
+		c.Abort()
+		return nil, nil, false
+	}
+	claims, err := svrCtx.Jwt.ParseAccessToken(ctx, tokenPair.AccessToken)
+	if err != nil {
+		logrus.Error("failed to parse refreshed jwt token :" + err.Error())
+		response.Unauthorized(c, err.Error())
+		c.Abort()
+		return nil, nil, false
+	}
+	if !enterTenant(c, svrCtx, claims.TenantID) || !checkUserStatus(c, svrCtx, claims.UserID) {
+		return nil, nil, false
+	}
+	return tokenPair, claims, true
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"fmt"
+	"gin-admin/internal/model/rbac"
+	"gin-admin/internal/services"
+	"gin-admin/pkg/components/jwt"
+	"gin-admin/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/13 上午10:30
+* @Package: 模拟登录
+ */
+
+// IMPERSONATOR_CTX 模拟登录时保存在上下文中的管理员ID
+const IMPERSONATOR_CTX = "impersonatorId"
+
+// ImpersonatorID 当前请求是否处于模拟登录状态，返回发起模拟的管理员ID
+func ImpersonatorID(c *gin.Context) (uint, bool) {
+	id := c.GetUint(IMPERSONATOR_CTX)
+	return id, id != 0
+}
+
+// ForbidImpersonation 禁止模拟登录的会话访问（修改密码、两步验证、API Key、再次模拟等）
+func ForbidImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := ImpersonatorID(c); ok {
+			response.Forbidden(c, services.ErrImpersonationNested.Error())
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// auditImpersonation 执行后续处理，模拟登录的请求在完成后记录安全事件
+func auditImpersonation(c *gin.Context, svrCtx *services.ServiceContext, claims *jwt.CustomClaims) {
+	if claims.ImpersonatorID == 0 {
+		c.Next()
+		return
+	}
+	c.Set(IMPERSONATOR_CTX, claims.ImpersonatorID)
+	c.Next()
+	svrCtx.RecordImpersonatedRequest(c.Request.Context(), &rbac.SecurityEvent{
+		UserID:         claims.UserID,
+		Username:       claims.Username,
+		SessionID:      claims.SessionID,
+		DeviceID:       claims.DeviceID,
+		IP:             c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+		ImpersonatorID: claims.ImpersonatorID,
+		Detail:         fmt.Sprintf("%s %s %d", c.Request.Method, c.Request.URL.Path, c.Writer.Status()),
+	})
+}
@@ -4,10 +4,13 @@ import (
 	"errors"
 	"gin-admin/internal/services"
 	"gin-admin/pkg/components/jwt"
+	"gin-admin/pkg/errcode"
 	"gin-admin/pkg/response"
 	"github.com/gin-gonic/gin"
 	jwtv4 "github.com/golang-jwt/jwt/v4"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"net/http"
 	"strings"
 )
 
@@ -36,11 +39,20 @@ func JWT(svrCtx *services.ServiceContext) gin.HandlerFunc {
 		token := parts[1]
 		claims, err := svrCtx.Jwt.ParseAccessToken(c.Request.Context(), token)
 		if err == nil {
-			// 没过期，直接放行
+			// 没过期，检查账号状态后放行
+			if !enterTenant(c, svrCtx, claims.TenantID) || !checkUserStatus(c, svrCtx, claims.UserID) {
+				return
+			}
 			c.Set("uid", claims.UserID)
 			c.Set("username", claims.Username)
 			c.Set("sessionId", claims.SessionID)
-			c.Next()
+			auditImpersonation(c, svrCtx, claims)
+			return
+		}
+		// 会话被挤下线、空闲超时
+		if code, ok := sessionErrorCode(err); ok {
+			response.FailWithStatus(c, http.StatusUnauthorized, code, errcode.GetMessage(code))
+			c.Abort()
 			return
 		}
 		// 若是其他错误（签名错误、格式错误）
@@ -50,32 +62,61 @@ func JWT(svrCtx *services.ServiceContext) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
-		// 过期了，刷新token
-		refreshToken, errCookie := c.Cookie(REFRESHTOKEN_KEY)
-		if errCookie != nil {
-			response.Unauthorized(c, "登录已过期，请重新登录")
+		// 过期了，关闭自动刷新时由前端调用 /users/refresh
+		if svrCtx.Config.Security.Cookie.DisableImplicitRefresh {
+			response.FailWithStatus(c, http.StatusUnauthorized, errcode.TokenExpired, errcode.GetMessage(errcode.TokenExpired))
 			c.Abort()
 			return
 		}
-		tokenPair, errRefresh := svrCtx.Jwt.RefreshToken(c.Request.Context(), refreshToken)
-		if errRefresh != nil {
-			logrus.Error("failed to refresh jwt token :" + errRefresh.Error())
-			response.Unauthorized(c, errRefresh.Error())
-			c.Abort()
+		// 用 Cookie 中的刷新令牌自动刷新
+		refreshToken, ok := RefreshTokenFromCookie(c, svrCtx)
+		if !ok {
+			if !c.IsAborted() {
+				response.FailWithStatus(c, http.StatusUnauthorized, errcode.TokenExpired, "登录已过期，请重新登录")
+				c.Abort()
+			}
+			return
+		}
+		tokenPair, claims, ok := RefreshSession(c, svrCtx, refreshToken)
+		if !ok {
 			return
 		}
 		c.Header("X-Set-Access-Token", tokenPair.AccessToken)
-		c.SetCookie(REFRESHTOKEN_KEY,
-			tokenPair.RefreshToken,
-			int(svrCtx.Config.Jwt.RefreshTokenExpire.Seconds()),
-			"/",
-			"",
-			false,
-			true)
-		claims, _ = svrCtx.Jwt.ParseAccessToken(c.Request.Context(), tokenPair.AccessToken)
+		SetRefreshCookie(c, svrCtx, tokenPair.RefreshToken)
 		c.Set("uid", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("sessionId", claims.SessionID)
-		c.Next()
+		auditImpersonation(c, svrCtx, claims)
+	}
+}
+
+// checkUserStatus 禁用、锁定或已删除的用户即使持有未过期的 token 也不允许访问
+func checkUserStatus(c *gin.Context, svrCtx *services.ServiceContext, userID uint) bool {
+	err := svrCtx.Rbac.UserService.CheckUserStatus(c.Request.Context(), userID)
+	if err == nil {
+		return true
+	}
+	var e *errcode.Error
+	switch {
+	case errors.As(err, &e):
+		response.FailWithStatus(c, http.StatusUnauthorized, e.Code, e.Message)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.FailWithStatus(c, http.StatusUnauthorized, errcode.UserNotFound, errcode.GetMessage(errcode.UserNotFound))
+	default:
+		logrus.Error("failed to check user status :" + err.Error())
+		response.Fail(c, errcode.ServerError, errcode.GetMessage(errcode.ServerError))
+	}
+	c.Abort()
+	return false
+}
+
+// sessionErrorCode 需要前端区分提示的会话错误
+func sessionErrorCode(err error) (int, bool) {
+	switch {
+	case errors.Is(err, jwt.ErrSessionEvicted):
+		return errcode.SessionEvicted, true
+	case errors.Is(err, jwt.ErrSessionIdleTimeout):
+		return errcode.SessionIdleTimeout, true
 	}
+	return 0, false
 }
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gin-admin/internal/config"
+	"gin-admin/internal/migrates"
+	"gin-admin/internal/model/rbac"
+	"gin-admin/internal/services"
+	rbac2 "gin-admin/internal/services/rbac"
+	cache2 "gin-admin/pkg/components/cache"
+	"gin-admin/pkg/components/jwt"
+	"gin-admin/pkg/consts"
+	"gin-admin/pkg/errcode"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestServiceContext 基于内存 SQLite 和内存缓存的服务上下文
+func newTestServiceContext(t *testing.T) *services.ServiceContext {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	// 内存库每个连接都是独立的数据库
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(migrates.GetGroupModels("rbac")...))
+
+	cache := cache2.NewShardedMemoryCache(0)
+	jwtCfg := &jwt.Config{
+		Secret:             "test-secret-key-32-chars-minimum",
+		Issuer:             "test",
+		AccessTokenExpire:  time.Minute,
+		RefreshTokenExpire: time.Hour,
+	}
+	return &services.ServiceContext{
+		Config:       &config.AppConfig{Jwt: jwtCfg},
+		Db:           db,
+		Cache:        cache,
+		Jwt:          jwt.NewJwtService(*jwtCfg, cache),
+		CacheService: services.NewCacheService(cache, false),
+		Rbac:         rbac2.NewContext(db, cache),
+	}
+}
+
+// createTestUser 创建用户并签发访问令牌
+func createTestUser(t *testing.T, svrCtx *services.ServiceContext, username string, status consts.UserStatus) (*rbac.User, string) {
+	ctx := context.Background()
+	user := &rbac.User{Username: username, Email: username + "@example.com", Status: status}
+	user.SetPassword("Passw0rd!")
+	require.NoError(t, svrCtx.Rbac.UserService.Create(ctx, user))
+	tp, err := svrCtx.Jwt.GenerateTokenPair(ctx, user.ID, user.Username, user.Email)
+	require.NoError(t, err)
+	return user, tp.AccessToken
+}
+
+// serveWithToken 携带访问令牌请求，返回 HTTP 状态码和响应中的业务错误码
+func serveWithToken(router *gin.Engine, method, path, token string) (int, int) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set(ACCESSTOKEN_KEY, jwt.TokenPrefix+" "+token)
+	}
+	router.ServeHTTP(w, req)
+	var body struct {
+		Code int `json:"code"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &body)
+	return w.Code, body.Code
+}
+
+func TestJWTUserStatus(t *testing.T) {
+	svrCtx := newTestServiceContext(t)
+	router := gin.New()
+	router.GET("/test", JWT(svrCtx), func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+
+	until := time.Now().Add(time.Hour)
+	cases := []struct {
+		name   string
+		status consts.UserStatus
+		until  *time.Time
+		want   int // HTTP 状态码
+		code   int
+	}{
+		{"Active", consts.UserStatusActive, nil, http.StatusOK, 0},
+		{"Locked", consts.UserStatusLocked, &until, http.StatusUnauthorized, errcode.UserLocked},
+		{"Locked By Admin", consts.UserStatusLocked, nil, http.StatusUnauthorized, errcode.UserLocked},
+		{"Disabled", consts.UserStatusDisabled, nil, http.StatusUnauthorized, errcode.UserDisabled},
+		{"Pending", consts.UserStatusPending, nil, http.StatusUnauthorized, errcode.EmailNotVerified},
+	}
+	for i, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			user, token := createTestUser(t, svrCtx, fmt.Sprintf("user%d", i), consts.UserStatusActive)
+			// 令牌签发之后状态才发生变化，已登录的用户同样被拒绝
+			err := svrCtx.Rbac.UserService.UpdateByID(context.Background(), user.ID, map[string]interface{}{
+				"status":       tc.status,
+				"locked_until": tc.until,
+			})
+			require.NoError(t, err)
+
+			status, code := serveWithToken(router, http.MethodGet, "/test", token)
+			assert.Equal(t, tc.want, status)
+			if tc.code != 0 {
+				assert.Equal(t, tc.code, code)
+			}
+		})
+	}
+
+	t.Run("Lock Expired", func(t *testing.T) {
+		user, token := createTestUser(t, svrCtx, "expired", consts.UserStatusActive)
+		past := time.Now().Add(-time.Minute)
+		err := svrCtx.Rbac.UserService.UpdateByID(context.Background(), user.ID, map[string]interface{}{
+			"status":       consts.UserStatusLocked,
+			"locked_until": &past,
+		})
+		require.NoError(t, err)
+		status, _ := serveWithToken(router, http.MethodGet, "/test", token)
+		assert.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("Deleted", func(t *testing.T) {
+		user, token := createTestUser(t, svrCtx, "deleted", consts.UserStatusActive)
+		require.NoError(t, svrCtx.Rbac.UserService.DeleteByID(context.Background(), user.ID))
+		status, code := serveWithToken(router, http.MethodGet, "/test", token)
+		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Equal(t, errcode.UserNotFound, code)
+	})
+}
@@ -34,6 +34,11 @@ func Logger() gin.HandlerFunc {
 			"user_agent": c.Request.UserAgent(),
 		})
 
+		// 模拟登录的请求标记发起模拟的管理员
+		if impersonatorID, ok := ImpersonatorID(c); ok {
+			entry = entry.WithField("impersonator_id", impersonatorID)
+		}
+
 		// 根据状态码选择日志级别
 		if statusCode >= 500 {
 			entry.Error("请求处理失败", statusCode)
@@ -1,10 +1,18 @@
 package middleware
 
 import (
+	"errors"
+	"gin-admin/internal/model/rbac"
 	"gin-admin/internal/services"
+	rbac2 "gin-admin/internal/services/rbac"
+	"gin-admin/pkg/components/policy"
+	_interface "gin-admin/pkg/interface"
 	"gin-admin/pkg/response"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"strings"
+	"time"
 )
 
 // PermissionMiddleware 权限验证中间件
@@ -17,22 +25,136 @@ func PermissionMiddleware(svrCtx *services.ServiceContext) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
-		has, err := svrCtx.CacheService.CheckUserPermission(c.Request.Context(), userID.(uint), c.FullPath(), c.Request.Method, svrCtx.Rbac.ResourceService.GetUserResources)
-		if err != nil {
-			has, err = svrCtx.Rbac.ResourceService.CheckUserPermission(c.Request.Context(), userID.(uint), c.FullPath(), c.Request.Method)
+		has, conditional, err := svrCtx.CacheService.CheckUserPermission(c.Request.Context(), userID.(uint), c.FullPath(), c.Request.Method, svrCtx.Rbac.ResourceService.GetUserGrants)
+		// 缓存不可用，或者只有条件授权（条件不缓存）时查询数据库
+		if err != nil || conditional {
+			var conditions []string
+			has, conditions, err = svrCtx.Rbac.ResourceService.CheckUserPermission(c.Request.Context(), userID.(uint), c.FullPath(), c.Request.Method)
 			if nil != err {
 				logrus.Error("failed check user permission: ", err)
 				response.InternalServerError(c, "权限检查失败")
 				c.Abort()
 				return
 			}
+			if !has && len(conditions) > 0 {
+				has = checkConditions(c, svrCtx, conditions)
+			}
 		}
 		if !has {
 			response.Forbidden(c, "没有权限")
 			c.Abort()
 			return
 		}
+		// 通过 API Key 访问时，还需要在 Key 的授权范围内
+		if apiKey, ok := c.Get(APIKEY_CTX); ok {
+			has, err = apiKeyInScope(c, svrCtx, apiKey.(*rbac.APIKey))
+			if err != nil {
+				logrus.Error("failed check api key scope: ", err)
+				response.InternalServerError(c, "权限检查失败")
+				c.Abort()
+				return
+			}
+			if !has {
+				response.Forbidden(c, "API Key 没有访问该接口的权限")
+				c.Abort()
+				return
+			}
+		}
+		// 权限按用户所属租户的角色校验，通过后平台超级管理员才可以切换到请求头指定的租户
+		if !switchTenant(c, svrCtx) {
+			return
+		}
 
 		c.Next()
 	}
 }
+
+// checkConditions 任一条件成立即授权，无法编译或求值出错的条件视为不成立
+func checkConditions(c *gin.Context, svrCtx *services.ServiceContext, conditions []string) bool {
+	env := conditionEnv(c, svrCtx)
+	for _, cond := range conditions {
+		prog, err := rbac2.CompileCondition(cond)
+		if err != nil {
+			logrus.Errorf("invalid grant condition %q: %v", cond, err)
+			continue
+		}
+		ok, err := prog.Eval(env)
+		if err != nil {
+			logrus.Errorf("failed to evaluate grant condition %q: %v", cond, err)
+			continue
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// conditionEnv 授权条件可以使用的请求属性（见 rbac.ConditionSchema），用户信息在条件用到时才查询
+func conditionEnv(c *gin.Context, svrCtx *services.ServiceContext) policy.Env {
+	now := time.Now()
+	var user *rbac.User
+	loadUser := func() *rbac.User {
+		if user == nil {
+			var err error
+			if user, err = svrCtx.Rbac.UserService.FindByID(c.Request.Context(), c.GetUint("uid")); err != nil {
+				logrus.Errorf("failed to load user %d for grant condition: %v", c.GetUint("uid"), err)
+				user = &rbac.User{}
+			}
+		}
+		return user
+	}
+	return func(name string) (interface{}, bool) {
+		switch name {
+		case "ip":
+			return c.ClientIP(), true
+		case "method":
+			return c.Request.Method, true
+		case "path":
+			return c.Request.URL.Path, true
+		case "time.hour":
+			return now.Hour(), true
+		case "time.minute":
+			return now.Minute(), true
+		case "time.weekday":
+			return int(now.Weekday()), true
+		case "claims.uid":
+			return c.GetUint("uid"), true
+		case "claims.username":
+			return c.GetString("username"), true
+		case "claims.tenant_id":
+			return c.GetUint(TENANT_HOME_CTX), true
+		case "claims.api_key":
+			_, ok := c.Get(APIKEY_CTX)
+			return ok, true
+		case "claims.impersonated":
+			_, ok := ImpersonatorID(c)
+			return ok, true
+		case "user.status":
+			return int(loadUser().Status), true
+		case "user.dept_id":
+			return loadUser().DeptID, true
+		}
+		if param, ok := strings.CutPrefix(name, "params."); ok {
+			return c.Param(param), true
+		}
+		return nil, false
+	}
+}
+
+// apiKeyInScope 当前接口对应的资源 Code 是否在 API Key 的授权范围内
+// 只在用户本身的授权（含条件授权的求值，条件中可以用 claims.api_key 区分）通过后调用，
+// Key 的授权范围只能进一步收窄，不会让条件不成立的请求通过
+func apiKeyInScope(c *gin.Context, svrCtx *services.ServiceContext, apiKey *rbac.APIKey) (bool, error) {
+	resource, err := svrCtx.Rbac.ResourceService.FindOne(c.Request.Context(), _interface.WithConditions(map[string]interface{}{
+		"path":   c.FullPath(),
+		"method": c.Request.Method,
+	}))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return apiKey.HasScope(resource.Code), nil
+}
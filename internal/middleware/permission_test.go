@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"gin-admin/internal/model/rbac"
+	"gin-admin/pkg/consts"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermissionConditions(t *testing.T) {
+	svrCtx := newTestServiceContext(t)
+	ctx := context.Background()
+	resource := rbac.Resource{Path: "/items/:id", Method: http.MethodGet, Code: "item:get"}
+	require.NoError(t, svrCtx.Rbac.ResourceService.Create(ctx, &resource))
+
+	// conditional 只能访问 id 为 1 的条目；full 无条件授权
+	conditional := &rbac.Role{Name: "conditional", Status: consts.ROLESTATUS_ACTIVE}
+	full := &rbac.Role{Name: "full", Status: consts.ROLESTATUS_ACTIVE}
+	for _, role := range []*rbac.Role{conditional, full} {
+		require.NoError(t, svrCtx.Rbac.RoleService.Create(ctx, role))
+	}
+	require.NoError(t, svrCtx.Rbac.RoleService.SetResources(ctx, conditional, []rbac.Resource{resource},
+		map[uint]string{resource.ID: "params.id == '1'"}))
+	require.NoError(t, svrCtx.Rbac.RoleService.SetResources(ctx, full, []rbac.Resource{resource}, nil))
+
+	limited, limitedToken := createTestUser(t, svrCtx, "limited", consts.UserStatusActive)
+	require.NoError(t, svrCtx.Db.Model(limited).Association("Roles").Append(conditional))
+	// 同时拥有条件授权和无条件授权时按无条件授权
+	both, bothToken := createTestUser(t, svrCtx, "both", consts.UserStatusActive)
+	require.NoError(t, svrCtx.Db.Model(both).Association("Roles").Append(conditional, full))
+
+	router := gin.New()
+	router.GET("/items/:id", JWT(svrCtx), PermissionMiddleware(svrCtx), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	cases := []struct {
+		name   string
+		token  string
+		path   string
+		status int
+	}{
+		{"条件成立", limitedToken, "/items/1", http.StatusOK},
+		// 第二次请求命中缓存中的条件授权成员，仍然查询条件并求值
+		{"命中缓存后条件成立", limitedToken, "/items/1", http.StatusOK},
+		{"条件不成立", limitedToken, "/items/2", http.StatusForbidden},
+		{"无条件授权", bothToken, "/items/2", http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, _ := serveWithToken(router, http.MethodGet, tc.path, tc.token)
+			assert.Equal(t, tc.status, status)
+		})
+	}
+
+	// 条件不缓存，只缓存带 ? 前缀的成员，由中间件回查数据库
+	member := http.MethodGet + "_" + resource.Path
+	for _, tc := range []struct {
+		uid         uint
+		granted     bool
+		conditional bool
+	}{
+		{limited.ID, false, true},
+		{both.ID, true, false},
+	} {
+		key := fmt.Sprintf("permission:%d", tc.uid)
+		granted, err := svrCtx.Cache.SIsMember(ctx, key, member)
+		require.NoError(t, err)
+		assert.Equal(t, tc.granted, granted)
+		conditional, err := svrCtx.Cache.SIsMember(ctx, key, "?"+member)
+		require.NoError(t, err)
+		assert.Equal(t, tc.conditional, conditional)
+	}
+}
+
+func TestPermissionDenied(t *testing.T) {
+	svrCtx := newTestServiceContext(t)
+	ctx := context.Background()
+	list := rbac.Resource{Path: "/items", Method: http.MethodGet, Code: "item:list"}
+	create := rbac.Resource{Path: "/items", Method: http.MethodPost, Code: "item:create"}
+	for _, res := range []*rbac.Resource{&list, &create} {
+		require.NoError(t, svrCtx.Rbac.ResourceService.Create(ctx, res))
+	}
+	// reader 无条件授权列表，conditional 条件授权新建，deny 禁止访问两者
+	reader := &rbac.Role{Name: "reader", Status: consts.ROLESTATUS_ACTIVE}
+	conditional := &rbac.Role{Name: "conditional", Status: consts.ROLESTATUS_ACTIVE}
+	deny := &rbac.Role{Name: "deny", Status: consts.ROLESTATUS_ACTIVE}
+	for _, role := range []*rbac.Role{reader, conditional, deny} {
+		require.NoError(t, svrCtx.Rbac.RoleService.Create(ctx, role))
+	}
+	require.NoError(t, svrCtx.Rbac.RoleService.SetResources(ctx, reader, []rbac.Resource{list}, nil))
+	require.NoError(t, svrCtx.Rbac.RoleService.SetResources(ctx, conditional, []rbac.Resource{create},
+		map[uint]string{create.ID: "time.hour >= 0"}))
+	require.NoError(t, svrCtx.Rbac.RoleService.SetDeniedResources(ctx, deny, []uint{list.ID, create.ID}))
+
+	user, token := createTestUser(t, svrCtx, "denied", consts.UserStatusActive)
+	require.NoError(t, svrCtx.Db.Model(user).Association("Roles").Append(reader, conditional, deny))
+
+	router := gin.New()
+	handler := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/items", JWT(svrCtx), PermissionMiddleware(svrCtx), handler)
+	router.POST("/items", JWT(svrCtx), PermissionMiddleware(svrCtx), handler)
+
+	// 第一次请求写入缓存，第二次命中缓存
+	for i := 0; i < 2; i++ {
+		for _, res := range []rbac.Resource{list, create} {
+			status, _ := serveWithToken(router, res.Method, res.Path, token)
+			assert.Equal(t, http.StatusForbidden, status, "%s %s", res.Method, res.Path)
+		}
+	}
+
+	// 被禁止的接口不以任何形式写入缓存
+	key := fmt.Sprintf("permission:%d", user.ID)
+	exists, err := svrCtx.Cache.Exists(ctx, key)
+	require.NoError(t, err)
+	require.True(t, exists)
+	for _, res := range []rbac.Resource{list, create} {
+		member := res.Method + "_" + res.Path
+		for _, m := range []string{member, "?" + member} {
+			ok, err := svrCtx.Cache.SIsMember(ctx, key, m)
+			require.NoError(t, err)
+			assert.False(t, ok, m)
+		}
+	}
+}
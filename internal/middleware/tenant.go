@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"gin-admin/internal/services"
+	rbac2 "gin-admin/internal/services/rbac"
+	"gin-admin/pkg/components/tenant"
+	"gin-admin/pkg/errcode"
+	"gin-admin/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/17 上午9:30
+* @Package: 多租户：解析请求租户，认证后进入用户所属租户
+ */
+
+const (
+	// TENANT_CTX 当前请求操作的租户ID
+	TENANT_CTX = "tenantId"
+	// TENANT_HOME_CTX 当前登录用户所属的租户ID
+	TENANT_HOME_CTX = "homeTenantId"
+	// tenantRequestedCtx 请求头中指定的租户ID
+	tenantRequestedCtx = "requestedTenantId"
+)
+
+// Tenant 按请求头确定租户，未登录的接口（登录、注册、找回密码等）在该租户内查询账号
+// 未携带请求头时为平台租户；认证中间件会再切换到令牌中记录的用户所属租户
+func Tenant(svrCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !svrCtx.Config.Tenant.Enabled {
+			c.Next()
+			return
+		}
+		id := tenant.PlatformID
+		if header := c.GetHeader(svrCtx.Config.Tenant.HeaderName()); header != "" {
+			v, err := strconv.ParseUint(header, 10, 64)
+			if err != nil {
+				response.FailWithStatus(c, http.StatusBadRequest, errcode.TenantInvalid, errcode.GetMessage(errcode.TenantInvalid))
+				c.Abort()
+				return
+			}
+			id = uint(v)
+			c.Set(tenantRequestedCtx, id)
+		}
+		if !checkTenant(c, svrCtx, id) {
+			return
+		}
+		setTenant(c, id)
+		c.Next()
+	}
+}
+
+// PlatformOnly 只允许平台超级管理员访问（租户管理），需放在认证中间件之后
+func PlatformOnly(svrCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !platformAdmin(c, svrCtx) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// PlatformManaged 平台统一维护、不按租户隔离的数据（如菜单），开启多租户后只有平台超级管理员可以修改
+// 未开启多租户时不做限制，按接口权限校验，需放在认证中间件之后
+func PlatformManaged(svrCtx *services.ServiceContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if svrCtx.Config.Tenant.Enabled && !platformAdmin(c, svrCtx) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// platformAdmin 当前用户属于平台租户且是超级管理员，否则写入响应并中止请求
+func platformAdmin(c *gin.Context, svrCtx *services.ServiceContext) bool {
+	if c.GetUint(TENANT_HOME_CTX) != tenant.PlatformID {
+		response.FailWithStatus(c, http.StatusForbidden, errcode.TenantForbidden, errcode.GetMessage(errcode.TenantForbidden))
+		c.Abort()
+		return false
+	}
+	ok, err := svrCtx.Rbac.TenantService.IsPlatformAdmin(c.Request.Context(), c.GetUint("uid"))
+	if err != nil {
+		logrus.Error("failed to check platform admin :" + err.Error())
+		response.Fail(c, errcode.ServerError, errcode.GetMessage(errcode.ServerError))
+		c.Abort()
+		return false
+	}
+	if !ok {
+		response.FailWithStatus(c, http.StatusForbidden, errcode.TenantForbidden, errcode.GetMessage(errcode.TenantForbidden))
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// HomeContext 当前用户所属租户的上下文，平台管理员切换租户后查询本人的信息时使用
+func HomeContext(c *gin.Context) context.Context {
+	home, ok := c.Get(TENANT_HOME_CTX)
+	if !ok {
+		return c.Request.Context()
+	}
+	return tenant.WithID(c.Request.Context(), home.(uint))
+}
+
+// enterTenant 认证通过后进入用户所属的租户，租户被禁用后已登录的用户同样无法访问
+// 失败时已写入响应并中止请求
+func enterTenant(c *gin.Context, svrCtx *services.ServiceContext, home uint) bool {
+	if !svrCtx.Config.Tenant.Enabled {
+		return true
+	}
+	if !checkTenant(c, svrCtx, home) {
+		return false
+	}
+	setTenant(c, home)
+	c.Set(TENANT_HOME_CTX, home)
+	return true
+}
+
+// switchTenant 请求头指定了其他租户时，只有平台超级管理员可以切换过去操作该租户的数据
+// 在权限校验之后执行，只对管理接口生效，个人接口始终使用用户所属租户
+func switchTenant(c *gin.Context, svrCtx *services.ServiceContext) bool {
+	requested, ok := c.Get(tenantRequestedCtx)
+	if !ok || requested.(uint) == c.GetUint(TENANT_HOME_CTX) {
+		return true
+	}
+	if c.GetUint(TENANT_HOME_CTX) == tenant.PlatformID {
+		allowed, err := svrCtx.Rbac.TenantService.IsPlatformAdmin(c.Request.Context(), c.GetUint("uid"))
+		if err != nil {
+			logrus.Error("failed to check platform admin :" + err.Error())
+			response.Fail(c, errcode.ServerError, errcode.GetMessage(errcode.ServerError))
+			c.Abort()
+			return false
+		}
+		if allowed {
+			setTenant(c, requested.(uint))
+			return true
+		}
+	}
+	response.FailWithStatus(c, http.StatusForbidden, errcode.TenantForbidden, errcode.GetMessage(errcode.TenantForbidden))
+	c.Abort()
+	return false
+}
+
+// checkTenant 租户存在且已启用，失败时写入响应并中止请求
+func checkTenant(c *gin.Context, svrCtx *services.ServiceContext, id uint) bool {
+	err := svrCtx.Rbac.TenantService.CheckActive(c.Request.Context(), id)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.FailWithStatus(c, http.StatusBadRequest, errcode.TenantInvalid, errcode.GetMessage(errcode.TenantInvalid))
+	case errors.Is(err, rbac2.ErrTenantDisabled):
+		response.FailWithStatus(c, http.StatusForbidden, errcode.TenantDisabled, errcode.GetMessage(errcode.TenantDisabled))
+	default:
+		logrus.Error("failed to check tenant :" + err.Error())
+		response.Fail(c, errcode.ServerError, errcode.GetMessage(errcode.ServerError))
+	}
+	c.Abort()
+	return false
+}
+
+// setTenant 后续经 GORM 的读写都限定在该租户内
+func setTenant(c *gin.Context, id uint) {
+	c.Request = c.Request.WithContext(tenant.WithID(c.Request.Context(), id))
+	c.Set(TENANT_CTX, id)
+}
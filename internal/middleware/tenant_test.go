@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"gin-admin/internal/model/rbac"
+	"gin-admin/pkg/components/tenant"
+	"gin-admin/pkg/consts"
+	"gin-admin/pkg/errcode"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSwitchTenant(t *testing.T) {
+	svrCtx := newTestServiceContext(t)
+	ctx := context.Background()
+	admin := &rbac.Role{Name: "超级管理员", BuiltIn: true, Status: consts.ROLESTATUS_ACTIVE}
+	require.NoError(t, svrCtx.Rbac.RoleService.Create(ctx, admin))
+	staff := &rbac.Role{Name: "员工", Status: consts.ROLESTATUS_ACTIVE}
+	require.NoError(t, svrCtx.Rbac.RoleService.Create(ctx, staff))
+	platformAdmin, _ := createTestUser(t, svrCtx, "root", consts.UserStatusActive)
+	require.NoError(t, svrCtx.Db.Model(platformAdmin).Association("Roles").Append(admin))
+	platformStaff, _ := createTestUser(t, svrCtx, "staff", consts.UserStatusActive)
+	require.NoError(t, svrCtx.Db.Model(platformStaff).Association("Roles").Append(staff))
+
+	platform, other := tenant.PlatformID, uint(2)
+	cases := []struct {
+		name      string
+		uid       uint
+		home      uint
+		requested *uint
+		ok        bool
+		tenant    uint
+	}{
+		{"未指定租户", platformStaff.ID, platform, nil, true, platform},
+		{"指定所属租户", platformStaff.ID, platform, &platform, true, platform},
+		{"平台超级管理员切换租户", platformAdmin.ID, platform, &other, true, other},
+		{"平台普通用户不能切换租户", platformStaff.ID, platform, &other, false, platform},
+		// 其他租户的用户即使拥有内置角色（租户管理员）也不能切换
+		{"租户管理员不能切换租户", platformAdmin.ID, 3, &other, false, 3},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest("GET", "/test", nil)
+			c.Set("uid", tc.uid)
+			c.Set(TENANT_HOME_CTX, tc.home)
+			setTenant(c, tc.home)
+			if tc.requested != nil {
+				c.Set(tenantRequestedCtx, *tc.requested)
+			}
+
+			assert.Equal(t, tc.ok, switchTenant(c, svrCtx))
+			assert.Equal(t, !tc.ok, c.IsAborted())
+			id, _ := tenant.FromContext(c.Request.Context())
+			assert.Equal(t, tc.tenant, id)
+			assert.Equal(t, tc.tenant, c.GetUint(TENANT_CTX))
+			if !tc.ok {
+				assert.Equal(t, http.StatusForbidden, w.Code)
+				var body struct {
+					Code int `json:"code"`
+				}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.Equal(t, errcode.TenantForbidden, body.Code)
+			}
+		})
+	}
+}
+
+func TestPlatformManaged(t *testing.T) {
+	svrCtx := newTestServiceContext(t)
+	ctx := context.Background()
+	admin := &rbac.Role{Name: "超级管理员", BuiltIn: true, Status: consts.ROLESTATUS_ACTIVE}
+	require.NoError(t, svrCtx.Rbac.RoleService.Create(ctx, admin))
+	root, _ := createTestUser(t, svrCtx, "root", consts.UserStatusActive)
+	require.NoError(t, svrCtx.Db.Model(root).Association("Roles").Append(admin))
+	staff, _ := createTestUser(t, svrCtx, "staff", consts.UserStatusActive)
+
+	cases := []struct {
+		name    string
+		enabled bool
+		uid     uint
+		home    uint
+		status  int
+	}{
+		{"未开启多租户不做限制", false, staff.ID, tenant.PlatformID, http.StatusOK},
+		{"平台超级管理员", true, root.ID, tenant.PlatformID, http.StatusOK},
+		{"平台普通用户", true, staff.ID, tenant.PlatformID, http.StatusForbidden},
+		{"其他租户的管理员", true, root.ID, 3, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svrCtx.Config.Tenant.Enabled = tc.enabled
+			w := httptest.NewRecorder()
+			_, router := gin.CreateTestContext(w)
+			router.POST("/menus", func(c *gin.Context) {
+				c.Set("uid", tc.uid)
+				c.Set(TENANT_HOME_CTX, tc.home)
+			}, PlatformManaged(svrCtx), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+			req, _ := http.NewRequest(http.MethodPost, "/menus", nil)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tc.status, w.Code)
+		})
+	}
+}
@@ -18,5 +18,16 @@ func init() {
 		&rbac.Role{},
 		&rbac.Permission{},
 		&rbac.Resource{},
+		&rbac.RoleResource{},
+		&rbac.SecurityEvent{},
+		&rbac.UserMFA{},
+		&rbac.MFARecoveryCode{},
+		&rbac.PasswordResetToken{},
+		&rbac.UserIdentity{},
+		&rbac.APIKey{},
+		&rbac.PasswordHistory{},
+		&rbac.Department{},
+		&rbac.Tenant{},
+		&rbac.Menu{},
 	)
 }
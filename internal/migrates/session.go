@@ -0,0 +1,19 @@
+package migrates
+
+import (
+	"gin-admin/pkg/components/jwt"
+)
+
+/*
+ * @Author: zouyx
+ * @Email: 1003941268@qq.com
+ * @Date:   2025 2025/12/9 下午3:20
+ * @Package: 会话表注册（jwt.session_store 为 db 时使用）
+ */
+
+func init() {
+	RegisterGroup("session",
+		&jwt.SessionRecord{},
+		&jwt.SessionLock{},
+	)
+}
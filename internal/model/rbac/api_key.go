@@ -0,0 +1,44 @@
+package rbac
+
+import "time"
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/13 上午10:00
+* @Package:
+ */
+
+// APIKey 用户创建的 API Key，供脚本、CI 等非交互场景调用接口
+// @Description 只保存哈希，明文仅在创建时返回一次；Prefix 用于在列表中辨认是哪一个 Key
+type APIKey struct {
+	BaseModel
+	TenantID   uint       `gorm:"not null;default:0;index" json:"tenant_id" example:"0" description:"所属租户ID，与所属用户一致"`
+	UserID     uint       `gorm:"not null;index" json:"user_id" example:"1" description:"所属用户ID"`
+	Name       string     `gorm:"size:50;not null" json:"name" example:"ci-deploy" description:"名称"`
+	Prefix     string     `gorm:"size:20;not null" json:"prefix" example:"ga_3f9c2a1b" description:"Key 前缀，用于辨认"`
+	KeyHash    string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Scopes     []string   `gorm:"serializer:json;type:text" json:"scopes" description:"可访问的资源 Code，只能是所属用户拥有的资源"`
+	ExpiresAt  *time.Time `json:"expires_at" description:"过期时间，为空表示永不过期"`
+	LastUsedAt *time.Time `json:"last_used_at" description:"最近使用时间"`
+	LastUsedIP string     `gorm:"size:45" json:"last_used_ip" description:"最近使用IP"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// Expired Key 是否已过期
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !k.ExpiresAt.After(now)
+}
+
+// HasScope 是否包含指定资源 Code
+func (k *APIKey) HasScope(code string) bool {
+	for _, s := range k.Scopes {
+		if s == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package rbac
+
+import (
+	"gin-admin/pkg/consts"
+	"gorm.io/gorm"
+	"time"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/16 上午10:20
+* @Package: 部门（组织架构）
+ */
+
+// Department 部门模型
+// @Description 部门信息模型，通过 ParentID 组成树形结构
+type Department struct {
+	BaseModel
+	TenantID uint              `gorm:"not null;default:0;index" json:"tenant_id" example:"0" description:"所属租户ID"`
+	Name     string            `gorm:"size:50;not null" json:"name" example:"研发部" description:"部门名称"`
+	ParentID uint              `gorm:"index;default:0;not null" json:"parent_id" example:"0" description:"上级部门ID，0 表示顶级部门"`
+	Sort     int               `gorm:"default:0;not null" json:"sort" example:"0" description:"排序，同级部门按从小到大排列"`
+	LeaderID *uint             `gorm:"index" json:"leader_id" example:"1" description:"负责人用户ID"`
+	Status   consts.DeptStatus `gorm:"type:tinyint;default:1;not null" json:"status" example:"1" description:"部门状态（1:启用 2:禁用）"`
+	// 下级部门，仅在查询部门树时填充
+	Children []*Department `gorm:"-" json:"children,omitempty" description:"下级部门"`
+}
+
+func (Department) TableName() string {
+	return "departments"
+}
+
+func (d *Department) BeforeCreate(tx *gorm.DB) error {
+	d.CreatedAt = time.Now()
+	return nil
+}
+
+func (d *Department) BeforeUpdate(tx *gorm.DB) error {
+	d.UpdatedAt = time.Now()
+	return nil
+}
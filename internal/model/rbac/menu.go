@@ -0,0 +1,45 @@
+package rbac
+
+import (
+	"gin-admin/pkg/consts"
+	"gorm.io/gorm"
+	"time"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/18 上午10:00
+* @Package: 前端菜单（路由）
+ */
+
+// Menu 菜单模型
+// @Description 前端菜单和路由，通过 ParentID 组成树形结构，按权限编码控制可见性
+type Menu struct {
+	BaseModel
+	Name       string            `gorm:"size:50;not null" json:"name" example:"用户管理" description:"菜单名称"`
+	ParentID   uint              `gorm:"index;default:0;not null" json:"parent_id" example:"0" description:"上级菜单ID，0 表示顶级菜单"`
+	Icon       string            `gorm:"size:100" json:"icon" example:"user" description:"图标"`
+	Path       string            `gorm:"size:200" json:"path" example:"/system/users" description:"前端路由地址"`
+	Component  string            `gorm:"size:200" json:"component" example:"system/user/index" description:"前端组件路径，目录为空"`
+	Sort       int               `gorm:"default:0;not null" json:"sort" example:"0" description:"排序，同级菜单按从小到大排列"`
+	Hidden     bool              `gorm:"default:false;not null" json:"hidden" example:"false" description:"不在导航中显示，前端仍注册路由（如详情页）"`
+	Permission string            `gorm:"size:100" json:"permission" example:"user:manage" description:"所需权限，权限分组编码或资源编码，为空表示登录即可见"`
+	Status     consts.MenuStatus `gorm:"type:tinyint;default:1;not null" json:"status" example:"1" description:"菜单状态（1:启用 2:禁用）"`
+	// 下级菜单，仅在查询菜单树时填充
+	Children []*Menu `gorm:"-" json:"children,omitempty" description:"下级菜单"`
+}
+
+func (Menu) TableName() string {
+	return "menus"
+}
+
+func (m *Menu) BeforeCreate(tx *gorm.DB) error {
+	m.CreatedAt = time.Now()
+	return nil
+}
+
+func (m *Menu) BeforeUpdate(tx *gorm.DB) error {
+	m.UpdatedAt = time.Now()
+	return nil
+}
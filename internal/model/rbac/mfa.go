@@ -0,0 +1,39 @@
+package rbac
+
+import "time"
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/11 下午2:30
+* @Package:
+ */
+
+// UserMFA 用户两步验证（TOTP）配置
+// @Description 绑定验证器后 Enabled 才为 true，未完成绑定的记录只保存待确认的密钥
+type UserMFA struct {
+	BaseModel
+	UserID uint `gorm:"not null;uniqueIndex" json:"user_id" example:"1" description:"用户ID"`
+	// TOTP 密钥，配置了 security.mfa.secret_key 时加密保存
+	Secret    string     `gorm:"size:255;not null" json:"-"`
+	Enabled   bool       `gorm:"default:false;not null" json:"enabled" description:"是否已开启"`
+	EnabledAt *time.Time `json:"enabled_at" description:"开启时间"`
+	// 最近一次验证通过的时间步，同一时间步内的口令不允许重复使用
+	LastUsedStep uint64 `gorm:"default:0;not null" json:"-"`
+}
+
+func (UserMFA) TableName() string {
+	return "user_mfa"
+}
+
+// MFARecoveryCode 两步验证恢复码，只保存哈希，每个只能使用一次
+type MFARecoveryCode struct {
+	BaseModel
+	UserID   uint       `gorm:"not null;index" json:"user_id"`
+	CodeHash string     `gorm:"size:64;not null" json:"-"`
+	UsedAt   *time.Time `json:"used_at"`
+}
+
+func (MFARecoveryCode) TableName() string {
+	return "mfa_recovery_codes"
+}
@@ -0,0 +1,19 @@
+package rbac
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/13 下午4:30
+* @Package:
+ */
+
+// PasswordHistory 用户历史密码哈希，用于禁止重复使用最近的密码
+type PasswordHistory struct {
+	BaseModel
+	UserID   uint   `gorm:"not null;index" json:"user_id"`
+	Password string `gorm:"size:255;not null" json:"-"`
+}
+
+func (PasswordHistory) TableName() string {
+	return "password_histories"
+}
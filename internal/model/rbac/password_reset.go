@@ -0,0 +1,24 @@
+package rbac
+
+import "time"
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/12 上午10:20
+* @Package:
+ */
+
+// PasswordResetToken 找回密码令牌，只保存哈希，使用一次后失效
+type PasswordResetToken struct {
+	BaseModel
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	IP        string     `gorm:"size:64" json:"ip" description:"申请重置的客户端IP"`
+}
+
+func (PasswordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
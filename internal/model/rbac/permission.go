@@ -1,6 +1,6 @@
 package rbac
 
-// Permission 权限组模型（仅作为逻辑分组，用于前端UI展示，不参与实际授权）
+// Permission 权限组模型（仅作为逻辑分组，用于前端UI展示和菜单可见性，不参与接口授权）
 type Permission struct {
 	BaseModel
 	Name      string     `gorm:"size:50;not null;uniqueIndex:idx_perm_name" json:"name" example:"用户管理" description:"权限组中文名"`
@@ -14,6 +14,9 @@ type Resource struct {
 	Description  string `gorm:"size:200" json:"description" example:"获取用户列表" description:"接口中文描述"`
 	PermissionID *uint  `gorm:"index:idx_resource_permission" json:"permission_id" example:"1" description:"所属权限分组ID（仅用于UI展示分组）"`
 	Roles        []Role `gorm:"many2many:role_resources;" json:"roles" description:"拥有该资源的角色（实际授权）"`
+	// 授权条件和禁止访问，只在按角色查询授权时填充，不对应 resources 表的列
+	Condition string `gorm:"column:grant_condition;->;-:migration" json:"condition,omitempty" description:"授权条件表达式，为空表示无条件授权"`
+	Denied    bool   `gorm:"column:grant_denied;->;-:migration" json:"denied,omitempty" description:"被角色禁止访问，优先于授权"`
 }
 
 func (r *Resource) BeforeCreate(tx *gorm.DB) error {
@@ -6,20 +6,41 @@ import (
 	"time"
 )
 
+// RoleResource 角色资源授权，Condition 非空时只有条件表达式成立才授权
 type RoleResource struct {
-	RoleId     uint `json:"role_id"`
-	ResourceId uint `json:"resource_id"`
+	RoleId     uint   `gorm:"primaryKey" json:"role_id"`
+	ResourceId uint   `gorm:"primaryKey" json:"resource_id"`
+	Condition  string `gorm:"column:condition_expr;size:500;not null;default:''" json:"condition" description:"授权条件表达式，为空表示无条件授权"`
+}
+
+func (RoleResource) TableName() string {
+	return "role_resources"
 }
 
 // Role 角色模型
 // @Description 角色信息模型
 type Role struct {
 	BaseModel
-	Name        string            `gorm:"size:50;not null;uniqueIndex:idx_role_name" json:"name" example:"admin" description:"角色名称"`
+	TenantID    uint              `gorm:"not null;default:0;uniqueIndex:idx_role_tenant_name,priority:1" json:"tenant_id" example:"0" description:"所属租户ID，0 表示平台租户"`
+	Name        string            `gorm:"size:50;not null;uniqueIndex:idx_role_tenant_name,priority:2" json:"name" example:"admin" description:"角色名称（租户内唯一）"`
 	Status      consts.RoleStatus `gorm:"type:tinyint;default:1;not null" json:"status" example:"1" description:"角色状态（1:启用 2:禁用）"`
 	BuiltIn     bool              `gorm:"default:false" json:"built_in" description:"保护内置角色不被外部删除"`
 	Description string            `gorm:"size:200;index:idx_role_desc" json:"description" example:"系统管理员" description:"角色描述"`
-	Resources   []Resource        `gorm:"many2many:role_resources;" json:"resources" description:"角色可访问的资源（实际授权）"`
+	RequireMFA  bool              `gorm:"default:false;not null" json:"require_mfa" description:"该角色的用户必须开启两步验证"`
+	// 数据权限，默认全部数据
+	DataScope        consts.DataScope `gorm:"type:tinyint;default:1;not null" json:"data_scope" example:"1" description:"数据权限（1:全部 2:自定义部门 3:本部门及下级 4:本部门 5:仅本人）"`
+	DataScopeDeptIDs []uint           `gorm:"serializer:json;type:text" json:"data_scope_dept_ids" description:"自定义数据权限的部门ID"`
+	Resources        []Resource       `gorm:"many2many:role_resources;" json:"resources" description:"角色可访问的资源（实际授权）"`
+	// 禁止访问的资源，下级角色同样继承；用户的任一角色禁止后，其他角色的授权都不再生效
+	DeniedResources []Resource `gorm:"many2many:role_resource_denies;" json:"denied_resources" description:"禁止访问的资源，优先于授权"`
+	// 上级角色，继承所有上级角色（含间接上级）的资源
+	Parents []Role `gorm:"many2many:role_parents;joinForeignKey:RoleID;joinReferences:ParentID" json:"parents,omitempty" description:"上级角色"`
+}
+
+// RoleParent 角色继承关系
+type RoleParent struct {
+	RoleID   uint `json:"role_id"`
+	ParentID uint `json:"parent_id"`
 }
 
 func (Role) TableName() string {
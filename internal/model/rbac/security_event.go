@@ -0,0 +1,32 @@
+package rbac
+
+import "gin-admin/pkg/consts"
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/10 上午10:05
+* @Package:
+ */
+
+// SecurityEvent 安全事件
+// @Description 安全事件记录（如 refresh token 重用、模拟登录），用于审计追溯
+type SecurityEvent struct {
+	BaseModel
+	TenantID  uint                     `gorm:"not null;default:0;index" json:"tenant_id" example:"0" description:"所属租户ID"`
+	Type      consts.SecurityEventType `gorm:"size:50;not null;index" json:"type" example:"refresh_token_reuse" description:"事件类型"`
+	UserID    uint                     `gorm:"not null;index" json:"user_id" example:"1" description:"用户ID"`
+	Username  string                   `gorm:"size:50" json:"username" example:"johndoe" description:"用户名"`
+	SessionID string                   `gorm:"size:64;index" json:"session_id" description:"会话ID"`
+	DeviceID  string                   `gorm:"size:128" json:"device_id" description:"设备ID"`
+	IP        string                   `gorm:"size:64" json:"ip" example:"127.0.0.1" description:"客户端IP"`
+	UserAgent string                   `gorm:"size:255" json:"user_agent" description:"客户端 User-Agent"`
+	Action    string                   `gorm:"size:50" json:"action" example:"revoke_all" description:"已执行的处置措施"`
+	// 模拟登录相关事件记录发起模拟的管理员和请求内容
+	ImpersonatorID uint   `gorm:"not null;default:0;index" json:"impersonator_id,omitempty" example:"1" description:"模拟登录的管理员ID"`
+	Detail         string `gorm:"size:512" json:"detail,omitempty" example:"POST /api/v1/users/1" description:"事件详情"`
+}
+
+func (SecurityEvent) TableName() string {
+	return "security_events"
+}
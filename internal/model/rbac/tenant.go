@@ -0,0 +1,37 @@
+package rbac
+
+import (
+	"gin-admin/pkg/consts"
+	"gorm.io/gorm"
+	"time"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/17 上午9:30
+* @Package: 租户
+ */
+
+// Tenant 租户模型
+// @Description 租户信息模型；ID 为 0 的平台租户是隐含的，不对应记录
+type Tenant struct {
+	BaseModel
+	Name   string              `gorm:"size:100;not null" json:"name" example:"示例公司" description:"租户名称"`
+	Code   string              `gorm:"size:50;not null;uniqueIndex" json:"code" example:"acme" description:"租户编码，全局唯一"`
+	Status consts.TenantStatus `gorm:"type:tinyint;default:1;not null" json:"status" example:"1" description:"租户状态（1:启用 2:禁用）"`
+}
+
+func (Tenant) TableName() string {
+	return "tenants"
+}
+
+func (t *Tenant) BeforeCreate(tx *gorm.DB) error {
+	t.CreatedAt = time.Now()
+	return nil
+}
+
+func (t *Tenant) BeforeUpdate(tx *gorm.DB) error {
+	t.UpdatedAt = time.Now()
+	return nil
+}
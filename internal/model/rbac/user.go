@@ -1,8 +1,10 @@
 package rbac
 
 import (
+	"errors"
+	"gin-admin/pkg/components/password"
+	"gin-admin/pkg/components/uploader"
 	"gin-admin/pkg/consts"
-	"golang.org/x/crypto/bcrypt"
 	"time"
 
 	"gorm.io/gorm"
@@ -12,33 +14,72 @@ import (
 // @Description 用户信息模型
 type User struct {
 	BaseModel
-	Username string            `gorm:"size:50;not null;uniqueIndex" json:"username" example:"johndoe" description:"用户名"`
-	Password string            `gorm:"size:100;not null" json:"password" description:"密码"`
-	Email    string            `gorm:"size:100;uniqueIndex" json:"email" example:"john@example.com" description:"邮箱"`
-	Avatar   string            `gorm:"size:255" json:"avatar" example:"https://example.com/avatar.jpg" description:"头像URL"`
+	TenantID uint              `gorm:"not null;default:0;uniqueIndex:idx_users_tenant_username,priority:1;uniqueIndex:idx_users_tenant_email,priority:1" json:"tenant_id" example:"0" description:"所属租户ID，0 表示平台租户"`
+	Username string            `gorm:"size:50;not null;uniqueIndex:idx_users_tenant_username,priority:2" json:"username" example:"johndoe" description:"用户名（租户内唯一）"`
+	Password string            `gorm:"size:255;not null" json:"password" description:"密码哈希（PHC 格式），通过 SetPassword 设置"`
+	Email    string            `gorm:"size:100;uniqueIndex:idx_users_tenant_email,priority:2" json:"email" example:"john@example.com" description:"邮箱（租户内唯一）"`
+	Avatar   uploader.FilePath `gorm:"size:255" json:"avatar" example:"https://example.com/avatar.jpg" description:"头像URL（保存相对路径，序列化为完整URL）"`
 	BuiltIn  bool              `gorm:"default:false" json:"built_in" description:"保护内置用户不被外部删除"`
 	Gender   consts.Gender     `gorm:"type:tinyint;default:0;not null" json:"gender" example:"1"`
 	Status   consts.UserStatus `gorm:"type:tinyint;default:1;not null" json:"status" example:"1" description:"用户状态"`
-	Roles    []Role            `gorm:"many2many:user_roles;" json:"roles" description:"用户角色"`
+	DeptID   uint              `gorm:"index;default:0;not null" json:"dept_id" example:"1" description:"所属部门ID，0 表示未分配"`
+	// 临时锁定的解锁时间，为空且状态为锁定时表示需要管理员解锁
+	LockedUntil *time.Time `json:"locked_until" description:"解锁时间"`
+	// 邮箱验证时间，为空表示未验证
+	EmailVerifiedAt *time.Time `json:"email_verified_at" description:"邮箱验证时间"`
+	// 修改后待验证的新邮箱，验证通过后才替换 Email，之前登录、找回密码仍使用原邮箱
+	PendingEmail string `gorm:"size:100" json:"pending_email" description:"待验证的新邮箱"`
+	// 最近一次设置密码的时间，用于密码有效期
+	PasswordChangedAt *time.Time `json:"password_changed_at" description:"密码修改时间"`
+	Roles             []Role     `gorm:"many2many:user_roles;" json:"roles" description:"用户角色"`
+
+	// plainPassword 通过 SetPassword 设置的明文密码，保存前计算哈希后清空
+	// Password 字段本身始终视为哈希，不再根据内容猜测是否需要加密
+	plainPassword string
+	// passwordChanged 本次保存设置了新密码，保存后写入历史密码
+	passwordChanged bool
 }
 
 func (User) TableName() string {
 	return "users"
 }
 
-// BeforeSave 保存前的钩子函数
+// SetPassword 设置新密码（明文），保存时计算哈希
+func (u *User) SetPassword(plain string) {
+	u.plainPassword = plain
+}
+
+// BeforeSave 保存前的钩子函数，只对通过 SetPassword 设置的明文计算哈希
 func (u *User) BeforeSave(tx *gorm.DB) error {
-	// 如果密码已经是哈希值，则不再加密
-	if len(u.Password) == 60 && u.Password[0:4] == "$2a$" {
+	if u.plainPassword == "" {
 		return nil
 	}
-
-	// 加密密码
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+	hashed, err := password.Hash(u.plainPassword)
 	if err != nil {
 		return err
 	}
-	u.Password = string(hashedPassword)
+	now := time.Now()
+	u.Password = hashed
+	u.PasswordChangedAt = &now
+	u.plainPassword = ""
+	u.passwordChanged = true
+	return nil
+}
+
+// AfterSave 记录历史密码
+func (u *User) AfterSave(tx *gorm.DB) error {
+	if !u.passwordChanged {
+		return nil
+	}
+	u.passwordChanged = false
+	return tx.Create(&PasswordHistory{UserID: u.ID, Password: u.Password}).Error
+}
+
+// BeforeCreate 新用户必须通过 SetPassword 设置密码
+func (u *User) BeforeCreate(tx *gorm.DB) error {
+	if u.Password == "" {
+		return errors.New("user password is not set")
+	}
 	return nil
 }
 
@@ -47,8 +88,24 @@ func (u *User) BeforeUpdate(tx *gorm.DB) error {
 	return nil
 }
 
-// CheckPassword 检查密码是否正确
-func (u *User) CheckPassword(password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
-	return err == nil
+// PasswordSetAt 密码设置时间，早期用户没有记录时使用注册时间
+func (u *User) PasswordSetAt() time.Time {
+	if u.PasswordChangedAt != nil {
+		return *u.PasswordChangedAt
+	}
+	return u.CreatedAt
+}
+
+// Locked 是否处于锁定状态，临时锁定到期后视为未锁定
+func (u *User) Locked(now time.Time) bool {
+	if u.Status != consts.UserStatusLocked {
+		return false
+	}
+	return u.LockedUntil == nil || now.Before(*u.LockedUntil)
+}
+
+// CheckPassword 检查密码是否正确，rehash 为 true 时表示哈希参数已过时，应重新计算
+func (u *User) CheckPassword(plain string) (ok, rehash bool) {
+	ok, rehash, err := password.Verify(u.Password, plain)
+	return ok && err == nil, rehash
 }
@@ -0,0 +1,24 @@
+package rbac
+
+import "time"
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/12 下午3:10
+* @Package:
+ */
+
+// UserIdentity 第三方账号关联，同一提供方的同一个 subject 只能关联一个用户
+type UserIdentity struct {
+	BaseModel
+	UserID      uint      `gorm:"not null;index" json:"user_id" example:"1" description:"用户ID"`
+	Provider    string    `gorm:"size:50;not null;uniqueIndex:idx_identity_provider_subject,priority:1" json:"provider" example:"corp" description:"提供方"`
+	Subject     string    `gorm:"size:255;not null;uniqueIndex:idx_identity_provider_subject,priority:2" json:"subject" description:"提供方中的用户唯一标识"`
+	Email       string    `gorm:"size:100" json:"email" description:"提供方返回的邮箱"`
+	LastLoginAt time.Time `json:"last_login_at" description:"最近一次通过该提供方登录的时间"`
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"gin-admin/internal/model/rbac"
+	"gin-admin/pkg/components/tenant"
 	_interface "gin-admin/pkg/interface"
 	"github.com/sirupsen/logrus"
 	"math/rand"
@@ -22,9 +23,10 @@ import (
 // ICacheService 缓存服务接口
 type ICacheService interface {
 	// 权限相关缓存
-	CheckUserPermission(ctx context.Context, userID uint, path, method string, fn func(ctx context.Context, uid uint) ([]rbac.Resource, error)) (bool, error)
+	CheckUserPermission(ctx context.Context, userID uint, path, method string, fn func(ctx context.Context, uid uint) ([]rbac.Resource, error)) (granted, conditional bool, err error)
 	ClearUserPermissions(ctx context.Context, userID uint, ttl time.Duration, updateFn func() error) error
 	ClearMultipleUsersPermissions(ctx context.Context, userIDs []uint, ttl time.Duration, updateFn func() error) error
+	ClearRoleUsersPermissions(ctx context.Context, roleID uint, ttl time.Duration, listUsers func(ctx context.Context, roleID uint) ([]uint, error), updateFn func() error) error
 	SetUserPermissions(ctx context.Context, userID uint, resources []rbac.Resource) error
 	ClearAllPermissions(ctx context.Context) error
 	// Token黑名单
@@ -42,11 +44,14 @@ type ICacheService interface {
 type cacheService struct {
 	client _interface.ICache
 	sg     singleflight.Group // 防止缓存击穿（多个请求同时查询同一个不存在的key）
+	// tenantScoped 开启了多租户，权限缓存键按租户划分命名空间（同一用户在不同租户下的授权不同）
+	tenantScoped bool
 }
 
-func NewCacheService(cache _interface.ICache) ICacheService {
+func NewCacheService(cache _interface.ICache, tenantScoped bool) ICacheService {
 	return &cacheService{
-		client: cache,
+		client:       cache,
+		tenantScoped: tenantScoped,
 	}
 }
 
@@ -56,13 +61,14 @@ func NewCacheService(cache _interface.ICache) ICacheService {
 
 const (
 	// 缓存Key前缀
-	cacheKeyPermissionPrefix = "permission:"       // 权限: permission:userID (使用Set存储 path_method)
+	cacheKeyPermissionPrefix = "permission:"       // 权限: permission:[t租户ID:]userID (使用Set存储 path_method)
 	cacheKeyToken            = "token:%s"          // Token黑名单: token:tokenString
 	cacheKeyJWTBlacklist     = "jwt:blacklist:%s"  // JWT黑名单: jwt:blacklist:token
 	cacheKeyUserSessions     = "user:sessions:%d"  // 用户会话: user:sessions:userID -> Set[sessionID]
 	cacheKeySessionTokens    = "session:tokens:%s" // 会话令牌: session:tokens:sessionID -> {access, refresh}
 	cacheKeyRefreshCount     = "refresh:count:%s"  // 刷新计数: refresh:count:refreshToken -> count
 	cacheKeyEmptyMarker      = "empty:%s"          // 空值标记（防止缓存穿透）
+	conditionalMemberPrefix  = "?"                 // 条件授权的成员前缀: ?GET_/api/v1/users，条件本身不缓存
 
 	// 缓存TTL
 	ttlPermission       = 10 * time.Minute   // 权限缓存10分钟
@@ -76,36 +82,64 @@ const (
 // 权限相关缓存
 // ================================
 
+// permissionKey 用户权限缓存键，开启多租户时带上租户命名空间：permission:t1:userID
+func (s *cacheService) permissionKey(ctx context.Context, userID uint) string {
+	namespace := ""
+	if s.tenantScoped {
+		namespace = tenant.Namespace(ctx)
+	}
+	return fmt.Sprintf("%s%s%d", cacheKeyPermissionPrefix, namespace, userID)
+}
+
+// allTenants 开启多租户时在没有确定租户的上下文中修改权限（如平台后台任务），用户的权限可能缓存在任意租户的命名空间下
+// 此时不带租户的前缀 permission: 覆盖所有租户，失效时整体清除权限缓存
+func (s *cacheService) allTenants(ctx context.Context) bool {
+	return s.tenantScoped && tenant.Namespace(ctx) == ""
+}
+
+// deletePermissions 删除用户在当前租户下的权限缓存，没有确定租户时清除所有权限缓存
+func (s *cacheService) deletePermissions(ctx context.Context, userIDs []uint) error {
+	if s.allTenants(ctx) {
+		return s.client.DeletePrefix(ctx, cacheKeyPermissionPrefix)
+	}
+	keys := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		keys = append(keys, s.permissionKey(ctx, userID))
+	}
+	return s.client.Delete(ctx, keys...)
+}
+
 // CheckUserPermission 检查用户权限（带缓存 + 防穿透 + 防击穿）
-// 使用Redis Set存储用户的所有权限资源，格式：permission:userID -> Set["GET_/api/v1/users", "POST_/api/v1/posts"]
+// 使用Redis Set存储用户的所有权限资源，格式：permission:[t租户ID:]userID -> Set["GET_/api/v1/users", "POST_/api/v1/posts"]
+// 无条件授权时 granted 为 true；只有条件授权时 conditional 为 true，由调用方查询条件并求值
 // 优化措施：
 // 1. 防穿透：缓存空权限（用户没有任何权限时也缓存）
 // 2. 防击穿：使用 singleflight 确保同一个 key 只有一个请求去查询数据库
 // 3. 防雪崩：TTL 添加随机偏移
-func (s *cacheService) CheckUserPermission(ctx context.Context, userID uint, path, method string, fn func(ctx context.Context, uid uint) ([]rbac.Resource, error)) (bool, error) {
+func (s *cacheService) CheckUserPermission(ctx context.Context, userID uint, path, method string, fn func(ctx context.Context, uid uint) ([]rbac.Resource, error)) (granted, conditional bool, err error) {
 	if s.client == nil {
 		// 缓存不可用
-		return false, _interface.ErrUnreachable
+		return false, false, _interface.ErrUnreachable
 	}
 
-	cacheKey := fmt.Sprintf("%s%d", cacheKeyPermissionPrefix, userID)
+	cacheKey := s.permissionKey(ctx, userID)
 	member := fmt.Sprintf("%s_%s", method, path)
 
 	// 查询缓存key是否存在
 	exists, err := s.client.Exists(ctx, cacheKey)
 	if err != nil {
 		// 缓存查询失败
-		return false, err
+		return false, false, err
 	}
 	// 缓存命中
 	if exists {
 		logrus.Info("success check user permission from cache")
-		return s.client.SIsMember(ctx, cacheKey, member)
+		return s.checkMember(ctx, cacheKey, member)
 	}
 
 	// 缓存未命中：使用 singleflight 防止缓存击穿
 	// 同一时刻只有一个请求去查询数据库并设置缓存
-	sfKey := fmt.Sprintf("load_permission:%d", userID)
+	sfKey := "load_" + cacheKey
 	_, err, _ = s.sg.Do(sfKey, func() (interface{}, error) {
 		// Double Check
 		exists, err = s.client.Exists(ctx, cacheKey)
@@ -124,24 +158,54 @@ func (s *cacheService) CheckUserPermission(ctx context.Context, userID uint, pat
 	})
 	if err != nil {
 		// 加载失败
-		return false, err
+		return false, false, err
 	}
 	// check
-	return s.client.SIsMember(ctx, cacheKey, member)
+	return s.checkMember(ctx, cacheKey, member)
+}
+
+// checkMember 无条件授权的成员不存在时，再检查是否有条件授权
+func (s *cacheService) checkMember(ctx context.Context, cacheKey, member string) (granted, conditional bool, err error) {
+	granted, err = s.client.SIsMember(ctx, cacheKey, member)
+	if err != nil || granted {
+		return granted, false, err
+	}
+	conditional, err = s.client.SIsMember(ctx, cacheKey, conditionalMemberPrefix+member)
+	return false, conditional, err
 }
 
 // SetUserPermissions 设置用户权限缓存
 // 应该在用户登录或权限变更后调用
+// resources 中 Denied 的资源不写入缓存，同一接口的授权（含条件授权）也一并排除，禁止优先于授权
 func (s *cacheService) SetUserPermissions(ctx context.Context, userID uint, resources []rbac.Resource) error {
 	if s.client == nil {
 		return nil
 	}
-	cacheKey := fmt.Sprintf("%s%d", cacheKeyPermissionPrefix, userID)
+	cacheKey := s.permissionKey(ctx, userID)
+
+	denied := make(map[string]struct{})
+	for _, resource := range resources {
+		if resource.Denied {
+			denied[fmt.Sprintf("%s_%s", resource.Method, resource.Path)] = struct{}{}
+		}
+	}
+	// 添加所有权限到Set
+	members := make([]interface{}, 0, len(resources))
+	for _, resource := range resources {
+		member := fmt.Sprintf("%s_%s", resource.Method, resource.Path)
+		if _, ok := denied[member]; ok {
+			continue
+		}
+		if resource.Condition != "" {
+			member = conditionalMemberPrefix + member
+		}
+		members = append(members, member)
+	}
 
 	// 先删除旧缓存
 	_ = s.client.Delete(ctx, cacheKey)
 	// 如果没有权限，设置一个空标记
-	if len(resources) == 0 {
+	if len(members) == 0 {
 		// 使用一个特殊值标记"空权限"
 		emptyMarker := "_EMPTY_"
 		if err := s.client.SAdd(ctx, cacheKey, emptyMarker); err != nil {
@@ -150,12 +214,6 @@ func (s *cacheService) SetUserPermissions(ctx context.Context, userID uint, reso
 		// todo: 理论上empty权限标记超时时间较短，这儿后面来加吧
 		return s.client.Expire(ctx, cacheKey, s.getPermissionTTL())
 	}
-	// 添加所有权限到Set
-	members := make([]interface{}, 0, len(resources))
-	for _, resource := range resources {
-		member := fmt.Sprintf("%s_%s", resource.Method, resource.Path)
-		members = append(members, member)
-	}
 	if err := s.client.SAdd(ctx, cacheKey, members...); err != nil {
 		return err
 	}
@@ -175,8 +233,8 @@ func (s *cacheService) ClearUserPermissions(ctx context.Context, userID uint, tt
 		return nil
 	}
 
-	key := fmt.Sprintf("%s%d", cacheKeyPermissionPrefix, userID)
-	if err := s.client.Delete(ctx, key); err != nil {
+	key := s.permissionKey(ctx, userID)
+	if err := s.deletePermissions(ctx, []uint{userID}); err != nil {
 		logrus.Printf("[DelayDoubleDelete] first delete failed: key=%s, err=%v", key, err)
 		return err
 	}
@@ -185,7 +243,8 @@ func (s *cacheService) ClearUserPermissions(ctx context.Context, userID uint, tt
 		logrus.Printf("[DelayDoubleDelete] updateFn failed: key=%s, err=%v", key, err)
 		return err
 	}
-	// 异步延迟删除
+	// 异步延迟删除，保留上下文中的租户
+	delCtx := context.WithoutCancel(ctx)
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
@@ -193,7 +252,7 @@ func (s *cacheService) ClearUserPermissions(ctx context.Context, userID uint, tt
 			}
 		}()
 		time.Sleep(ttl)
-		if err := s.client.Delete(context.Background(), key); err != nil {
+		if err := s.deletePermissions(delCtx, []uint{userID}); err != nil {
 			logrus.Printf("[DelayDoubleDelete] second delete failed: key=%s, err=%v", key, err)
 		}
 	}()
@@ -204,14 +263,9 @@ func (s *cacheService) ClearUserPermissions(ctx context.Context, userID uint, tt
 func (s *cacheService) ClearMultipleUsersPermissions(ctx context.Context, userIDs []uint, ttl time.Duration, updateFn func() error) error {
 	// 第一次删除缓存
 	if s.client != nil && len(userIDs) > 0 {
-		keys := make([]string, 0, len(userIDs))
-		for _, userID := range userIDs {
-			keys = append(keys, fmt.Sprintf("%s%d", cacheKeyPermissionPrefix, userID))
-		}
-
 		// 删除缓存，失败不影响后续数据库更新
-		if err := s.client.Delete(ctx, keys...); err != nil {
-			logrus.Printf("[DelayDoubleDelete] first delete failed: keys=%v, err=%v", keys, err)
+		if err := s.deletePermissions(ctx, userIDs); err != nil {
+			logrus.Printf("[DelayDoubleDelete] first delete failed: users=%v, err=%v", userIDs, err)
 		}
 	}
 
@@ -221,28 +275,34 @@ func (s *cacheService) ClearMultipleUsersPermissions(ctx context.Context, userID
 		return err
 	}
 
-	// 异步延迟删除（如果 cache 存在且有 userIDs）
+	// 异步延迟删除（如果 cache 存在且有 userIDs），保留上下文中的租户
 	if s.client != nil && len(userIDs) > 0 {
-		keys := make([]string, 0, len(userIDs))
-		for _, userID := range userIDs {
-			keys = append(keys, fmt.Sprintf("%s%d", cacheKeyPermissionPrefix, userID))
-		}
-
-		go func(rKeys []string) {
+		go func(delCtx context.Context) {
 			defer func() {
 				if r := recover(); r != nil {
 					logrus.Printf("[DelayDoubleDelete] panic: %v", r)
 				}
 			}()
 			time.Sleep(ttl)
-			if err := s.client.Delete(context.Background(), rKeys...); err != nil {
-				logrus.Printf("[DelayDoubleDelete] second delete failed: keys=%v, err=%v", rKeys, err)
+			if err := s.deletePermissions(delCtx, userIDs); err != nil {
+				logrus.Printf("[DelayDoubleDelete] second delete failed: users=%v, err=%v", userIDs, err)
 			}
-		}(keys)
+		}(context.WithoutCancel(ctx))
 	}
 
 	return nil
 }
+
+// ClearRoleUsersPermissions 角色的资源或继承关系变更时，清除拥有该角色及其下级角色的所有用户的权限缓存（延迟双删）
+// listUsers 需返回该角色及其所有下级角色的用户，在更新数据库之前调用
+func (s *cacheService) ClearRoleUsersPermissions(ctx context.Context, roleID uint, ttl time.Duration, listUsers func(ctx context.Context, roleID uint) ([]uint, error), updateFn func() error) error {
+	userIDs, err := listUsers(ctx, roleID)
+	if err != nil {
+		return err
+	}
+	return s.ClearMultipleUsersPermissions(ctx, userIDs, ttl, updateFn)
+}
+
 func (s *cacheService) ClearAllPermissions(ctx context.Context) error {
 	if s.client == nil {
 		return nil
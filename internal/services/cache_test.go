@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"gin-admin/internal/model/rbac"
+	cache2 "gin-admin/pkg/components/cache"
+	"gin-admin/pkg/components/tenant"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantPermissionCache(t *testing.T) {
+	cache := cache2.NewShardedMemoryCache(0)
+	svc := NewCacheService(cache, true)
+	ctx := context.Background()
+	ctx1, ctx2 := tenant.WithID(ctx, 1), tenant.WithID(ctx, 2)
+
+	// 同一用户在租户 1 下有权限，在租户 2 下没有
+	grants := func(ctx context.Context, uid uint) ([]rbac.Resource, error) {
+		if id, _ := tenant.FromContext(ctx); id == 1 {
+			return []rbac.Resource{{Path: "/items", Method: http.MethodGet}}, nil
+		}
+		return nil, nil
+	}
+	check := func(ctx context.Context) bool {
+		granted, _, err := svc.CheckUserPermission(ctx, 7, "/items", http.MethodGet, grants)
+		require.NoError(t, err)
+		return granted
+	}
+	exists := func(key string) bool {
+		ok, err := cache.Exists(ctx, key)
+		require.NoError(t, err)
+		return ok
+	}
+	assert.True(t, check(ctx1))
+	assert.False(t, check(ctx2))
+	assert.True(t, exists("permission:t1:7"))
+	assert.True(t, exists("permission:t2:7"))
+
+	noop := func() error { return nil }
+	// 带租户时只清除该租户下的缓存
+	require.NoError(t, svc.ClearUserPermissions(ctx1, 7, time.Millisecond, noop))
+	assert.False(t, exists("permission:t1:7"))
+	assert.True(t, exists("permission:t2:7"))
+
+	// 没有确定租户时清除所有租户下的缓存
+	require.NoError(t, svc.SetUserPermissions(ctx1, 7, nil))
+	require.NoError(t, svc.ClearMultipleUsersPermissions(ctx, []uint{7}, time.Millisecond, noop))
+	assert.False(t, exists("permission:t1:7"))
+	assert.False(t, exists("permission:t2:7"))
+}
@@ -1,15 +1,22 @@
 package services
 
 import (
+	"context"
 	"gin-admin/internal/config"
 	rbac2 "gin-admin/internal/services/rbac"
 	cache2 "gin-admin/pkg/components/cache"
 	"gin-admin/pkg/components/jwt"
+	"gin-admin/pkg/components/mailer"
 	"gin-admin/pkg/components/orm"
+	"gin-admin/pkg/components/password"
 	redis2 "gin-admin/pkg/components/redis"
+	"gin-admin/pkg/components/tenant"
+	"gin-admin/pkg/components/totp"
 	"gin-admin/pkg/components/uploader"
 	_interface "gin-admin/pkg/interface"
+	"gin-admin/pkg/validator"
 	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
@@ -30,9 +37,18 @@ type ServiceContext struct {
 	Db       *gorm.DB
 	Cache    _interface.ICache
 	Uploader _interface.IUploader
+	Mailer   _interface.IMailer
 	Jwt      jwt.Service
 	// 服务缓存
 	CacheService ICacheService
+	// 登录失败限制
+	LoginGuard *LoginGuard
+	// 两步验证登录凭证
+	MFAChallenges *MFAChallengeStore
+	// 第三方登录
+	OAuth *OAuthProviders
+	// 密码策略
+	PasswordPolicy *password.Policy
 	// RBAC Services
 	Rbac *rbac2.Context
 }
@@ -42,21 +58,102 @@ func MustInitServiceContext(c *config.AppConfig) *ServiceContext {
 	if err != nil {
 		panic(err)
 	}
+	// 租户插件需在创建各个 Service 之前注册，Service 据此为租户隔离的模型划分缓存命名空间
+	if c.Tenant.Enabled {
+		if err = db.Use(tenant.Plugin{}); err != nil {
+			panic(err)
+		}
+	}
 	var redisClient *redis.Client
 	if c.Cache != nil {
 		redisClient, err = redis2.NewClient(*c.Cache)
 	}
 	// 初始化缓存
 	cacheInstance := cache2.NewCache(redisClient)
+	// 密码哈希算法，需在任何用户写入之前设置
+	password.SetDefault(password.NewHasher(c.Security.PasswordHash))
 
 	SvcContext = &ServiceContext{
-		Config:       c,
-		Db:           db,
-		Cache:        cacheInstance,
-		Uploader:     uploader.NewUploader(*c.Upload, c.Server.Port),
-		CacheService: NewCacheService(cacheInstance),
-		Jwt:          jwt.NewJwtService(*c.Jwt, cacheInstance),
-		Rbac:         rbac2.NewContext(db, cacheInstance),
+		Config:         c,
+		Db:             db,
+		Cache:          cacheInstance,
+		Uploader:       uploader.NewUploader(*c.Upload, c.Server.Port),
+		CacheService:   NewCacheService(cacheInstance, c.Tenant.Enabled),
+		LoginGuard:     NewLoginGuard(c.Security.LoginGuard, cacheInstance),
+		MFAChallenges:  NewMFAChallengeStore(c.Security.MFA, cacheInstance),
+		OAuth:          NewOAuthProviders(c.SSO, cacheInstance),
+		PasswordPolicy: password.NewPolicy(c.Security.PasswordPolicy),
+		Rbac:           rbac2.NewContext(db, cacheInstance),
 	}
+	// 请求参数中的 password 标签使用同一份密码策略
+	validator.SetPasswordPolicy(SvcContext.PasswordPolicy)
+	SvcContext.Mailer = mustInitMailer(c.Mail, c.App.Env)
+	SvcContext.Rbac.MFAService.SetSecretBox(mustInitMFASecretBox(c))
+	mustCheckJwtKeyCache(c, cacheInstance)
+	SvcContext.Jwt = jwt.NewJwtService(*c.Jwt, cacheInstance, SvcContext.jwtOptions()...)
 	return SvcContext
 }
+
+// mustInitMailer 开发环境未配置邮件时写入本地发件箱，避免依赖邮件服务器
+// 其他环境必须配置邮件，避免重置密码等邮件被静默写入本地文件
+func mustInitMailer(c *mailer.Config, env string) _interface.IMailer {
+	if c == nil {
+		if env != "dev" {
+			panic("mail is not configured")
+		}
+		logrus.Warn("mail is not configured, emails will be written to ./outbox")
+		c = &mailer.Config{Type: mailer.TypeFile, From: "noreply@localhost"}
+	}
+	m, err := mailer.NewMailer(*c)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// mustInitMFASecretBox TOTP 密钥加密保存，除开发环境外必须配置加密密钥
+func mustInitMFASecretBox(c *config.AppConfig) *totp.SecretBox {
+	key := c.Security.MFA.SecretKey
+	if key == "" {
+		if c.App.Env != "dev" {
+			panic("security.mfa.secret_key is required to encrypt totp secrets")
+		}
+		logrus.Warn("security.mfa.secret_key is not configured, totp secrets will be stored in plaintext")
+	}
+	box, err := totp.NewSecretBox(key)
+	if err != nil {
+		panic(err)
+	}
+	return box
+}
+
+// mustCheckJwtKeyCache 自动轮换的签名密钥保存在缓存中，内存缓存下每个实例各自生成密钥、重启后密钥丢失，
+// 未配置 key_ring_secret 时私钥明文写入缓存，能读取 Redis 的人即可伪造 token；除开发环境外均拒绝启动
+func mustCheckJwtKeyCache(c *config.AppConfig, cache _interface.ICache) {
+	if !c.Jwt.DynamicKeys() {
+		return
+	}
+	if !cache2.IsShared(cache) {
+		if c.App.Env != "dev" {
+			panic("jwt key rotation requires a shared redis cache, configure cache or static jwt keys")
+		}
+		logrus.Warn("jwt signing keys are generated into the in-memory cache, they are lost on restart and not shared between instances")
+	}
+	if c.Jwt.KeyRingSecret == "" {
+		if c.App.Env != "dev" {
+			panic("jwt.key_ring_secret is required to encrypt generated jwt signing keys")
+		}
+		logrus.Warn("jwt.key_ring_secret is not configured, generated jwt signing keys will be cached in plaintext")
+	}
+}
+
+// jwtOptions 根据配置选择会话存储，并挂载安全事件处理
+func (s *ServiceContext) jwtOptions() []jwt.ServiceOption {
+	opts := []jwt.ServiceOption{jwt.WithReuseHandler(s.onRefreshTokenReuse)}
+	if s.Config.Jwt.SessionStore == jwt.SessionStoreDB {
+		manager := jwt.NewDBSessionManager(s.Db)
+		manager.StartCleanup(context.Background(), 0)
+		opts = append(opts, jwt.WithSessionManager(manager))
+	}
+	return opts
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gin-admin/internal/model/rbac"
+	"gin-admin/pkg/components/jwt"
+	_interface "gin-admin/pkg/interface"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/14 下午3:10
+* @Package: 注册邮箱验证
+ */
+
+const (
+	cacheKeyEmailVerifyCooldown = "email:verify:cooldown:%s" // 发送验证邮件的冷却期
+	cacheKeyEmailVerifyCount    = "email:verify:count:%s"    // 当天发送验证邮件的次数
+	cacheKeyEmailChange         = "email:change:%s"          // 修改邮箱的确认令牌（哈希）对应的用户和新邮箱
+)
+
+var (
+	ErrEmailVerifyTokenInvalid = errors.New("验证链接无效或已过期")
+	ErrEmailVerifySecretEmpty  = errors.New("未配置邮箱验证签名密钥")
+)
+
+// emailChange 待确认的邮箱修改
+type emailChange struct {
+	UserID uint   `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+// EmailVerificationEnabled 是否开启注册邮箱验证
+func (s *ServiceContext) EmailVerificationEnabled() bool {
+	return s.Config.Security.EmailVerification.Enabled
+}
+
+// emailVerifyTTL 验证链接有效期
+func (s *ServiceContext) emailVerifyTTL() time.Duration {
+	if ttl := s.Config.Security.EmailVerification.TokenTTL; ttl > 0 {
+		return ttl
+	}
+	return 24 * time.Hour
+}
+
+// emailVerifySecret 验证链接的签名密钥，开启邮箱验证时启动前已校验必须配置
+func (s *ServiceContext) emailVerifySecret() ([]byte, error) {
+	secret := s.Config.Security.EmailVerification.Secret
+	if secret == "" {
+		return nil, ErrEmailVerifySecretEmpty
+	}
+	return []byte(secret), nil
+}
+
+// AllowVerificationMail 按邮箱限制验证邮件的发送频率（冷却期 + 每日上限），不论邮箱是否已注册
+// 被限制时返回需要等待的时间
+func (s *ServiceContext) AllowVerificationMail(ctx context.Context, email string) (time.Duration, error) {
+	cfg := s.Config.Security.EmailVerification
+	cooldown, limit := cfg.Cooldown, cfg.DailyLimit
+	if cooldown <= 0 {
+		cooldown = time.Minute
+	}
+	if limit <= 0 {
+		limit = 5
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	// 冷却期用 SetNX 抢占，并发请求只有一个能通过
+	cooldownKey := fmt.Sprintf(cacheKeyEmailVerifyCooldown, email)
+	ok, err := s.Cache.SetNX(ctx, cooldownKey, 1, cooldown)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return s.cacheTTL(ctx, cooldownKey, cooldown), nil
+	}
+
+	countKey := fmt.Sprintf(cacheKeyEmailVerifyCount, email)
+	count, err := _interface.IncrWithTTL(ctx, s.Cache, countKey, 24*time.Hour)
+	if err != nil {
+		return 0, err
+	}
+	if count > int64(limit) {
+		return s.cacheTTL(ctx, countKey, 24*time.Hour), nil
+	}
+	return 0, nil
+}
+
+// cacheTTL 缓存剩余有效期，取不到时使用 fallback
+func (s *ServiceContext) cacheTTL(ctx context.Context, key string, fallback time.Duration) time.Duration {
+	ttl, err := s.Cache.TTL(ctx, key)
+	if err != nil || ttl <= 0 {
+		return fallback
+	}
+	return ttl
+}
+
+// SendVerificationMail 签发验证链接并发送邮件，调用方负责先检查发送频率
+func (s *ServiceContext) SendVerificationMail(ctx context.Context, user *rbac.User) error {
+	ttl := s.emailVerifyTTL()
+	token, err := s.signEmailVerifyToken(user, time.Now().Add(ttl))
+	if err != nil {
+		return err
+	}
+	link := token
+	if verifyURL := s.Config.Security.EmailVerification.VerifyURL; verifyURL != "" {
+		link = verifyURL + "?token=" + url.QueryEscape(token)
+	}
+	body := fmt.Sprintf("%s，您好：\n\n感谢注册，请在 %d 小时内通过以下链接（或验证令牌）验证您的邮箱，验证后即可登录：\n\n%s\n\n如果不是您本人操作，请忽略本邮件。\n",
+		user.Username, int(ttl.Hours()), link)
+	return s.Mailer.Send(ctx, &_interface.MailMessage{
+		To:      []string{user.Email},
+		Subject: fmt.Sprintf("【%s】验证邮箱", s.Config.App.Name),
+		Body:    body,
+	})
+}
+
+// RequestEmailChange 保存待验证的新邮箱并签发确认令牌，令牌只保存哈希
+// 新邮箱验证前不替换原邮箱，避免被盗用的会话直接把账号邮箱改为攻击者的邮箱后找回密码
+func (s *ServiceContext) RequestEmailChange(ctx context.Context, userID uint, email string) (string, error) {
+	if err := s.Rbac.UserService.UpdateByID(ctx, userID, map[string]interface{}{"pending_email": email}); err != nil {
+		return "", err
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	err := s.Cache.Set(ctx, fmt.Sprintf(cacheKeyEmailChange, jwt.Hash(token)), emailChange{UserID: userID, Email: email}, s.emailVerifyTTL())
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// SendEmailChangeMail 向新邮箱发送确认链接，同时通知原邮箱，账号被盗用时本人可以及时发现
+func (s *ServiceContext) SendEmailChangeMail(ctx context.Context, user *rbac.User, email, token string) error {
+	ttl := s.emailVerifyTTL()
+	link := token
+	if verifyURL := s.Config.Security.EmailVerification.VerifyURL; verifyURL != "" {
+		link = verifyURL + "?token=" + url.QueryEscape(token)
+	}
+	body := fmt.Sprintf("%s，您好：\n\n您申请将账号邮箱修改为本邮箱，请在 %d 小时内通过以下链接（或验证令牌）完成验证，验证后新邮箱生效：\n\n%s\n\n如果不是您本人操作，请忽略本邮件。\n",
+		user.Username, int(ttl.Hours()), link)
+	err := s.Mailer.Send(ctx, &_interface.MailMessage{
+		To:      []string{email},
+		Subject: fmt.Sprintf("【%s】验证新邮箱", s.Config.App.Name),
+		Body:    body,
+	})
+	if err != nil || user.Email == "" {
+		return err
+	}
+	notice := fmt.Sprintf("%s，您好：\n\n您的账号申请将邮箱修改为 %s，新邮箱验证后生效。\n\n如果不是您本人操作，说明您的账号可能已被盗用，请立即修改密码并联系管理员。\n",
+		user.Username, email)
+	return s.Mailer.Send(ctx, &_interface.MailMessage{
+		To:      []string{user.Email},
+		Subject: fmt.Sprintf("【%s】邮箱修改提醒", s.Config.App.Name),
+		Body:    notice,
+	})
+}
+
+// VerifyEmail 校验验证令牌并标记邮箱已验证，待验证的用户同时启用
+// 令牌绑定签发时的邮箱，修改邮箱后旧链接失效；重复验证同一邮箱视为成功
+// 修改邮箱的确认令牌同样由此验证，验证后替换为新邮箱
+func (s *ServiceContext) VerifyEmail(ctx context.Context, token string) (*rbac.User, error) {
+	if user, ok, err := s.confirmEmailChange(ctx, token); ok || err != nil {
+		return user, err
+	}
+	userID, emailHash, err := s.parseEmailVerifyToken(token)
+	// 未开启邮箱验证时只签发修改邮箱的确认令牌，其他令牌均无效
+	if errors.Is(err, ErrEmailVerifySecretEmpty) {
+		return nil, ErrEmailVerifyTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = s.Rbac.UserService.TenantContext(ctx, userID)
+	if err != nil {
+		return nil, ErrEmailVerifyTokenInvalid
+	}
+	user, err := s.Rbac.UserService.FindByID(ctx, userID)
+	if err != nil || user.Email == "" || !hmac.Equal([]byte(emailHash), []byte(verifyEmailHash(user.Email))) {
+		return nil, ErrEmailVerifyTokenInvalid
+	}
+	if _, err = s.Rbac.UserService.MarkEmailVerified(ctx, user.ID, user.Email); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// confirmEmailChange 修改邮箱的确认令牌，令牌只能使用一次；不是确认令牌时返回 ok=false
+func (s *ServiceContext) confirmEmailChange(ctx context.Context, token string) (*rbac.User, bool, error) {
+	key := fmt.Sprintf(cacheKeyEmailChange, jwt.Hash(token))
+	var change emailChange
+	if err := s.Cache.Get(ctx, key, &change); err != nil {
+		if errors.Is(err, _interface.ErrKeyNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	if err := s.Cache.Delete(ctx, key); err != nil {
+		return nil, true, err
+	}
+	ctx, err := s.Rbac.UserService.TenantContext(ctx, change.UserID)
+	if err != nil {
+		return nil, true, ErrEmailVerifyTokenInvalid
+	}
+	// 之后又申请了其他邮箱时，旧的确认链接失效
+	applied, err := s.Rbac.UserService.ApplyPendingEmail(ctx, change.UserID, change.Email)
+	if err != nil {
+		return nil, true, err
+	}
+	if !applied {
+		return nil, true, ErrEmailVerifyTokenInvalid
+	}
+	user, err := s.Rbac.UserService.FindByID(ctx, change.UserID)
+	return user, true, err
+}
+
+// signEmailVerifyToken 验证令牌：base64url(uid.过期时间.邮箱摘要).base64url(HMAC-SHA256)
+func (s *ServiceContext) signEmailVerifyToken(user *rbac.User, expiresAt time.Time) (string, error) {
+	secret, err := s.emailVerifySecret()
+	if err != nil {
+		return "", err
+	}
+	payload := fmt.Sprintf("%d.%d.%s", user.ID, expiresAt.Unix(), verifyEmailHash(user.Email))
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// parseEmailVerifyToken 校验签名和有效期，返回用户 ID 和邮箱摘要
+func (s *ServiceContext) parseEmailVerifyToken(token string) (uint, string, error) {
+	secret, err := s.emailVerifySecret()
+	if err != nil {
+		return 0, "", err
+	}
+	encoded, encodedSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return 0, "", ErrEmailVerifyTokenInvalid
+	}
+	payload, err1 := base64.RawURLEncoding.DecodeString(encoded)
+	sig, err2 := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err1 != nil || err2 != nil {
+		return 0, "", ErrEmailVerifyTokenInvalid
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return 0, "", ErrEmailVerifyTokenInvalid
+	}
+	parts := strings.Split(string(payload), ".")
+	if len(parts) != 3 {
+		return 0, "", ErrEmailVerifyTokenInvalid
+	}
+	userID, err1 := strconv.ParseUint(parts[0], 10, 64)
+	exp, err2 := strconv.ParseInt(parts[1], 10, 64)
+	if err1 != nil || err2 != nil || time.Now().Unix() > exp {
+		return 0, "", ErrEmailVerifyTokenInvalid
+	}
+	return uint(userID), parts[2], nil
+}
+
+// verifyEmailHash 令牌中只保存邮箱摘要，不暴露邮箱地址
+func verifyEmailHash(email string) string {
+	return jwt.Hash(strings.ToLower(email))[:16]
+}
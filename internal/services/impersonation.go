@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gin-admin/internal/model/rbac"
+	"gin-admin/pkg/components/jwt"
+	"gin-admin/pkg/consts"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/13 上午10:30
+* @Package: 模拟登录（管理员以其他用户身份登录排查问题）
+ */
+
+var (
+	ErrImpersonateSelf       = errors.New("不能模拟登录自己")
+	ErrImpersonationNested   = errors.New("模拟登录状态下不允许该操作")
+	ErrImpersonationEscalate = errors.New("不能模拟拥有自己所没有权限的用户")
+)
+
+// ImpersonationRequest 发起模拟登录的上下文
+type ImpersonationRequest struct {
+	Impersonator *rbac.User // 发起模拟的管理员
+	Target       *rbac.User // 被模拟的用户，需预加载 Roles
+	IP           string
+	UserAgent    string
+}
+
+// impersonationTTL 模拟会话的绝对有效期
+func (s *ServiceContext) impersonationTTL() time.Duration {
+	if ttl := s.Config.Security.Impersonation.TTL; ttl > 0 {
+		return ttl
+	}
+	return 30 * time.Minute
+}
+
+// Impersonate 以目标用户身份签发短期令牌，并记录安全事件，返回令牌和模拟会话的结束时间
+// 目标用户的权限必须是管理员权限的子集，避免通过模拟登录提权
+func (s *ServiceContext) Impersonate(ctx context.Context, req ImpersonationRequest) (*jwt.TokenPair, time.Time, error) {
+	if req.Impersonator.ID == req.Target.ID {
+		return nil, time.Time{}, ErrImpersonateSelf
+	}
+	escalate, err := s.grantsExceed(ctx, req.Target.ID, req.Impersonator.ID)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if escalate {
+		return nil, time.Time{}, ErrImpersonationEscalate
+	}
+	roles := make([]string, 0, len(req.Target.Roles))
+	for _, role := range req.Target.Roles {
+		roles = append(roles, role.Name)
+	}
+	ttl := s.impersonationTTL()
+	expiresAt := time.Now().Add(ttl)
+	tokenPair, err := s.Jwt.GenerateTokenPair(ctx, req.Target.ID, req.Target.Username, req.Target.Email,
+		jwt.WithImpersonator(req.Impersonator.ID),
+		jwt.WithSessionLifetime(ttl),
+		jwt.WithClientInfo(req.IP, req.UserAgent),
+		jwt.WithRoles(roles...),
+		jwt.WithTenant(req.Target.TenantID),
+	)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	logrus.Warnf("impersonation started: impersonator=%d(%s) user=%d(%s) ip=%s",
+		req.Impersonator.ID, req.Impersonator.Username, req.Target.ID, req.Target.Username, req.IP)
+	s.saveSecurityEvent(ctx, &rbac.SecurityEvent{
+		Type:           consts.SecurityEventImpersonationStart,
+		UserID:         req.Target.ID,
+		Username:       req.Target.Username,
+		IP:             req.IP,
+		UserAgent:      req.UserAgent,
+		ImpersonatorID: req.Impersonator.ID,
+		Detail:         fmt.Sprintf("impersonator=%s ttl=%s", req.Impersonator.Username, ttl),
+	})
+	return tokenPair, expiresAt, nil
+}
+
+// grantsExceed userID 是否拥有 otherID 所没有的资源权限
+func (s *ServiceContext) grantsExceed(ctx context.Context, userID, otherID uint) (bool, error) {
+	resources, err := s.Rbac.ResourceService.GetUserResources(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	others, err := s.Rbac.ResourceService.GetUserResources(ctx, otherID)
+	if err != nil {
+		return false, err
+	}
+	granted := make(map[uint]struct{}, len(others))
+	for _, res := range others {
+		granted[res.ID] = struct{}{}
+	}
+	for _, res := range resources {
+		if _, ok := granted[res.ID]; !ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RecordImpersonatedRequest 记录模拟登录期间的请求，用于事后审计
+func (s *ServiceContext) RecordImpersonatedRequest(ctx context.Context, event *rbac.SecurityEvent) {
+	event.Type = consts.SecurityEventImpersonatedAccess
+	s.saveSecurityEvent(ctx, event)
+}
+
+// saveSecurityEvent 持久化安全事件，失败只记录日志，不影响请求
+func (s *ServiceContext) saveSecurityEvent(ctx context.Context, event *rbac.SecurityEvent) {
+	if err := s.Rbac.SecurityEventService.Create(ctx, event); err != nil {
+		logrus.Errorf("failed to save security event :%s", err.Error())
+	}
+}
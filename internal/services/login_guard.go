@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"gin-admin/internal/config"
+	_interface "gin-admin/pkg/interface"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/11 上午10:12
+* @Package: 登录防暴力破解 - 按账号、IP 统计失败次数
+ */
+
+const (
+	cacheKeyLoginFailAccount = "login:fail:account:%s" // 账号连续失败次数
+	cacheKeyLoginFailIP      = "login:fail:ip:%s"      // IP 在统计窗口内的失败次数
+	cacheKeyLoginBlockIP     = "login:block:ip:%s"     // IP 被拒绝登录的标记，TTL 即剩余时长
+	cacheKeyLoginDelay       = "login:delay:%s"        // 账号下次允许尝试前的等待标记
+)
+
+// LoginGuard 登录失败限制
+// 账号连续失败达到 DelayAfter 次后每次失败都需要等待一段时间（逐次翻倍），
+// 达到 MaxAccountFailures 次后由调用方临时锁定账号；同一 IP 失败过多时直接拒绝登录
+type LoginGuard struct {
+	cfg   config.LoginGuardConfig
+	cache _interface.ICache
+}
+
+func NewLoginGuard(cfg config.LoginGuardConfig, cache _interface.ICache) *LoginGuard {
+	if cfg.MaxAccountFailures <= 0 {
+		cfg.MaxAccountFailures = 5
+	}
+	if cfg.MaxIPFailures <= 0 {
+		cfg.MaxIPFailures = 20
+	}
+	if cfg.FailureWindow <= 0 {
+		cfg.FailureWindow = 15 * time.Minute
+	}
+	if cfg.LockDuration <= 0 {
+		cfg.LockDuration = 30 * time.Minute
+	}
+	if cfg.DelayAfter <= 0 {
+		cfg.DelayAfter = 3
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = time.Second
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = 30 * time.Second
+	}
+	return &LoginGuard{cfg: cfg, cache: cache}
+}
+
+// LockDuration 账号临时锁定时长
+func (g *LoginGuard) LockDuration() time.Duration {
+	return g.cfg.LockDuration
+}
+
+// Allow 返回还需等待的时长，为 0 表示允许本次登录尝试
+func (g *LoginGuard) Allow(ctx context.Context, account, ip string) time.Duration {
+	wait := g.ttl(ctx, fmt.Sprintf(cacheKeyLoginDelay, account))
+	if ipWait := g.ttl(ctx, fmt.Sprintf(cacheKeyLoginBlockIP, ip)); ipWait > wait {
+		wait = ipWait
+	}
+	return wait
+}
+
+// Fail 记录一次失败，返回账号连续失败次数；lock 为 true 时调用方应锁定账号，计数随之清零
+func (g *LoginGuard) Fail(ctx context.Context, account, ip string) (failures int64, lock bool) {
+	if n := g.incr(ctx, fmt.Sprintf(cacheKeyLoginFailIP, ip)); n >= int64(g.cfg.MaxIPFailures) {
+		block := g.ttl(ctx, fmt.Sprintf(cacheKeyLoginFailIP, ip))
+		if block <= 0 {
+			block = g.cfg.FailureWindow
+		}
+		if err := g.cache.Set(ctx, fmt.Sprintf(cacheKeyLoginBlockIP, ip), n, block); err != nil {
+			logrus.Errorf("failed to block login ip %s :%s", ip, err.Error())
+		}
+	}
+
+	failures = g.incr(ctx, fmt.Sprintf(cacheKeyLoginFailAccount, account))
+	if failures >= int64(g.cfg.MaxAccountFailures) {
+		g.Reset(ctx, account)
+		return failures, true
+	}
+	if failures >= int64(g.cfg.DelayAfter) {
+		delay := g.cfg.BaseDelay << (failures - int64(g.cfg.DelayAfter))
+		if delay <= 0 || delay > g.cfg.MaxDelay {
+			delay = g.cfg.MaxDelay
+		}
+		if err := g.cache.Set(ctx, fmt.Sprintf(cacheKeyLoginDelay, account), failures, delay); err != nil {
+			logrus.Errorf("failed to set login delay for %s :%s", account, err.Error())
+		}
+	}
+	return failures, false
+}
+
+// Reset 清除账号的失败计数和等待（登录成功、管理员解锁）
+func (g *LoginGuard) Reset(ctx context.Context, account string) {
+	err := g.cache.Delete(ctx, fmt.Sprintf(cacheKeyLoginFailAccount, account), fmt.Sprintf(cacheKeyLoginDelay, account))
+	if err != nil {
+		logrus.Errorf("failed to reset login failures for %s :%s", account, err.Error())
+	}
+}
+
+// incr 计数 +1，计数器创建时即带统计窗口的过期时间
+func (g *LoginGuard) incr(ctx context.Context, key string) int64 {
+	n, err := _interface.IncrWithTTL(ctx, g.cache, key, g.cfg.FailureWindow)
+	if err != nil {
+		logrus.Errorf("failed to count login failure %s :%s", key, err.Error())
+		return 0
+	}
+	return n
+}
+
+// ttl key 不存在或没有过期时间时返回 0
+func (g *LoginGuard) ttl(ctx context.Context, key string) time.Duration {
+	d, err := g.cache.TTL(ctx, key)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
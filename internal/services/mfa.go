@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gin-admin/internal/config"
+	"gin-admin/pkg/components/jwt"
+	_interface "gin-admin/pkg/interface"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/11 下午2:30
+* @Package: 两步验证登录凭证 - 密码校验通过后、完成两步验证前的短期凭证
+ */
+
+const (
+	cacheKeyMFAChallenge         = "mfa:challenge:%s"          // 凭证内容，key 为凭证的哈希
+	cacheKeyMFAChallengeAttempts = "mfa:challenge:%s:attempts" // 验证码错误次数
+)
+
+var ErrMFAChallengeInvalid = errors.New("两步验证已失效，请重新登录")
+
+// MFAChallenge 待完成两步验证的登录
+type MFAChallenge struct {
+	UserID uint `json:"user_id"`
+	// 角色要求开启两步验证但用户尚未绑定，需要先绑定验证器
+	Setup bool `json:"setup"`
+}
+
+// MFAChallengeStore 两步验证登录凭证存储
+type MFAChallengeStore struct {
+	cfg   config.MFAConfig
+	cache _interface.ICache
+}
+
+func NewMFAChallengeStore(cfg config.MFAConfig, cache _interface.ICache) *MFAChallengeStore {
+	if cfg.ChallengeTTL <= 0 {
+		cfg.ChallengeTTL = 5 * time.Minute
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.RecoveryCodes <= 0 {
+		cfg.RecoveryCodes = 10
+	}
+	return &MFAChallengeStore{cfg: cfg, cache: cache}
+}
+
+// TTL 凭证有效期
+func (m *MFAChallengeStore) TTL() time.Duration {
+	return m.cfg.ChallengeTTL
+}
+
+// RecoveryCodes 每次生成的恢复码数量
+func (m *MFAChallengeStore) RecoveryCodes() int {
+	return m.cfg.RecoveryCodes
+}
+
+// Issuer 验证器中显示的发行方，未配置时使用 fallback
+func (m *MFAChallengeStore) Issuer(fallback string) string {
+	if m.cfg.Issuer != "" {
+		return m.cfg.Issuer
+	}
+	return fallback
+}
+
+// Create 签发凭证，缓存中只保存凭证的哈希
+func (m *MFAChallengeStore) Create(ctx context.Context, ch MFAChallenge) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	if err := m.cache.Set(ctx, m.key(token), ch, m.cfg.ChallengeTTL); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Get 获取凭证，不存在或已过期时返回 ErrMFAChallengeInvalid
+func (m *MFAChallengeStore) Get(ctx context.Context, token string) (*MFAChallenge, error) {
+	var ch MFAChallenge
+	if err := m.cache.Get(ctx, m.key(token), &ch); err != nil {
+		if errors.Is(err, _interface.ErrKeyNotFound) {
+			return nil, ErrMFAChallengeInvalid
+		}
+		return nil, err
+	}
+	return &ch, nil
+}
+
+// Fail 记录一次验证码错误，次数用尽时凭证作废并返回 true
+func (m *MFAChallengeStore) Fail(ctx context.Context, token string) bool {
+	key := fmt.Sprintf(cacheKeyMFAChallengeAttempts, jwt.Hash(token))
+	n, err := _interface.IncrWithTTL(ctx, m.cache, key, m.cfg.ChallengeTTL)
+	if err != nil {
+		logrus.Errorf("failed to count mfa attempts :%s", err.Error())
+		return false
+	}
+	if n >= int64(m.cfg.MaxAttempts) {
+		m.Delete(ctx, token)
+		return true
+	}
+	return false
+}
+
+// Delete 作废凭证（验证通过或失败次数用尽）
+func (m *MFAChallengeStore) Delete(ctx context.Context, token string) {
+	hash := jwt.Hash(token)
+	err := m.cache.Delete(ctx, fmt.Sprintf(cacheKeyMFAChallenge, hash), fmt.Sprintf(cacheKeyMFAChallengeAttempts, hash))
+	if err != nil {
+		logrus.Errorf("failed to delete mfa challenge :%s", err.Error())
+	}
+}
+
+func (m *MFAChallengeStore) key(token string) string {
+	return fmt.Sprintf(cacheKeyMFAChallenge, jwt.Hash(token))
+}
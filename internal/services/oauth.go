@@ -0,0 +1,286 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gin-admin/internal/config"
+	"gin-admin/internal/model/rbac"
+	"gin-admin/pkg/components/oidc"
+	"gin-admin/pkg/components/tenant"
+	"gin-admin/pkg/consts"
+	_interface "gin-admin/pkg/interface"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/12 下午3:10
+* @Package: 第三方登录（OIDC / OAuth2 授权码 + PKCE）
+ */
+
+const cacheKeyOAuthState = "oauth:state:%s" // 跳转到提供方前保存的 state，回调时一次性取出
+
+var (
+	ErrOAuthProviderNotFound = errors.New("不支持的登录方式")
+	ErrOAuthStateInvalid     = errors.New("登录请求已失效，请重新登录")
+	ErrOAuthEmailRequired    = errors.New("第三方账号未提供邮箱，无法登录")
+	ErrOAuthEmailTaken       = errors.New("该邮箱已被其他账号使用，请联系管理员")
+)
+
+// usernameInvalidChars 自动生成用户名时去掉的字符
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// oauthState 一次登录请求的 state 对应的校验数据
+type oauthState struct {
+	Provider string `json:"provider"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	// Tenant 发起登录的租户，回调请求由提供方重定向而来，不携带租户请求头
+	Tenant *uint `json:"tenant,omitempty"`
+}
+
+// OAuthProviders 已配置的第三方登录提供方
+type OAuthProviders struct {
+	providers map[string]*oidc.Provider
+	order     []string
+	stateTTL  time.Duration
+	cache     _interface.ICache
+}
+
+func NewOAuthProviders(cfg *config.SSOConfig, cache _interface.ICache, opts ...oidc.Option) *OAuthProviders {
+	o := &OAuthProviders{providers: map[string]*oidc.Provider{}, stateTTL: 10 * time.Minute, cache: cache}
+	if cfg == nil {
+		return o
+	}
+	if cfg.StateTTL > 0 {
+		o.stateTTL = cfg.StateTTL
+	}
+	for _, pc := range cfg.Providers {
+		o.providers[pc.Name] = oidc.NewProvider(pc, opts...)
+		o.order = append(o.order, pc.Name)
+	}
+	return o
+}
+
+// List 按配置顺序返回所有提供方
+func (o *OAuthProviders) List() []oidc.ProviderConfig {
+	list := make([]oidc.ProviderConfig, 0, len(o.order))
+	for _, name := range o.order {
+		list = append(list, o.providers[name].Config())
+	}
+	return list
+}
+
+// Begin 生成 state、nonce 和 PKCE verifier 并返回提供方的授权地址和 state
+// 调用方需将 state 写入发起登录的浏览器（Cookie），回调时比对，防止登录 CSRF
+func (o *OAuthProviders) Begin(ctx context.Context, name string) (authURL, state string, err error) {
+	p, ok := o.providers[name]
+	if !ok {
+		return "", "", ErrOAuthProviderNotFound
+	}
+	var values [3]string
+	for i := range values {
+		v, err := oidc.RandomString()
+		if err != nil {
+			return "", "", err
+		}
+		values[i] = v
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+	authURL, err = p.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", "", err
+	}
+	saved := oauthState{Provider: name, Nonce: nonce, Verifier: verifier}
+	if id, ok := tenant.FromContext(ctx); ok {
+		saved.Tenant = &id
+	}
+	err = o.cache.Set(ctx, fmt.Sprintf(cacheKeyOAuthState, state), saved, o.stateTTL)
+	if err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// StateTTL 登录请求的有效期
+func (o *OAuthProviders) StateTTL() time.Duration {
+	return o.stateTTL
+}
+
+// Finish 校验 state 后用授权码换取第三方账号信息，state 只能使用一次
+// 返回的上下文恢复了发起登录时的租户，后续查找或创建本地用户应使用该上下文
+func (o *OAuthProviders) Finish(ctx context.Context, name, code, state string) (context.Context, *oidc.Identity, error) {
+	p, ok := o.providers[name]
+	if !ok {
+		return ctx, nil, ErrOAuthProviderNotFound
+	}
+	key := fmt.Sprintf(cacheKeyOAuthState, state)
+	var saved oauthState
+	if err := o.cache.Get(ctx, key, &saved); err != nil {
+		if errors.Is(err, _interface.ErrKeyNotFound) {
+			return ctx, nil, ErrOAuthStateInvalid
+		}
+		return ctx, nil, err
+	}
+	if err := o.cache.Delete(ctx, key); err != nil {
+		return ctx, nil, err
+	}
+	if saved.Provider != name {
+		return ctx, nil, ErrOAuthStateInvalid
+	}
+	if saved.Tenant != nil {
+		ctx = tenant.WithID(ctx, *saved.Tenant)
+	}
+	token, err := p.Exchange(ctx, code, saved.Verifier)
+	if err != nil {
+		return ctx, nil, err
+	}
+	identity, err := p.Identity(ctx, token, saved.Nonce)
+	return ctx, identity, err
+}
+
+// providerConfig 提供方配置
+func (o *OAuthProviders) providerConfig(name string) (oidc.ProviderConfig, bool) {
+	p, ok := o.providers[name]
+	if !ok {
+		return oidc.ProviderConfig{}, false
+	}
+	return p.Config(), true
+}
+
+// OAuthLoginUser 查找第三方账号关联的用户，首次登录时按配置关联同邮箱用户或自动创建用户
+func (s *ServiceContext) OAuthLoginUser(ctx context.Context, provider string, identity *oidc.Identity) (*rbac.User, error) {
+	cfg, ok := s.OAuth.providerConfig(provider)
+	if !ok {
+		return nil, ErrOAuthProviderNotFound
+	}
+	link, err := s.Rbac.UserIdentityService.FindBySubject(ctx, provider, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if link != nil {
+		if err = s.Rbac.UserIdentityService.TouchLogin(ctx, link.ID, identity.Email); err != nil {
+			logrus.Errorf("failed to update identity %d :%s", link.ID, err.Error())
+		}
+		return s.Rbac.UserService.FindByID(ctx, link.UserID, _interface.WithPreloads("Roles"))
+	}
+
+	if identity.Email == "" {
+		return nil, ErrOAuthEmailRequired
+	}
+	existing, err := s.Rbac.UserService.FindOne(ctx, _interface.WithConditions(map[string]interface{}{"email": identity.Email}), _interface.WithPreloads("Roles"))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if existing != nil {
+		// 只有提供方确认过邮箱时才能关联，否则任何人都能用他人的邮箱注册第三方账号接管本地账号
+		if !cfg.LinkByEmail || !identity.EmailVerified {
+			return nil, ErrOAuthEmailTaken
+		}
+		err = s.Rbac.UserIdentityService.Create(ctx, newUserIdentity(existing.ID, provider, identity))
+		if err != nil {
+			return nil, err
+		}
+		// 提供方已确认邮箱，本地待验证的账号视为验证通过
+		if existing.EmailVerifiedAt == nil {
+			if _, err = s.Rbac.UserService.MarkEmailVerified(ctx, existing.ID, existing.Email); err != nil {
+				return nil, err
+			}
+			return s.Rbac.UserService.FindByID(ctx, existing.ID, _interface.WithPreloads("Roles"))
+		}
+		return existing, nil
+	}
+	return s.createOAuthUser(ctx, cfg, identity)
+}
+
+// createOAuthUser 首次登录自动创建用户，随机密码（用户可通过找回密码设置）
+func (s *ServiceContext) createOAuthUser(ctx context.Context, cfg oidc.ProviderConfig, identity *oidc.Identity) (*rbac.User, error) {
+	password, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+	user := &rbac.User{
+		Email:  identity.Email,
+		Status: consts.UserStatusActive,
+	}
+	if identity.EmailVerified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	user.SetPassword(password)
+	if cfg.DefaultRole != "" {
+		role, err := s.Rbac.RoleService.FindOne(ctx, _interface.WithConditions(map[string]interface{}{"name": cfg.DefaultRole}))
+		if err == nil {
+			user.Roles = []rbac.Role{*role}
+		} else {
+			logrus.Errorf("default role %s of oauth provider %s not found :%s", cfg.DefaultRole, cfg.Name, err.Error())
+		}
+	}
+	err = s.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if user.Username, err = uniqueUsername(tx, identity); err != nil {
+			return err
+		}
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return tx.Create(newUserIdentity(user.ID, cfg.Name, identity)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.Rbac.UserService.ClearCache(ctx)
+	return user, nil
+}
+
+// uniqueUsername 依次尝试 preferred_username、邮箱前缀，重名时追加随机数字
+func uniqueUsername(tx *gorm.DB, identity *oidc.Identity) (string, error) {
+	base := usernameInvalidChars.ReplaceAllString(identity.PreferredUsername, "")
+	if base == "" {
+		base = usernameInvalidChars.ReplaceAllString(strings.Split(identity.Email, "@")[0], "")
+	}
+	if base == "" {
+		base = "user"
+	}
+	if len(base) > 40 {
+		base = base[:40]
+	}
+	candidate := base
+	for i := 0; i < 5; i++ {
+		var count int64
+		if err := tx.Model(&rbac.User{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+		if err != nil {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s_%06d", base, n.Int64())
+	}
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base + "_" + hex.EncodeToString(buf), nil
+}
+
+func newUserIdentity(userID uint, provider string, identity *oidc.Identity) *rbac.UserIdentity {
+	return &rbac.UserIdentity{
+		UserID:      userID,
+		Provider:    provider,
+		Subject:     identity.Subject,
+		Email:       identity.Email,
+		LastLoginAt: time.Now(),
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"gin-admin/internal/model/rbac"
 	types "gin-admin/internal/types/rbac"
+	"gin-admin/pkg/consts"
 )
 
 /*
@@ -18,6 +19,11 @@ type OptionField string
 const (
 	FieldRole OptionField = "role"
 	FieldUser OptionField = "user"
+	FieldDept OptionField = "dept"
+	// 角色的数据权限范围
+	FieldDataScope OptionField = "data_scope"
+	// 菜单状态
+	FieldMenuStatus OptionField = "menu_status"
 )
 
 // TODO 增加缓存
@@ -25,7 +31,7 @@ const (
 var OptionGenerators = map[OptionField]func(ctx context.Context) ([]types.Option, error){
 	FieldRole: func(ctx context.Context) ([]types.Option, error) {
 		roles := []rbac.Role{}
-		tx := SvcContext.Db
+		tx := SvcContext.Db.WithContext(ctx)
 		if err := tx.Find(&roles).Error; err != nil {
 			return nil, err
 		}
@@ -40,7 +46,7 @@ var OptionGenerators = map[OptionField]func(ctx context.Context) ([]types.Option
 	},
 	FieldUser: func(ctx context.Context) ([]types.Option, error) {
 		roles := []rbac.User{}
-		tx := SvcContext.Db
+		tx := SvcContext.Db.WithContext(ctx)
 		if err := tx.Find(&roles).Error; err != nil {
 			return nil, err
 		}
@@ -53,4 +59,41 @@ var OptionGenerators = map[OptionField]func(ctx context.Context) ([]types.Option
 		}
 		return opts, nil
 	},
+	FieldDataScope: func(ctx context.Context) ([]types.Option, error) {
+		scopes := consts.AllDataScope()
+		opts := make([]types.Option, len(scopes))
+		for i, scope := range scopes {
+			opts[i] = types.Option{
+				Label: scope.String(),
+				Value: scope,
+			}
+		}
+		return opts, nil
+	},
+	FieldMenuStatus: func(ctx context.Context) ([]types.Option, error) {
+		statuses := consts.AllMenuStatus()
+		opts := make([]types.Option, len(statuses))
+		for i, status := range statuses {
+			opts[i] = types.Option{
+				Label: status.String(),
+				Value: status,
+			}
+		}
+		return opts, nil
+	},
+	FieldDept: func(ctx context.Context) ([]types.Option, error) {
+		depts := []rbac.Department{}
+		tx := SvcContext.Db.WithContext(ctx)
+		if err := tx.Order("sort ASC, id ASC").Find(&depts).Error; err != nil {
+			return nil, err
+		}
+		opts := make([]types.Option, len(depts))
+		for i, d := range depts {
+			opts[i] = types.Option{
+				Label: d.Name,
+				Value: d.ID,
+			}
+		}
+		return opts, nil
+	},
 }
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	rbac2 "gin-admin/internal/services/rbac"
+	"gin-admin/pkg/components/jwt"
+	_interface "gin-admin/pkg/interface"
+	"gorm.io/gorm"
+	"time"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/13 下午4:30
+* @Package: 密码策略：历史密码、密码有效期
+ */
+
+const (
+	cacheKeyPasswordChange = "password:change:%s" // 密码过期后登录拿到的改密凭证
+	passwordChangeTTL      = 10 * time.Minute
+)
+
+var ErrPasswordChangeTokenInvalid = errors.New("改密凭证已失效，请重新登录")
+
+// ChangePassword 按密码策略设置新密码
+// 即使未开启历史密码限制，新密码也不能与当前密码相同，否则密码有效期形同虚设
+func (s *ServiceContext) ChangePassword(ctx context.Context, userID uint, plain string) error {
+	keep, err := s.passwordHistoryKeep(plain)
+	if err != nil {
+		return err
+	}
+	return s.Rbac.UserService.SetPassword(ctx, userID, plain, keep)
+}
+
+// ChangePasswordTx 与 ChangePassword 相同，在调用方的事务中设置新密码
+func (s *ServiceContext) ChangePasswordTx(tx *gorm.DB, userID uint, plain string) error {
+	keep, err := s.passwordHistoryKeep(plain)
+	if err != nil {
+		return err
+	}
+	return rbac2.SetPasswordTx(tx, userID, plain, keep)
+}
+
+// passwordHistoryKeep 校验密码策略，返回需要比对的历史密码个数
+func (s *ServiceContext) passwordHistoryKeep(plain string) (int, error) {
+	if err := s.PasswordPolicy.Validate(plain); err != nil {
+		return 0, err
+	}
+	keep := s.PasswordPolicy.Config().HistorySize
+	if keep < 1 {
+		keep = 1
+	}
+	return keep, nil
+}
+
+// IssuePasswordChangeToken 密码已过期时代替令牌返回的改密凭证，只保存哈希
+func (s *ServiceContext) IssuePasswordChangeToken(ctx context.Context, userID uint) (string, time.Duration, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", 0, err
+	}
+	token := hex.EncodeToString(buf)
+	if err := s.Cache.Set(ctx, fmt.Sprintf(cacheKeyPasswordChange, jwt.Hash(token)), userID, passwordChangeTTL); err != nil {
+		return "", 0, err
+	}
+	return token, passwordChangeTTL, nil
+}
+
+// PasswordChangeTokenUser 改密凭证对应的用户
+func (s *ServiceContext) PasswordChangeTokenUser(ctx context.Context, token string) (uint, error) {
+	var userID uint
+	err := s.Cache.Get(ctx, fmt.Sprintf(cacheKeyPasswordChange, jwt.Hash(token)), &userID)
+	if errors.Is(err, _interface.ErrKeyNotFound) {
+		return 0, ErrPasswordChangeTokenInvalid
+	}
+	return userID, err
+}
+
+// RevokePasswordChangeToken 改密成功后作废凭证
+func (s *ServiceContext) RevokePasswordChangeToken(ctx context.Context, token string) error {
+	return s.Cache.Delete(ctx, fmt.Sprintf(cacheKeyPasswordChange, jwt.Hash(token)))
+}
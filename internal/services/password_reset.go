@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"gin-admin/internal/model/rbac"
+	_interface "gin-admin/pkg/interface"
+	"net/url"
+	"time"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/12 上午10:20
+* @Package: 找回密码邮件
+ */
+
+const cacheKeyPasswordResetCooldown = "password:reset:cooldown:%d" // 发送重置邮件的冷却期
+
+// SendPasswordResetMail 签发重置令牌并发送邮件，冷却期内重复申请时直接忽略
+func (s *ServiceContext) SendPasswordResetMail(ctx context.Context, user *rbac.User, ip string) error {
+	cfg := s.Config.Security.PasswordReset
+	ttl, cooldown := cfg.TokenTTL, cfg.Cooldown
+	if ttl <= 0 {
+		ttl = 30 * time.Minute
+	}
+	if cooldown <= 0 {
+		cooldown = time.Minute
+	}
+	cooldownKey := fmt.Sprintf(cacheKeyPasswordResetCooldown, user.ID)
+	if exist, err := s.Cache.Exists(ctx, cooldownKey); err != nil || exist {
+		return err
+	}
+	if err := s.Cache.Set(ctx, cooldownKey, 1, cooldown); err != nil {
+		return err
+	}
+
+	token, err := s.Rbac.PasswordResetService.Issue(ctx, user.ID, ttl, ip)
+	if err != nil {
+		return err
+	}
+	link := token
+	if cfg.ResetURL != "" {
+		link = cfg.ResetURL + "?token=" + url.QueryEscape(token)
+	}
+	body := fmt.Sprintf("%s，您好：\n\n我们收到了重置您账号密码的申请，请在 %d 分钟内通过以下链接（或重置令牌）设置新密码：\n\n%s\n\n如果不是您本人操作，请忽略本邮件，您的密码不会被修改。\n",
+		user.Username, int(ttl.Minutes()), link)
+	return s.Mailer.Send(ctx, &_interface.MailMessage{
+		To:      []string{user.Email},
+		Subject: fmt.Sprintf("【%s】重置密码", s.Config.App.Name),
+		Body:    body,
+	})
+}
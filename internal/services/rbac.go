@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"gin-admin/internal/model/rbac"
+	"gin-admin/pkg/components/tenant"
 	"gin-admin/pkg/consts"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
@@ -55,7 +56,8 @@ func (s *rbacService) InitializeRBAC(routes []ProtectedRoute, config *RBACInitCo
 		logrus.Info("RBAC 自动初始化已禁用")
 		return nil
 	}
-	db := SvcContext.Db
+	// 权限分组和资源是全局的，超级管理员角色和用户创建在平台租户下
+	db := SvcContext.Db.WithContext(tenant.WithID(context.Background(), tenant.PlatformID))
 
 	logrus.Info("开始初始化 RBAC 权限系统...")
 	err := db.Transaction(func(tx *gorm.DB) error {
@@ -74,20 +76,9 @@ func (s *rbacService) InitializeRBAC(routes []ProtectedRoute, config *RBACInitCo
 			return fmt.Errorf("绑定资源到权限分组失败: %w", err)
 		}
 
-		// 4. 创建超级管理员角色
-		adminRole, err := s.initializeAdminRole(tx, config)
-		if err != nil {
-			return fmt.Errorf("初始化超级管理员角色失败: %w", err)
-		}
-
-		// 5. 绑定所有资源到超级管理员角色（实际授权）
-		if err := s.bindAllResourcesToRole(tx, adminRole.ID); err != nil {
-			return fmt.Errorf("绑定资源到角色失败: %w", err)
-		}
-
-		// 6. 创建默认管理员用户
-		if err := s.initializeAdminUser(tx, adminRole.ID, config); err != nil {
-			return fmt.Errorf("初始化管理员用户失败: %w", err)
+		// 4~6. 创建超级管理员角色、绑定所有资源并创建默认管理员用户
+		if err := s.InitializeAdmin(tx, config); err != nil {
+			return err
 		}
 		_ = SvcContext.CacheService.ClearAllPermissions(context.TODO())
 		return nil
@@ -343,6 +334,23 @@ func (s *rbacService) autoBindResourcesToPermissions(tx *gorm.DB, routes []Prote
 	return nil
 }
 
+// InitializeAdmin 在 tx 所在的租户内创建超级管理员角色（绑定所有资源）和管理员用户，已存在时补齐
+// 系统初始化时用于平台租户，新建租户时用于该租户
+func (s *rbacService) InitializeAdmin(tx *gorm.DB, config *RBACInitConfig) error {
+	adminRole, err := s.initializeAdminRole(tx, config)
+	if err != nil {
+		return fmt.Errorf("初始化超级管理员角色失败: %w", err)
+	}
+	// 实际授权
+	if err = s.bindAllResourcesToRole(tx, adminRole.ID); err != nil {
+		return fmt.Errorf("绑定资源到角色失败: %w", err)
+	}
+	if err = s.initializeAdminUser(tx, adminRole.ID, config); err != nil {
+		return fmt.Errorf("初始化管理员用户失败: %w", err)
+	}
+	return nil
+}
+
 // initializeAdminRole 初始化超级管理员角色
 func (s *rbacService) initializeAdminRole(tx *gorm.DB, config *RBACInitConfig) (*rbac.Role, error) {
 	logrus.Info("  - 初始化超级管理员角色...")
@@ -414,11 +422,11 @@ func (s *rbacService) initializeAdminUser(tx *gorm.DB, roleID uint, config *RBAC
 		// 创建管理员用户
 		user = rbac.User{
 			Username: config.AdminUsername,
-			Password: config.AdminPassword,
 			Email:    config.AdminEmail,
 			Status:   consts.UserStatusActive,
 			BuiltIn:  true,
 		}
+		user.SetPassword(config.AdminPassword)
 		if err := tx.Create(&user).Error; err != nil {
 			return err
 		}
@@ -0,0 +1,119 @@
+package rbac
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"gin-admin/internal/model/rbac"
+	"gin-admin/pkg/components/jwt"
+	"gin-admin/pkg/components/tenant"
+	_interface "gin-admin/pkg/interface"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/13 上午10:00
+* @Package: API Key
+ */
+
+const (
+	// APIKeyPrefix API Key 明文前缀，便于在请求头和日志中识别
+	APIKeyPrefix = "ga_"
+	// apiKeyTouchInterval 最近使用时间的更新间隔，避免每个请求都写库
+	apiKeyTouchInterval = time.Minute
+)
+
+var ErrAPIKeyInvalid = errors.New("API Key 无效或已过期")
+
+// APIKeyService API Key 服务
+// 认证相关查询直接读写数据库，不走 Service[T] 的查询缓存
+type APIKeyService struct {
+	_interface.Service[rbac.APIKey]
+}
+
+func NewAPIKeyService(db *gorm.DB, cache _interface.ICache) *APIKeyService {
+	return &APIKeyService{
+		Service: *_interface.NewService[rbac.APIKey](db, cache),
+	}
+}
+
+// IsAPIKey 是否为 API Key 格式
+func IsAPIKey(key string) bool {
+	return strings.HasPrefix(key, APIKeyPrefix)
+}
+
+// Generate 创建 API Key，返回明文（只在此时返回一次）
+// 格式：ga_<8位十六进制标识>_<随机串>，前半部分作为 Prefix 保存用于辨认
+func (s *APIKeyService) Generate(ctx context.Context, key *rbac.APIKey) (string, error) {
+	id := make([]byte, 4)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	key.Prefix = APIKeyPrefix + hex.EncodeToString(id)
+	raw := key.Prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	key.KeyHash = jwt.Hash(raw)
+	if err := s.Create(ctx, key); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// ListByUser 用户的所有 API Key
+func (s *APIKeyService) ListByUser(ctx context.Context, userID uint) ([]rbac.APIKey, error) {
+	var keys []rbac.APIKey
+	err := s.DB.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").Find(&keys).Error
+	return keys, err
+}
+
+// Revoke 删除用户的 API Key，不存在时返回 gorm.ErrRecordNotFound
+func (s *APIKeyService) Revoke(ctx context.Context, userID, id uint) error {
+	result := s.DB.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&rbac.APIKey{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	s.ClearCache(ctx)
+	return nil
+}
+
+// Authenticate 校验 API Key 明文，成功后记录最近使用时间和IP
+func (s *APIKeyService) Authenticate(ctx context.Context, raw, ip string) (*rbac.APIKey, error) {
+	if !IsAPIKey(raw) {
+		return nil, ErrAPIKeyInvalid
+	}
+	// Key 哈希全局唯一，跨租户查找，调用方随后进入 Key 所属的租户
+	ctx = tenant.SkipScope(ctx)
+	var key rbac.APIKey
+	err := s.DB.WithContext(ctx).Where("key_hash = ?", jwt.Hash(raw)).Take(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAPIKeyInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if key.Expired(now) {
+		return nil, ErrAPIKeyInvalid
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval || key.LastUsedIP != ip {
+		err = s.DB.WithContext(ctx).Model(&rbac.APIKey{}).Where("id = ?", key.ID).
+			Updates(map[string]interface{}{"last_used_at": now, "last_used_ip": ip}).Error
+		if err != nil {
+			return nil, err
+		}
+		key.LastUsedAt, key.LastUsedIP = &now, ip
+	}
+	return &key, nil
+}
@@ -0,0 +1,113 @@
+package rbac
+
+import (
+	"context"
+	"gin-admin/internal/model/rbac"
+	"gin-admin/pkg/components/policy"
+	"gorm.io/gorm"
+	"slices"
+	"sync"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/17 下午3:00
+* @Package: 角色资源的授权条件和禁止访问
+ */
+
+// ConditionSchema 授权条件可以使用的请求属性，由权限中间件在求值时提供
+var ConditionSchema = policy.Schema{
+	"ip":                  policy.KindString, // 客户端 IP
+	"method":              policy.KindString, // 请求方法
+	"path":                policy.KindString, // 请求路径
+	"time.hour":           policy.KindNumber, // 服务器本地时间 0-23
+	"time.minute":         policy.KindNumber, // 0-59
+	"time.weekday":        policy.KindNumber, // 0 为周日，1-6 为周一至周六
+	"params.*":            policy.KindString, // 路径参数，如 params.id
+	"claims.uid":          policy.KindNumber, // 当前用户ID
+	"claims.username":     policy.KindString,
+	"claims.tenant_id":    policy.KindNumber, // 用户所属租户
+	"claims.api_key":      policy.KindBool,   // 是否通过 API Key 访问
+	"claims.impersonated": policy.KindBool,   // 是否为模拟登录
+	"user.status":         policy.KindNumber, // 用户状态，见 consts.UserStatus
+	"user.dept_id":        policy.KindNumber, // 用户所属部门
+}
+
+// conditionPrograms 已编译的条件，表达式数量有限，按原文缓存在进程内
+var conditionPrograms sync.Map
+
+// CompileCondition 校验并编译授权条件，保存授权时校验，求值时复用编译结果
+func CompileCondition(expr string) (*policy.Program, error) {
+	if prog, ok := conditionPrograms.Load(expr); ok {
+		return prog.(*policy.Program), nil
+	}
+	prog, err := policy.Compile(expr, ConditionSchema)
+	if err != nil {
+		return nil, err
+	}
+	conditionPrograms.Store(expr, prog)
+	return prog, nil
+}
+
+// SetResources 替换角色的资源授权，conditions 为资源ID到授权条件的映射，不在其中的资源无条件授权
+// 条件需要事先通过 CompileCondition 校验
+func (rs *RoleService) SetResources(ctx context.Context, role *rbac.Role, resources []rbac.Resource, conditions map[uint]string) error {
+	err := rs.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(role).Association("Resources").Replace(resources); err != nil {
+			return err
+		}
+		if err := tx.Model(&rbac.RoleResource{}).Where("role_id = ?", role.ID).Update("condition_expr", "").Error; err != nil {
+			return err
+		}
+		for resourceId, cond := range conditions {
+			if cond == "" {
+				continue
+			}
+			err := tx.Model(&rbac.RoleResource{}).
+				Where("role_id = ? AND resource_id = ?", role.ID, resourceId).
+				Update("condition_expr", cond).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return rs.ClearCache(ctx)
+}
+
+// SetDeniedResources 替换角色禁止访问的资源，内置角色不能设置
+func (rs *RoleService) SetDeniedResources(ctx context.Context, role *rbac.Role, resourceIds []uint) error {
+	if role.BuiltIn {
+		return ErrRoleBuiltInDeny
+	}
+	resourceIds = slices.Compact(slices.Sorted(slices.Values(resourceIds))) // 去重
+	var resources []rbac.Resource
+	if len(resourceIds) != 0 {
+		err := rs.DB.WithContext(ctx).Where("id IN ?", resourceIds).Find(&resources).Error
+		if err != nil {
+			return err
+		}
+		if len(resources) != len(resourceIds) {
+			return gorm.ErrRecordNotFound
+		}
+	}
+	return rs.ReplaceAssociation(ctx, role, "DeniedResources", resources)
+}
+
+// ResourceConditions 角色的条件授权，资源ID到授权条件的映射
+func (rs *RoleService) ResourceConditions(ctx context.Context, roleId uint) (map[uint]string, error) {
+	var grants []rbac.RoleResource
+	err := rs.DB.WithContext(ctx).Where("role_id = ? AND condition_expr <> ''", roleId).Find(&grants).Error
+	if err != nil {
+		return nil, err
+	}
+	conditions := make(map[uint]string, len(grants))
+	for _, g := range grants {
+		conditions[g.ResourceId] = g.Condition
+	}
+	return conditions, nil
+}
@@ -13,17 +13,37 @@ import (
  */
 
 type Context struct {
-	PermissionService *PermissionService
-	RoleService       *RoleService
-	ResourceService   *ResourceService
-	UserService       *UserService
+	PermissionService    *PermissionService
+	RoleService          *RoleService
+	ResourceService      *ResourceService
+	UserService          *UserService
+	SecurityEventService *SecurityEventService
+	MFAService           *MFAService
+	PasswordResetService *PasswordResetService
+	UserIdentityService  *UserIdentityService
+	APIKeyService        *APIKeyService
+	DeptService          *DeptService
+	TenantService        *TenantService
+	MenuService          *MenuService
 }
 
 func NewContext(db *gorm.DB, cache _interface.ICache) *Context {
+	deptService := NewDeptService(db, cache)
+	roleService := NewRoleService(db, cache)
+	// 数据权限“本部门及下级部门”按部门树展开
+	roleService.SetDeptResolver(deptService)
 	return &Context{
-		PermissionService: NewPermissionService(db, cache),
-		RoleService:       NewRoleService(db, cache),
-		ResourceService:   NewResourceService(db, cache),
-		UserService:       NewUserService(db, cache),
+		PermissionService:    NewPermissionService(db, cache),
+		RoleService:          roleService,
+		ResourceService:      NewResourceService(db, cache),
+		UserService:          NewUserService(db, cache),
+		SecurityEventService: NewSecurityEventService(db, cache),
+		MFAService:           NewMFAService(db, cache),
+		PasswordResetService: NewPasswordResetService(db, cache),
+		UserIdentityService:  NewUserIdentityService(db, cache),
+		APIKeyService:        NewAPIKeyService(db, cache),
+		DeptService:          deptService,
+		TenantService:        NewTenantService(db, cache),
+		MenuService:          NewMenuService(db, cache),
 	}
 }
@@ -0,0 +1,156 @@
+package rbac
+
+import (
+	"context"
+	"gin-admin/internal/model/rbac"
+	"gin-admin/pkg/consts"
+	"gorm.io/gorm"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/15 下午2:30
+* @Package: 数据权限（行级权限）
+ */
+
+// DeptResolver 部门层级解析，由部门模块通过 RoleService.SetDeptResolver 注入
+type DeptResolver interface {
+	// SubDeptIDs 部门及其所有下级部门
+	SubDeptIDs(ctx context.Context, deptID uint) ([]uint, error)
+}
+
+// flatDeptResolver 没有部门层级时，下级部门只包含自身
+type flatDeptResolver struct{}
+
+func (flatDeptResolver) SubDeptIDs(ctx context.Context, deptID uint) ([]uint, error) {
+	return []uint{deptID}, nil
+}
+
+// DataScope 用户的有效数据权限，多个角色（含继承的上级角色）取并集
+//
+// 新模块接入时，数据表需要记录所属部门和创建人，查询时与其他条件放在同一个 WithScopes 中：
+//
+//	dataScope, err := svcCtx.Rbac.RoleService.UserDataScope(ctx, c.GetUint("uid"))
+//	...
+//	svc.FindPage(ctx, _interface.WithPagination(page, pageSize),
+//		_interface.WithScopes(filter, dataScope.Scope("dept_id", "created_by")))
+type DataScope struct {
+	UserID  uint   // 当前用户
+	All     bool   // 全部数据，不追加条件
+	Self    bool   // 可查看本人的数据
+	DeptIDs []uint // 可查看的部门
+}
+
+// Scope 追加数据权限条件的 GORM Scope，deptColumn 为所属部门列，userColumn 为所属用户（创建人）列
+// 部门条件与本人条件之间为 OR，整体与其他查询条件为 AND
+func (d *DataScope) Scope(deptColumn, userColumn string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if d.All {
+			return db
+		}
+		cond := db.Session(&gorm.Session{NewDB: true})
+		matched := false
+		if len(d.DeptIDs) > 0 && deptColumn != "" {
+			cond = cond.Or(db.Statement.Quote(deptColumn)+" IN ?", d.DeptIDs)
+			matched = true
+		}
+		if d.Self && userColumn != "" {
+			cond = cond.Or(db.Statement.Quote(userColumn)+" = ?", d.UserID)
+			matched = true
+		}
+		if !matched {
+			return db.Where("1 = 0")
+		}
+		return db.Where(cond)
+	}
+}
+
+// SetDeptResolver 注入部门层级解析，未注入时“本部门及下级部门”只包含本部门
+func (rs *RoleService) SetDeptResolver(resolver DeptResolver) {
+	rs.deptResolver = resolver
+}
+
+// UpdateDataScope 修改角色的数据权限，只有自定义部门时保留部门列表
+func (rs *RoleService) UpdateDataScope(ctx context.Context, roleId uint, scope consts.DataScope, deptIds []uint) error {
+	if scope != consts.DataScopeCustom {
+		deptIds = []uint{}
+	}
+	// 通过结构体更新，部门列表才会按 serializer 序列化
+	err := rs.DB.WithContext(ctx).Model(&rbac.Role{BaseModel: rbac.BaseModel{ID: roleId}}).
+		Select("data_scope", "data_scope_dept_ids").
+		Updates(&rbac.Role{DataScope: scope, DataScopeDeptIDs: deptIds}).Error
+	if err != nil {
+		return err
+	}
+	return rs.ClearCache(ctx)
+}
+
+// UserDataScope 计算用户的有效数据权限，禁用的角色不参与计算
+// 没有启用的角色的用户只能查看本人的数据
+func (rs *RoleService) UserDataScope(ctx context.Context, userID uint) (*DataScope, error) {
+	scope := &DataScope{UserID: userID}
+	roleIds, err := userRoleIDs(ctx, rs.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(roleIds) == 0 {
+		scope.Self = true
+		return scope, nil
+	}
+	var roles []rbac.Role
+	err = rs.DB.WithContext(ctx).Select("id", "data_scope", "data_scope_dept_ids").
+		Where("id IN ? AND status = ?", roleIds, consts.ROLESTATUS_ACTIVE).Find(&roles).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(roles) == 0 {
+		scope.Self = true
+		return scope, nil
+	}
+	var user rbac.User
+	if err = rs.DB.WithContext(ctx).Select("id", "dept_id").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	depts := make(map[uint]struct{})
+	addDepts := func(ids ...uint) {
+		for _, id := range ids {
+			if id != 0 {
+				depts[id] = struct{}{}
+			}
+		}
+	}
+	for _, role := range roles {
+		switch role.DataScope {
+		case consts.DataScopeAll:
+			scope.All = true
+			return scope, nil
+		case consts.DataScopeCustom:
+			addDepts(role.DataScopeDeptIDs...)
+		case consts.DataScopeDeptAndChildren:
+			if user.DeptID == 0 {
+				continue
+			}
+			ids, err := rs.resolver().SubDeptIDs(ctx, user.DeptID)
+			if err != nil {
+				return nil, err
+			}
+			addDepts(ids...)
+		case consts.DataScopeDept:
+			addDepts(user.DeptID)
+		default:
+			scope.Self = true
+		}
+	}
+	for id := range depts {
+		scope.DeptIDs = append(scope.DeptIDs, id)
+	}
+	return scope, nil
+}
+
+func (rs *RoleService) resolver() DeptResolver {
+	if rs.deptResolver == nil {
+		return flatDeptResolver{}
+	}
+	return rs.deptResolver
+}
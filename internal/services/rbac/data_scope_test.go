@@ -0,0 +1,82 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"gin-admin/internal/model/rbac"
+	"gin-admin/pkg/consts"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserDataScope(t *testing.T) {
+	rc, _ := newTestContext(t)
+	ctx := context.Background()
+
+	// 总部 <- 研发部 <- 前端组，销售部为另一个顶级部门
+	hq := &rbac.Department{Name: "总部", Status: consts.DeptStatusActive}
+	require.NoError(t, rc.DeptService.Create(ctx, hq))
+	dev := &rbac.Department{Name: "研发部", ParentID: hq.ID, Status: consts.DeptStatusActive}
+	require.NoError(t, rc.DeptService.Create(ctx, dev))
+	fe := &rbac.Department{Name: "前端组", ParentID: dev.ID, Status: consts.DeptStatusActive}
+	require.NoError(t, rc.DeptService.Create(ctx, fe))
+	sales := &rbac.Department{Name: "销售部", Status: consts.DeptStatusActive}
+	require.NoError(t, rc.DeptService.Create(ctx, sales))
+
+	roles := map[string]*rbac.Role{}
+	for name, scope := range map[string]consts.DataScope{
+		"all":      consts.DataScopeAll,
+		"custom":   consts.DataScopeCustom,
+		"children": consts.DataScopeDeptAndChildren,
+		"dept":     consts.DataScopeDept,
+		"self":     consts.DataScopeSelf,
+	} {
+		role := createTestRoles(t, rc, name)[0]
+		var deptIds []uint
+		if scope == consts.DataScopeCustom {
+			deptIds = []uint{sales.ID}
+		}
+		require.NoError(t, rc.RoleService.UpdateDataScope(ctx, role.ID, scope, deptIds))
+		roles[name] = role
+	}
+	// 禁用的角色即使是全部数据也不生效
+	disabled := createTestRoles(t, rc, "disabled")[0]
+	require.NoError(t, rc.RoleService.UpdateDataScope(ctx, disabled.ID, consts.DataScopeAll, nil))
+	require.NoError(t, rc.RoleService.UpdateByID(ctx, disabled.ID, map[string]interface{}{"status": consts.ROLESTATUS_INACTIVE}))
+	// 继承上级角色的数据权限
+	child := createTestRoles(t, rc, "child")[0]
+	require.NoError(t, rc.RoleService.UpdateDataScope(ctx, child.ID, consts.DataScopeSelf, nil))
+	require.NoError(t, rc.RoleService.SetParents(ctx, child, []uint{roles["custom"].ID}))
+
+	tests := []struct {
+		name  string
+		roles []*rbac.Role
+		all   bool
+		self  bool
+		depts []uint
+	}{
+		{"没有角色只能查看本人", nil, false, true, nil},
+		{"全部数据", []*rbac.Role{roles["dept"], roles["all"]}, true, false, nil},
+		{"本部门及下级部门", []*rbac.Role{roles["children"]}, false, false, []uint{dev.ID, fe.ID}},
+		{"自定义部门与本部门取并集", []*rbac.Role{roles["custom"], roles["dept"]}, false, false, []uint{sales.ID, dev.ID}},
+		{"部门与本人取并集", []*rbac.Role{roles["dept"], roles["self"]}, false, true, []uint{dev.ID}},
+		{"禁用的角色不生效", []*rbac.Role{disabled, roles["self"]}, false, true, nil},
+		{"只有禁用的角色时只能查看本人", []*rbac.Role{disabled}, false, true, nil},
+		{"继承上级角色", []*rbac.Role{child}, false, true, []uint{sales.ID}},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := createTestUserWithRoles(t, rc, fmt.Sprintf("user%d", i), tt.roles...)
+			require.NoError(t, rc.UserService.UpdateByID(ctx, user.ID, map[string]interface{}{"dept_id": dev.ID}))
+
+			scope, err := rc.RoleService.UserDataScope(ctx, user.ID)
+			require.NoError(t, err)
+			assert.Equal(t, user.ID, scope.UserID)
+			assert.Equal(t, tt.all, scope.All)
+			assert.Equal(t, tt.self, scope.Self)
+			assert.ElementsMatch(t, tt.depts, scope.DeptIDs)
+		})
+	}
+}
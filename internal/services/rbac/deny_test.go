@@ -0,0 +1,90 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"gin-admin/internal/model/rbac"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestDeniedResources(t *testing.T) {
+	rc, db := newTestContext(t)
+	ctx := context.Background()
+	list := rbac.Resource{Path: "/users", Method: http.MethodGet, Code: "user:list"}
+	create := rbac.Resource{Path: "/users", Method: http.MethodPost, Code: "user:create"}
+	export := rbac.Resource{Path: "/users/export", Method: http.MethodGet, Code: "user:export"}
+	for _, res := range []*rbac.Resource{&list, &create, &export} {
+		require.NoError(t, rc.ResourceService.Create(ctx, res))
+	}
+
+	r := createTestRoles(t, rc, "reader", "conditional", "denyList", "denyCreate", "parent", "child")
+	reader, conditional, denyList, denyCreate, parent, child := r[0], r[1], r[2], r[3], r[4], r[5]
+	require.NoError(t, rc.RoleService.SetResources(ctx, reader, []rbac.Resource{list, create, export}, nil))
+	require.NoError(t, rc.RoleService.SetResources(ctx, conditional, []rbac.Resource{create}, map[uint]string{create.ID: "time.hour >= 0"}))
+	require.NoError(t, rc.RoleService.SetDeniedResources(ctx, denyList, []uint{list.ID, list.ID}))
+	require.NoError(t, rc.RoleService.SetDeniedResources(ctx, denyCreate, []uint{create.ID}))
+	// child 从 parent 继承禁止访问
+	require.NoError(t, rc.RoleService.SetDeniedResources(ctx, parent, []uint{export.ID}))
+	require.NoError(t, rc.RoleService.SetParents(ctx, child, []uint{parent.ID}))
+
+	tests := []struct {
+		name     string
+		roles    []*rbac.Role
+		resource rbac.Resource
+		granted  bool
+	}{
+		{"禁止优先于其他角色的无条件授权", []*rbac.Role{reader, denyList}, list, false},
+		{"禁止优先于条件授权", []*rbac.Role{conditional, denyCreate}, create, false},
+		{"继承上级角色的禁止", []*rbac.Role{reader, child}, export, false},
+		{"未被禁止的资源不受影响", []*rbac.Role{reader, child}, list, true},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := createTestUserWithRoles(t, rc, fmt.Sprintf("user%d", i), tt.roles...)
+
+			granted, conditions, err := rc.ResourceService.CheckUserPermission(ctx, user.ID, tt.resource.Path, tt.resource.Method)
+			require.NoError(t, err)
+			assert.Equal(t, tt.granted, granted)
+			assert.Empty(t, conditions)
+
+			grants, err := rc.ResourceService.GetUserGrants(ctx, user.ID)
+			require.NoError(t, err)
+			var found bool
+			for _, g := range grants {
+				if g.ID == tt.resource.ID {
+					found = true
+					assert.Equal(t, !tt.granted, g.Denied)
+					assert.Empty(t, g.Condition)
+				}
+			}
+			assert.True(t, found)
+
+			resources, err := rc.ResourceService.GetUserResources(ctx, user.ID)
+			require.NoError(t, err)
+			ids := make([]uint, 0, len(resources))
+			for _, res := range resources {
+				ids = append(ids, res.ID)
+			}
+			assert.Equal(t, tt.granted, slices.Contains(ids, tt.resource.ID))
+		})
+	}
+
+	t.Run("内置角色不能设置", func(t *testing.T) {
+		builtIn := &rbac.Role{Name: "builtIn", BuiltIn: true}
+		require.NoError(t, rc.RoleService.Create(ctx, builtIn))
+		assert.ErrorIs(t, rc.RoleService.SetDeniedResources(ctx, builtIn, []uint{list.ID}), ErrRoleBuiltInDeny)
+	})
+	t.Run("资源不存在", func(t *testing.T) {
+		assert.ErrorIs(t, rc.RoleService.SetDeniedResources(ctx, denyList, []uint{list.ID, 999}), gorm.ErrRecordNotFound)
+		// 重复的资源只写入一次，失败的修改不影响已有的禁止
+		var denied []uint
+		require.NoError(t, db.Table("role_resource_denies").Where("role_id = ?", denyList.ID).Pluck("resource_id", &denied).Error)
+		assert.Equal(t, []uint{list.ID}, denied)
+	})
+}
@@ -0,0 +1,150 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"gin-admin/internal/model/rbac"
+	_interface "gin-admin/pkg/interface"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"slices"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/16 上午10:20
+* @Package: Department Service
+ */
+
+var (
+	ErrDeptCycle       = errors.New("不能将部门移动到自身或其下级部门之下")
+	ErrDeptHasChildren = errors.New("部门下存在下级部门，不能删除")
+	ErrDeptHasUsers    = errors.New("部门下存在用户，不能删除")
+)
+
+// DeptService 部门服务
+type DeptService struct {
+	_interface.Service[rbac.Department]
+}
+
+func NewDeptService(db *gorm.DB, cache _interface.ICache) *DeptService {
+	return &DeptService{
+		Service: *_interface.NewService[rbac.Department](db, cache),
+	}
+}
+
+// listAll 全部部门，同级按 sort、id 排序；部门数量有限，直接在内存中组装
+func (ds *DeptService) listAll(ctx context.Context) ([]rbac.Department, error) {
+	return ds.List(ctx, _interface.WithOrderBy("sort ASC, id ASC"))
+}
+
+// Tree 部门树，一次查询后在内存中组装，父部门不存在的部门作为顶级部门返回
+func (ds *DeptService) Tree(ctx context.Context) ([]*rbac.Department, error) {
+	depts, err := ds.listAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make(map[uint]*rbac.Department, len(depts))
+	for i := range depts {
+		nodes[depts[i].ID] = &depts[i]
+	}
+	roots := make([]*rbac.Department, 0)
+	for i := range depts {
+		node := &depts[i]
+		if parent, ok := nodes[node.ParentID]; ok && node.ParentID != node.ID {
+			parent.Children = append(parent.Children, node)
+			continue
+		}
+		roots = append(roots, node)
+	}
+	return roots, nil
+}
+
+// SubDeptIDs 部门及其所有下级部门（含间接下级），实现 DeptResolver
+func (ds *DeptService) SubDeptIDs(ctx context.Context, deptID uint) ([]uint, error) {
+	depts, err := ds.listAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[uint][]uint, len(depts))
+	for _, d := range depts {
+		children[d.ParentID] = append(children[d.ParentID], d.ID)
+	}
+	return walkGraph([]uint{deptID}, adjacency(children))
+}
+
+// CheckParent 校验上级部门：0 表示顶级部门，否则必须存在，且不能是部门自身或其下级部门
+// 新建部门时 deptID 传 0
+func (ds *DeptService) CheckParent(ctx context.Context, deptID, parentID uint) error {
+	return checkDeptParent(ctx, ds.DB, deptID, parentID)
+}
+
+// checkDeptParent 在 db 上逐层查询下级部门并校验上级部门
+// 查询到的部门加行锁，在事务中调用时并发的移动会在同一部门上互相等待，不会各自通过校验后形成循环
+func checkDeptParent(ctx context.Context, db *gorm.DB, deptID, parentID uint) error {
+	if parentID == 0 {
+		return nil
+	}
+	lock := clause.Locking{Strength: "UPDATE"}
+	if deptID != 0 {
+		descendants, err := walkGraph([]uint{deptID}, func(level []uint) ([]uint, error) {
+			var ids []uint
+			err := db.WithContext(ctx).Model(&rbac.Department{}).Clauses(lock).Where("parent_id IN ?", level).Pluck("id", &ids).Error
+			return ids, err
+		})
+		if err != nil {
+			return err
+		}
+		if slices.Contains(descendants, parentID) {
+			return ErrDeptCycle
+		}
+	}
+	var ids []uint
+	if err := db.WithContext(ctx).Model(&rbac.Department{}).Clauses(lock).Where("id = ?", parentID).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// UpdateDept 更新部门，updates 包含 parent_id 时在同一事务中校验上级部门
+func (ds *DeptService) UpdateDept(ctx context.Context, deptID uint, updates map[string]interface{}) error {
+	return ds.Transaction(ctx, func(ctx context.Context, tx *gorm.DB, txRepo _interface.IRepo[rbac.Department]) error {
+		if parentID, ok := updates["parent_id"].(uint); ok {
+			if err := checkDeptParent(ctx, tx, deptID, parentID); err != nil {
+				return err
+			}
+		}
+		return txRepo.UpdateByID(ctx, deptID, updates)
+	})
+}
+
+// Move 调整部门的上级部门和排序，下级部门随之移动
+func (ds *DeptService) Move(ctx context.Context, deptID, parentID uint, sort int) error {
+	return ds.UpdateDept(ctx, deptID, map[string]interface{}{
+		"parent_id": parentID,
+		"sort":      sort,
+	})
+}
+
+// DeleteDept 删除部门，存在下级部门或用户时拒绝删除
+func (ds *DeptService) DeleteDept(ctx context.Context, deptID uint) error {
+	exist, err := ds.Exists(ctx, _interface.WithConditions(map[string]interface{}{"parent_id": deptID}))
+	if err != nil {
+		return err
+	}
+	if exist {
+		return ErrDeptHasChildren
+	}
+	var users int64
+	if err = ds.DB.WithContext(ctx).Model(&rbac.User{}).Where("dept_id = ?", deptID).Count(&users).Error; err != nil {
+		return err
+	}
+	if users > 0 {
+		return ErrDeptHasUsers
+	}
+	return ds.DeleteByID(ctx, deptID)
+}
@@ -0,0 +1,93 @@
+package rbac
+
+import (
+	"context"
+	"gin-admin/internal/model/rbac"
+	"gin-admin/pkg/consts"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// createTestDept 在 parentID 下创建启用的部门
+func createTestDept(t *testing.T, rc *Context, name string, parentID uint) *rbac.Department {
+	dept := &rbac.Department{Name: name, ParentID: parentID, Status: consts.DeptStatusActive}
+	require.NoError(t, rc.DeptService.Create(context.Background(), dept))
+	return dept
+}
+
+func TestDeptMove(t *testing.T) {
+	rc, _ := newTestContext(t)
+	ctx := context.Background()
+	// 总部 <- 研发部 <- 前端组，销售部为另一个顶级部门
+	hq := createTestDept(t, rc, "总部", 0)
+	dev := createTestDept(t, rc, "研发部", hq.ID)
+	fe := createTestDept(t, rc, "前端组", dev.ID)
+	sales := createTestDept(t, rc, "销售部", 0)
+
+	ids, err := rc.DeptService.SubDeptIDs(ctx, hq.ID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{hq.ID, dev.ID, fe.ID}, ids)
+
+	// 研发部移动到销售部下，前端组随之移动
+	require.NoError(t, rc.DeptService.Move(ctx, dev.ID, sales.ID, 2))
+	moved, err := rc.DeptService.FindByID(ctx, dev.ID)
+	require.NoError(t, err)
+	assert.Equal(t, sales.ID, moved.ParentID)
+	assert.Equal(t, 2, moved.Sort)
+	ids, err = rc.DeptService.SubDeptIDs(ctx, sales.ID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{sales.ID, dev.ID, fe.ID}, ids)
+
+	// 移动为顶级部门
+	require.NoError(t, rc.DeptService.Move(ctx, dev.ID, 0, 0))
+	ids, err = rc.DeptService.SubDeptIDs(ctx, sales.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{sales.ID}, ids)
+}
+
+func TestDeptCycle(t *testing.T) {
+	rc, _ := newTestContext(t)
+	ctx := context.Background()
+	// a <- b <- c
+	a := createTestDept(t, rc, "a", 0)
+	b := createTestDept(t, rc, "b", a.ID)
+	c := createTestDept(t, rc, "c", b.ID)
+
+	tests := []struct {
+		name   string
+		dept   uint
+		parent uint
+		err    error
+	}{
+		{"自身", a.ID, a.ID, ErrDeptCycle},
+		{"直接下级", a.ID, b.ID, ErrDeptCycle},
+		{"间接下级", a.ID, c.ID, ErrDeptCycle},
+		{"上级部门不存在", c.ID, 999, gorm.ErrRecordNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, rc.DeptService.CheckParent(ctx, tt.dept, tt.parent), tt.err)
+			assert.ErrorIs(t, rc.DeptService.Move(ctx, tt.dept, tt.parent, 0), tt.err)
+			assert.ErrorIs(t, rc.DeptService.UpdateDept(ctx, tt.dept, map[string]interface{}{
+				"name":      "renamed",
+				"parent_id": tt.parent,
+			}), tt.err)
+		})
+	}
+
+	// 被拒绝的修改不写入任何字段
+	depts, err := rc.DeptService.listAll(ctx)
+	require.NoError(t, err)
+	parents := make(map[string]uint, len(depts))
+	for _, d := range depts {
+		parents[d.Name] = d.ParentID
+	}
+	assert.Equal(t, map[string]uint{"a": 0, "b": a.ID, "c": b.ID}, parents)
+
+	// 新建部门只校验上级部门是否存在
+	assert.NoError(t, rc.DeptService.CheckParent(ctx, 0, c.ID))
+	assert.ErrorIs(t, rc.DeptService.CheckParent(ctx, 0, 999), gorm.ErrRecordNotFound)
+}
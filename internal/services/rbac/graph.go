@@ -0,0 +1,46 @@
+package rbac
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/19 上午10:00
+* @Package: 角色继承、部门、菜单等层级关系的遍历
+ */
+
+// walkGraph 从 start 出发逐层展开，结果包含 start，已访问的节点不重复展开，存在环时也能结束
+// next 返回一层节点的全部相邻节点，可以逐层查询数据库，也可以用 adjacency 读取内存中的邻接表
+func walkGraph(start []uint, next func(level []uint) ([]uint, error)) ([]uint, error) {
+	visited := make(map[uint]struct{}, len(start))
+	result := make([]uint, 0, len(start))
+	level := start
+	for len(level) > 0 {
+		frontier := make([]uint, 0, len(level))
+		for _, id := range level {
+			if _, ok := visited[id]; ok {
+				continue
+			}
+			visited[id] = struct{}{}
+			result = append(result, id)
+			frontier = append(frontier, id)
+		}
+		if len(frontier) == 0 {
+			break
+		}
+		var err error
+		if level, err = next(frontier); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// adjacency 内存中的邻接表，作为 walkGraph 的 next
+func adjacency(edges map[uint][]uint) func(level []uint) ([]uint, error) {
+	return func(level []uint) ([]uint, error) {
+		var ids []uint
+		for _, id := range level {
+			ids = append(ids, edges[id]...)
+		}
+		return ids, nil
+	}
+}
@@ -0,0 +1,178 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"gin-admin/internal/model/rbac"
+	"gin-admin/pkg/consts"
+	_interface "gin-admin/pkg/interface"
+	"gorm.io/gorm"
+	"slices"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/18 上午10:00
+* @Package: Menu Service
+ */
+
+var (
+	ErrMenuCycle       = errors.New("不能将菜单移动到自身或其下级菜单之下")
+	ErrMenuHasChildren = errors.New("菜单下存在下级菜单，不能删除")
+)
+
+// MenuService 菜单服务
+type MenuService struct {
+	_interface.Service[rbac.Menu]
+}
+
+func NewMenuService(db *gorm.DB, cache _interface.ICache) *MenuService {
+	return &MenuService{
+		Service: *_interface.NewService[rbac.Menu](db, cache),
+	}
+}
+
+// Tree 全部菜单的树形结构，同级按 sort、id 排序，上级菜单不存在的菜单作为顶级菜单返回
+func (ms *MenuService) Tree(ctx context.Context) ([]*rbac.Menu, error) {
+	menus, err := ms.List(ctx, _interface.WithOrderBy("sort ASC, id ASC"))
+	if err != nil {
+		return nil, err
+	}
+	return buildMenuTree(menus), nil
+}
+
+// UserTree 用户可见的菜单树，perms 为 UserService.GetUserPerms 返回的有效权限
+// 禁用的菜单和所需权限未授权的菜单连同下级菜单一起排除；目录（没有组件）的下级菜单全部被排除时目录也不返回
+func (ms *MenuService) UserTree(ctx context.Context, perms []rbac.Permission) ([]*rbac.Menu, error) {
+	menus, err := ms.List(ctx, _interface.WithOrderBy("sort ASC, id ASC"))
+	if err != nil {
+		return nil, err
+	}
+	codes := grantedCodes(perms)
+	visible := make([]rbac.Menu, 0, len(menus))
+	for _, m := range menus {
+		if m.Status == consts.MenuStatusDisabled {
+			continue
+		}
+		if _, ok := codes[m.Permission]; m.Permission != "" && !ok {
+			continue
+		}
+		visible = append(visible, m)
+	}
+	roots := buildMenuTree(visible)
+	// 上级菜单被排除后，下级菜单不能作为顶级菜单返回
+	exists := make(map[uint]struct{}, len(menus))
+	parents := make(map[uint]struct{}, len(menus))
+	for _, m := range menus {
+		exists[m.ID] = struct{}{}
+		parents[m.ParentID] = struct{}{}
+	}
+	kept := make([]*rbac.Menu, 0, len(roots))
+	for _, root := range roots {
+		if _, ok := exists[root.ParentID]; ok && root.ParentID != root.ID {
+			continue
+		}
+		kept = append(kept, root)
+	}
+	return pruneEmptyMenus(kept, parents), nil
+}
+
+// CheckParent 校验上级菜单：0 表示顶级菜单，否则必须存在，且不能是菜单自身或其下级菜单
+// 新建菜单时 menuID 传 0
+func (ms *MenuService) CheckParent(ctx context.Context, menuID, parentID uint) error {
+	if parentID == 0 {
+		return nil
+	}
+	if menuID != 0 {
+		menus, err := ms.List(ctx)
+		if err != nil {
+			return err
+		}
+		children := make(map[uint][]uint, len(menus))
+		for _, m := range menus {
+			children[m.ParentID] = append(children[m.ParentID], m.ID)
+		}
+		descendants, err := walkGraph([]uint{menuID}, adjacency(children))
+		if err != nil {
+			return err
+		}
+		if slices.Contains(descendants, parentID) {
+			return ErrMenuCycle
+		}
+	}
+	exist, err := ms.ExistsByID(ctx, parentID)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteMenu 删除菜单，存在下级菜单时拒绝删除
+func (ms *MenuService) DeleteMenu(ctx context.Context, menuID uint) error {
+	exist, err := ms.Exists(ctx, _interface.WithConditions(map[string]interface{}{"parent_id": menuID}))
+	if err != nil {
+		return err
+	}
+	if exist {
+		return ErrMenuHasChildren
+	}
+	return ms.DeleteByID(ctx, menuID)
+}
+
+// buildMenuTree 在内存中组装菜单树，保持 menus 的顺序
+func buildMenuTree(menus []rbac.Menu) []*rbac.Menu {
+	nodes := make(map[uint]*rbac.Menu, len(menus))
+	for i := range menus {
+		nodes[menus[i].ID] = &menus[i]
+	}
+	roots := make([]*rbac.Menu, 0)
+	for i := range menus {
+		node := &menus[i]
+		if parent, ok := nodes[node.ParentID]; ok && node.ParentID != node.ID {
+			parent.Children = append(parent.Children, node)
+			continue
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
+
+// pruneEmptyMenus 去掉没有组件、下级菜单又全部不可见的目录，parents 为配置了下级菜单的菜单ID
+// 没有配置下级菜单、也没有组件的菜单（如外链）保留
+func pruneEmptyMenus(menus []*rbac.Menu, parents map[uint]struct{}) []*rbac.Menu {
+	kept := make([]*rbac.Menu, 0, len(menus))
+	for _, m := range menus {
+		m.Children = pruneEmptyMenus(m.Children, parents)
+		if _, ok := parents[m.ID]; ok && m.Component == "" && len(m.Children) == 0 {
+			continue
+		}
+		if len(m.Children) == 0 {
+			m.Children = nil
+		}
+		kept = append(kept, m)
+	}
+	return kept
+}
+
+// grantedCodes 已授权的权限分组编码和资源编码，被禁止访问的资源不算；分组下有任一资源授权即视为拥有该分组
+// 条件授权（Condition 非空）同样算作已授权：条件依赖具体请求（IP、时间、路径参数等），生成菜单时无法求值，
+// 菜单只决定入口是否显示，调用接口时权限中间件仍会对条件求值
+func grantedCodes(perms []rbac.Permission) map[string]struct{} {
+	codes := make(map[string]struct{})
+	for _, p := range perms {
+		for _, res := range p.Resources {
+			if res.Denied {
+				continue
+			}
+			codes[p.Code] = struct{}{}
+			if res.Code != "" {
+				codes[res.Code] = struct{}{}
+			}
+		}
+	}
+	return codes
+}
@@ -0,0 +1,179 @@
+package rbac
+
+import (
+	"context"
+	"gin-admin/internal/model/rbac"
+	"gin-admin/pkg/consts"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestGrantedCodes(t *testing.T) {
+	tests := []struct {
+		name  string
+		perms []rbac.Permission
+		want  []string
+	}{
+		{"没有权限", nil, nil},
+		{"分组和资源编码", []rbac.Permission{
+			{Code: "user:manage", Resources: []rbac.Resource{{Code: "user:list"}, {Code: "user:add"}}},
+		}, []string{"user:manage", "user:list", "user:add"}},
+		{"被禁止的资源不算", []rbac.Permission{
+			{Code: "user:manage", Resources: []rbac.Resource{{Code: "user:list"}, {Code: "user:add", Denied: true}}},
+		}, []string{"user:manage", "user:list"}},
+		{"分组下的资源全部被禁止", []rbac.Permission{
+			{Code: "user:manage", Resources: []rbac.Resource{{Code: "user:list", Denied: true}}},
+		}, nil},
+		{"条件授权算作已授权", []rbac.Permission{
+			{Code: "user:manage", Resources: []rbac.Resource{{Code: "user:list", Condition: "time.hour >= 9"}}},
+		}, []string{"user:manage", "user:list"}},
+		{"没有编码的资源只授权分组", []rbac.Permission{
+			{Code: "user:manage", Resources: []rbac.Resource{{}}},
+		}, []string{"user:manage"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codes := grantedCodes(tt.perms)
+			got := make([]string, 0, len(codes))
+			for code := range codes {
+				got = append(got, code)
+			}
+			assert.ElementsMatch(t, tt.want, got)
+		})
+	}
+}
+
+// menuNames 菜单树按先序展开为 名称 -> 上级名称，顶级菜单的上级为空
+func menuNames(menus []*rbac.Menu, parent string, into map[string]string) map[string]string {
+	for _, m := range menus {
+		into[m.Name] = parent
+		menuNames(m.Children, m.Name, into)
+	}
+	return into
+}
+
+func TestPruneEmptyMenus(t *testing.T) {
+	tests := []struct {
+		name  string
+		menus []*rbac.Menu
+		// parents 配置了下级菜单的菜单ID
+		parents []uint
+		want    map[string]string
+	}{
+		{"下级菜单全部不可见的目录", []*rbac.Menu{
+			{BaseModel: rbac.BaseModel{ID: 1}, Name: "系统管理"},
+		}, []uint{1}, map[string]string{}},
+		{"有组件的菜单保留", []*rbac.Menu{
+			{BaseModel: rbac.BaseModel{ID: 1}, Name: "用户管理", Component: "system/user/index"},
+		}, []uint{1}, map[string]string{"用户管理": ""}},
+		{"没有下级菜单的外链保留", []*rbac.Menu{
+			{BaseModel: rbac.BaseModel{ID: 1}, Name: "文档", Path: "https://example.com"},
+		}, nil, map[string]string{"文档": ""}},
+		{"逐层去掉空目录", []*rbac.Menu{
+			{BaseModel: rbac.BaseModel{ID: 1}, Name: "系统管理", Children: []*rbac.Menu{
+				{BaseModel: rbac.BaseModel{ID: 2}, ParentID: 1, Name: "权限"},
+				{BaseModel: rbac.BaseModel{ID: 3}, ParentID: 1, Name: "用户管理", Component: "system/user/index"},
+			}},
+			{BaseModel: rbac.BaseModel{ID: 4}, Name: "监控", Children: []*rbac.Menu{
+				{BaseModel: rbac.BaseModel{ID: 5}, ParentID: 4, Name: "日志"},
+			}},
+		}, []uint{1, 2, 4, 5}, map[string]string{"系统管理": "", "用户管理": "系统管理"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parents := make(map[uint]struct{}, len(tt.parents))
+			for _, id := range tt.parents {
+				parents[id] = struct{}{}
+			}
+			got := pruneEmptyMenus(tt.menus, parents)
+			assert.Equal(t, tt.want, menuNames(got, "", map[string]string{}))
+			for _, m := range got {
+				if m.Component != "" {
+					assert.Nil(t, m.Children, "没有下级菜单时不返回空数组")
+				}
+			}
+		})
+	}
+}
+
+func TestUserTree(t *testing.T) {
+	rc, _ := newTestContext(t)
+	ctx := context.Background()
+	// 系统管理（目录） <- 用户管理、角色管理、日志（禁用） <- 日志详情；首页登录即可见
+	create := func(name string, parentID uint, component, permission string, status consts.MenuStatus) *rbac.Menu {
+		m := &rbac.Menu{Name: name, ParentID: parentID, Component: component, Permission: permission, Status: status}
+		require.NoError(t, rc.MenuService.Create(ctx, m))
+		return m
+	}
+	create("首页", 0, "home/index", "", consts.MenuStatusActive)
+	system := create("系统管理", 0, "", "", consts.MenuStatusActive)
+	create("用户管理", system.ID, "system/user/index", "user:manage", consts.MenuStatusActive)
+	create("角色管理", system.ID, "system/role/index", "role:list", consts.MenuStatusActive)
+	logs := create("日志", system.ID, "system/log/index", "", consts.MenuStatusDisabled)
+	create("日志详情", logs.ID, "system/log/detail", "", consts.MenuStatusActive)
+
+	tests := []struct {
+		name  string
+		perms []rbac.Permission
+		want  map[string]string
+	}{
+		{"没有权限时目录一并去掉", nil, map[string]string{"首页": ""}},
+		{"按分组编码可见", []rbac.Permission{
+			{Code: "user:manage", Resources: []rbac.Resource{{Code: "user:list"}}},
+		}, map[string]string{"首页": "", "系统管理": "", "用户管理": "系统管理"}},
+		{"按资源编码可见", []rbac.Permission{
+			{Code: "role:manage", Resources: []rbac.Resource{{Code: "role:list"}}},
+		}, map[string]string{"首页": "", "系统管理": "", "角色管理": "系统管理"}},
+		{"被禁止的资源不可见", []rbac.Permission{
+			{Code: "role:manage", Resources: []rbac.Resource{{Code: "role:list", Denied: true}}},
+		}, map[string]string{"首页": ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := rc.MenuService.UserTree(ctx, tt.perms)
+			require.NoError(t, err)
+			// 禁用的菜单连同下级菜单一起排除，下级菜单不会作为顶级菜单返回
+			assert.Equal(t, tt.want, menuNames(tree, "", map[string]string{}))
+		})
+	}
+}
+
+func TestMenuCheckParent(t *testing.T) {
+	rc, _ := newTestContext(t)
+	ctx := context.Background()
+	// a <- b <- c
+	a := &rbac.Menu{Name: "a", Component: "a"}
+	require.NoError(t, rc.MenuService.Create(ctx, a))
+	b := &rbac.Menu{Name: "b", ParentID: a.ID, Component: "b"}
+	require.NoError(t, rc.MenuService.Create(ctx, b))
+	c := &rbac.Menu{Name: "c", ParentID: b.ID, Component: "c"}
+	require.NoError(t, rc.MenuService.Create(ctx, c))
+
+	tests := []struct {
+		name   string
+		menu   uint
+		parent uint
+		err    error
+	}{
+		{"顶级菜单", c.ID, 0, nil},
+		{"合法的上级菜单", c.ID, a.ID, nil},
+		{"新建菜单", 0, c.ID, nil},
+		{"自身", a.ID, a.ID, ErrMenuCycle},
+		{"直接下级", a.ID, b.ID, ErrMenuCycle},
+		{"间接下级", a.ID, c.ID, ErrMenuCycle},
+		{"上级菜单不存在", c.ID, 999, gorm.ErrRecordNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := rc.MenuService.CheckParent(ctx, tt.menu, tt.parent)
+			if tt.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package rbac
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"gin-admin/internal/model/rbac"
+	"gin-admin/pkg/components/totp"
+	"gin-admin/pkg/consts"
+	_interface "gin-admin/pkg/interface"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/11 下午2:30
+* @Package: 两步验证（TOTP + 恢复码）
+ */
+
+var (
+	ErrMFANotEnabled     = errors.New("未开启两步验证")
+	ErrMFAAlreadyEnabled = errors.New("已开启两步验证")
+	ErrMFANotSetup       = errors.New("请先获取两步验证密钥")
+	ErrMFAInvalidCode    = errors.New("验证码错误")
+)
+
+// MFAService 两步验证服务
+// 安全相关状态直接读写数据库，不走 Service[T] 的查询缓存
+type MFAService struct {
+	_interface.Service[rbac.UserMFA]
+	opts totp.Options
+	box  *totp.SecretBox
+}
+
+func NewMFAService(db *gorm.DB, cache _interface.ICache) *MFAService {
+	box, _ := totp.NewSecretBox("")
+	return &MFAService{
+		Service: *_interface.NewService[rbac.UserMFA](db, cache),
+		opts:    totp.DefaultOptions,
+		box:     box,
+	}
+}
+
+// SetSecretBox 设置 TOTP 密钥的加密方式，之后绑定的密钥加密保存，加密前保存的明文密钥在下次验证通过时加密
+func (s *MFAService) SetSecretBox(box *totp.SecretBox) {
+	s.box = box
+}
+
+// URI 生成验证器绑定链接（二维码内容）
+func (s *MFAService) URI(issuer, account, secret string) string {
+	return s.opts.URI(issuer, account, secret)
+}
+
+// State 查询用户是否已开启两步验证，以及所属角色是否要求开启
+func (s *MFAService) State(ctx context.Context, userID uint) (enabled, required bool, err error) {
+	mfa, err := s.get(ctx, userID)
+	if err != nil {
+		return false, false, err
+	}
+	var count int64
+	err = s.DB.WithContext(ctx).Table("roles").
+		Joins("JOIN user_roles ur ON ur.role_id = roles.id").
+		Where("ur.user_id = ? AND roles.require_mfa = ? AND roles.status = ?", userID, true, consts.ROLESTATUS_ACTIVE).
+		Count(&count).Error
+	if err != nil {
+		return false, false, err
+	}
+	return mfa != nil && mfa.Enabled, count > 0, nil
+}
+
+// RemainingRecoveryCodes 未使用的恢复码数量
+func (s *MFAService) RemainingRecoveryCodes(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := s.DB.WithContext(ctx).Model(&rbac.MFARecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// Setup 生成新的待绑定密钥，重复调用会覆盖之前未确认的密钥
+func (s *MFAService) Setup(ctx context.Context, userID uint) (string, error) {
+	mfa, err := s.get(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if mfa != nil && mfa.Enabled {
+		return "", ErrMFAAlreadyEnabled
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := s.box.Seal(secret)
+	if err != nil {
+		return "", err
+	}
+	err = s.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"secret", "last_used_step", "updated_at"}),
+	}).Create(&rbac.UserMFA{UserID: userID, Secret: sealed}).Error
+	if err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// Enable 校验验证器生成的口令后开启两步验证，返回恢复码明文（仅此一次）
+func (s *MFAService) Enable(ctx context.Context, userID uint, code string, recoveryCodes int) ([]string, error) {
+	mfa, err := s.get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil {
+		return nil, ErrMFANotSetup
+	}
+	if mfa.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	secret, err := s.box.Open(mfa.Secret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := s.opts.Validate(secret, code, time.Now())
+	if !ok {
+		return nil, ErrMFAInvalidCode
+	}
+	updates := map[string]interface{}{
+		"enabled":        true,
+		"enabled_at":     time.Now(),
+		"last_used_step": step,
+	}
+	// 加密前保存的明文密钥，绑定时改为加密保存
+	if s.box.Enabled() && secret == mfa.Secret {
+		if updates["secret"], err = s.box.Seal(secret); err != nil {
+			return nil, err
+		}
+	}
+	var codes []string
+	err = s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&rbac.UserMFA{}).Where("id = ?", mfa.ID).Updates(updates).Error
+		if err != nil {
+			return err
+		}
+		codes, err = replaceRecoveryCodes(tx, userID, recoveryCodes)
+		return err
+	})
+	return codes, err
+}
+
+// Verify 校验 TOTP 口令或恢复码，恢复码使用后即失效
+func (s *MFAService) Verify(ctx context.Context, userID uint, code string) error {
+	mfa, err := s.get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if mfa == nil || !mfa.Enabled {
+		return ErrMFANotEnabled
+	}
+	secret, err := s.box.Open(mfa.Secret)
+	if err != nil {
+		return err
+	}
+	if step, ok := s.opts.Validate(secret, code, time.Now()); ok {
+		updates := map[string]interface{}{"last_used_step": step}
+		// 加密前保存的明文密钥，验证通过后改为加密保存
+		if s.box.Enabled() && secret == mfa.Secret {
+			if updates["secret"], err = s.box.Seal(secret); err != nil {
+				return err
+			}
+		}
+		// 条件更新保证同一时间步的口令只能用一次
+		result := s.DB.WithContext(ctx).Model(&rbac.UserMFA{}).
+			Where("id = ? AND last_used_step < ?", mfa.ID, step).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrMFAInvalidCode
+		}
+		return nil
+	}
+	result := s.DB.WithContext(ctx).Model(&rbac.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashRecoveryCode(code)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMFAInvalidCode
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧的全部作废
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, userID uint, n int) ([]string, error) {
+	var codes []string
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userID, n)
+		return err
+	})
+	return codes, err
+}
+
+// Disable 关闭两步验证，同时删除密钥和恢复码
+func (s *MFAService) Disable(ctx context.Context, userID uint) error {
+	return s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&rbac.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&rbac.UserMFA{}).Error
+	})
+}
+
+func (s *MFAService) get(ctx context.Context, userID uint) (*rbac.UserMFA, error) {
+	var mfa rbac.UserMFA
+	err := s.DB.WithContext(ctx).Where("user_id = ?", userID).Take(&mfa).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &mfa, nil
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, n int) ([]string, error) {
+	codes, err := totp.GenerateRecoveryCodes(n)
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Where("user_id = ?", userID).Delete(&rbac.MFARecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	records := make([]rbac.MFARecoveryCode, 0, len(codes))
+	for _, code := range codes {
+		records = append(records, rbac.MFARecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(code)})
+	}
+	if err = tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode 恢复码是高熵随机串，直接 sha256 即可
+func hashRecoveryCode(code string) string {
+	h := sha256.Sum256([]byte(totp.NormalizeRecoveryCode(code)))
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,84 @@
+package rbac
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"gin-admin/internal/model/rbac"
+	"gin-admin/pkg/components/jwt"
+	_interface "gin-admin/pkg/interface"
+	"gorm.io/gorm"
+	"time"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/12 上午10:20
+* @Package: 找回密码令牌
+ */
+
+var ErrResetTokenInvalid = errors.New("重置链接无效或已过期")
+
+// PasswordResetService 找回密码令牌服务
+type PasswordResetService struct {
+	_interface.Service[rbac.PasswordResetToken]
+}
+
+func NewPasswordResetService(db *gorm.DB, cache _interface.ICache) *PasswordResetService {
+	return &PasswordResetService{
+		Service: *_interface.NewService[rbac.PasswordResetToken](db, cache),
+	}
+}
+
+// Issue 签发重置令牌，同一用户之前未使用的令牌全部作废，返回令牌明文
+func (s *PasswordResetService) Issue(ctx context.Context, userID uint, ttl time.Duration, ip string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	now := time.Now()
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&rbac.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", userID).
+			Update("used_at", now).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&rbac.PasswordResetToken{
+			UserID:    userID,
+			TokenHash: jwt.Hash(token),
+			ExpiresAt: now.Add(ttl),
+			IP:        ip,
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Consume 使用令牌，成功返回令牌所属用户；条件更新保证并发请求中只有一个能成功
+func (s *PasswordResetService) Consume(ctx context.Context, token string) (uint, error) {
+	var record rbac.PasswordResetToken
+	err := s.DB.WithContext(ctx).Where("token_hash = ?", jwt.Hash(token)).Take(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrResetTokenInvalid
+	}
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	result := s.DB.WithContext(ctx).Model(&rbac.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", record.ID, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, ErrResetTokenInvalid
+	}
+	return record.UserID, nil
+}
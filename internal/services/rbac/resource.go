@@ -1,10 +1,12 @@
 package rbac
 
 import (
+	"cmp"
 	"context"
 	"gin-admin/internal/model/rbac"
 	_interface "gin-admin/pkg/interface"
 	"gorm.io/gorm"
+	"slices"
 )
 
 /*
@@ -24,33 +26,109 @@ func NewResourceService(db *gorm.DB, cache _interface.ICache) *ResourceService {
 		Service: *_interface.NewService[rbac.Resource](db, cache),
 	}
 }
-func (s *ResourceService) CheckUserPermission(ctx context.Context, userID uint, path string, method string) (bool, error) {
-	// 直接检查 role_resources（不再查询 role_permissions）
-	var count int64
-	err := s.DB.WithContext(ctx).Raw(`
+
+// CheckUserPermission 用户的角色（含继承的上级角色）是否绑定了该资源，任一角色禁止访问时不授权
+// granted 为存在无条件授权；否则 conditions 为各条件授权的表达式，任一成立即授权
+func (s *ResourceService) CheckUserPermission(ctx context.Context, userID uint, path string, method string) (granted bool, conditions []string, err error) {
+	roleIds, err := userRoleIDs(ctx, s.DB, userID)
+	if err != nil || len(roleIds) == 0 {
+		return false, nil, err
+	}
+	var denied int64
+	err = s.DB.WithContext(ctx).Raw(`
 		SELECT COUNT(*) FROM resources res
+		JOIN role_resource_denies rd ON res.id = rd.resource_id
+		WHERE rd.role_id IN ? AND res.path = ? AND res.method = ?
+	`, roleIds, path, method).Scan(&denied).Error
+	if err != nil || denied > 0 {
+		return false, nil, err
+	}
+	// 直接检查 role_resources（不再查询 role_permissions）
+	var grants []string
+	err = s.DB.WithContext(ctx).Raw(`
+		SELECT rr.condition_expr FROM resources res
 		JOIN role_resources rr ON res.id = rr.resource_id
-		JOIN user_roles ur ON rr.role_id = ur.role_id
-		WHERE ur.user_id = ? AND res.path = ? AND res.method = ?
-	`, userID, path, method).Scan(&count).Error
+		WHERE rr.role_id IN ? AND res.path = ? AND res.method = ?
+	`, roleIds, path, method).Scan(&grants).Error
+	if err != nil {
+		return false, nil, err
+	}
+	for _, cond := range grants {
+		if cond == "" {
+			return true, nil, nil
+		}
+		if !slices.Contains(conditions, cond) {
+			conditions = append(conditions, cond)
+		}
+	}
+	return false, conditions, nil
+}
 
-	return count > 0, err
+// GetUserResources 获取用户可访问的资源列表（直接通过 role_resources，含继承的上级角色的资源），不含被禁止访问的资源
+// 只有条件授权的资源 Condition 非空
+func (s *ResourceService) GetUserResources(ctx context.Context, userID uint) ([]rbac.Resource, error) {
+	grants, err := s.GetUserGrants(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	resources := make([]rbac.Resource, 0, len(grants))
+	for _, res := range grants {
+		if !res.Denied {
+			resources = append(resources, res)
+		}
+	}
+	return resources, nil
+}
 
+// GetUserGrants 用户的有效授权，被禁止访问的资源同样返回，Denied 为 true
+func (s *ResourceService) GetUserGrants(ctx context.Context, userID uint) ([]rbac.Resource, error) {
+	roleIds, err := userRoleIDs(ctx, s.DB, userID)
+	if err != nil || len(roleIds) == 0 {
+		return nil, err
+	}
+	return roleGrants(ctx, s.DB, roleIds)
 }
 
-// GetUserResources 获取用户可访问的资源列表（直接通过 role_resources）
-func (s *ResourceService) GetUserResources(ctx context.Context, userID uint) ([]rbac.Resource, error) {
-	var resources []rbac.Resource
-	err := s.DB.WithContext(ctx).Raw(`
-		SELECT DISTINCT res.* FROM resources res
+// roleGrants 多个角色合并后的授权，按路径和方法排序
+// 任一角色禁止访问即为禁止；否则任一角色无条件授权即为无条件
+func roleGrants(ctx context.Context, db *gorm.DB, roleIds []uint) ([]rbac.Resource, error) {
+	var allowed, denied []rbac.Resource
+	err := db.WithContext(ctx).Raw(`
+		SELECT res.*, rr.condition_expr AS grant_condition FROM resources res
 		JOIN role_resources rr ON res.id = rr.resource_id
-		JOIN user_roles ur ON rr.role_id = ur.role_id
-		WHERE ur.user_id = ?
-		ORDER BY res.path, res.method
-	`, userID).Find(&resources).Error
-
+		WHERE rr.role_id IN ?
+	`, roleIds).Find(&allowed).Error
 	if err != nil {
 		return nil, err
 	}
-	return resources, nil
+	err = db.WithContext(ctx).Raw(`
+		SELECT DISTINCT res.* FROM resources res
+		JOIN role_resource_denies rd ON res.id = rd.resource_id
+		WHERE rd.role_id IN ?
+	`, roleIds).Find(&denied).Error
+	if err != nil {
+		return nil, err
+	}
+	grants := make([]rbac.Resource, 0, len(allowed)+len(denied))
+	index := make(map[uint]int, len(allowed)+len(denied))
+	for _, res := range denied {
+		res.Denied = true
+		index[res.ID] = len(grants)
+		grants = append(grants, res)
+	}
+	for _, res := range allowed {
+		i, ok := index[res.ID]
+		if !ok {
+			index[res.ID] = len(grants)
+			grants = append(grants, res)
+			continue
+		}
+		if !grants[i].Denied && res.Condition == "" {
+			grants[i].Condition = ""
+		}
+	}
+	slices.SortFunc(grants, func(a, b rbac.Resource) int {
+		return cmp.Or(cmp.Compare(a.Path, b.Path), cmp.Compare(a.Method, b.Method))
+	})
+	return grants, nil
 }
@@ -1,9 +1,13 @@
 package rbac
 
 import (
+	"context"
+	"errors"
 	"gin-admin/internal/model/rbac"
 	_interface "gin-admin/pkg/interface"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"slices"
 )
 
 /*
@@ -13,9 +17,15 @@ import (
 * @Package: Role Service
  */
 
+var (
+	ErrRoleCycle       = errors.New("不能将角色自身或其下级角色设为上级角色")
+	ErrRoleBuiltInDeny = errors.New("内置角色不能设置禁止访问的资源")
+)
+
 // RoleService 角色服务
 type RoleService struct {
 	_interface.Service[rbac.Role]
+	deptResolver DeptResolver
 }
 
 func NewRoleService(db *gorm.DB, cache _interface.ICache) *RoleService {
@@ -24,11 +34,15 @@ func NewRoleService(db *gorm.DB, cache _interface.ICache) *RoleService {
 	}
 }
 
-func (rs *RoleService) ListRoleUsers(roleId uint) (uids []uint, err error) {
+// ListRoleUsers 拥有指定角色（直接分配）的用户
+func (rs *RoleService) ListRoleUsers(roleIds ...uint) (uids []uint, err error) {
+	if len(roleIds) == 0 {
+		return nil, nil
+	}
 	err = rs.DB.
 		Table("user_roles").
-		Select("user_id").
-		Where("role_id = ?", roleId).
+		Distinct("user_id").
+		Where("role_id IN ?", roleIds).
 		Find(&uids).Error
 	if err != nil {
 		return nil, err
@@ -36,3 +50,90 @@ func (rs *RoleService) ListRoleUsers(roleId uint) (uids []uint, err error) {
 	return uids, nil
 
 }
+
+// ListRoleTreeUsers 拥有该角色或其任一下级角色的用户，角色的资源或继承关系变更时这些用户的权限都会变化
+func (rs *RoleService) ListRoleTreeUsers(ctx context.Context, roleId uint) ([]uint, error) {
+	roleIds, err := rs.DescendantIDs(ctx, roleId)
+	if err != nil {
+		return nil, err
+	}
+	return rs.ListRoleUsers(roleIds...)
+}
+
+// DescendantIDs 角色及其所有下级角色（含间接下级）
+func (rs *RoleService) DescendantIDs(ctx context.Context, roleId uint) ([]uint, error) {
+	return walkRoleParents(ctx, rs.DB, []uint{roleId}, false)
+}
+
+// SetParents 设置上级角色，上级角色不能是自身或自身的下级角色（避免循环继承）
+// 循环检查与写入在同一事务中；途经的下级角色和新的上级角色加行锁，并发设置继承关系时在共同的角色上互相等待，不会各自通过检查后形成循环
+func (rs *RoleService) SetParents(ctx context.Context, role *rbac.Role, parentIds []uint) error {
+	return rs.Transaction(ctx, func(ctx context.Context, tx *gorm.DB, txRepo _interface.IRepo[rbac.Role]) error {
+		lock := clause.Locking{Strength: "UPDATE"}
+		descendants, err := walkGraph([]uint{role.ID}, func(level []uint) ([]uint, error) {
+			var locked, ids []uint
+			if err := tx.WithContext(ctx).Model(&rbac.Role{}).Clauses(lock).Where("id IN ?", level).Pluck("id", &locked).Error; err != nil {
+				return nil, err
+			}
+			err := tx.WithContext(ctx).Table("role_parents").Where("parent_id IN ?", level).Pluck("role_id", &ids).Error
+			return ids, err
+		})
+		if err != nil {
+			return err
+		}
+		for _, id := range parentIds {
+			if slices.Contains(descendants, id) {
+				return ErrRoleCycle
+			}
+		}
+		parentIds = slices.Compact(slices.Sorted(slices.Values(parentIds))) // 去重
+		var parents []rbac.Role
+		if len(parentIds) != 0 {
+			parents, err = txRepo.FindByIDs(ctx, parentIds, _interface.WithScopes(func(db *gorm.DB) *gorm.DB {
+				return db.Clauses(lock)
+			}))
+			if err != nil {
+				return err
+			}
+			if len(parents) != len(parentIds) {
+				return gorm.ErrRecordNotFound
+			}
+		}
+		return txRepo.ReplaceAssociation(ctx, role, "Parents", parents)
+	})
+}
+
+// DeleteRole 删除角色及其继承关系，下级角色不再继承该角色的资源
+func (rs *RoleService) DeleteRole(ctx context.Context, roleId uint) error {
+	return rs.Transaction(ctx, func(ctx context.Context, tx *gorm.DB, txRepo _interface.IRepo[rbac.Role]) error {
+		err := tx.Where("role_id = ? OR parent_id = ?", roleId, roleId).Delete(&rbac.RoleParent{}).Error
+		if err != nil {
+			return err
+		}
+		return txRepo.DeleteByID(ctx, roleId)
+	})
+}
+
+// walkRoleParents 从 start 出发逐层查询继承关系，up 为 true 时查找上级，否则查找下级
+// 结果包含 start；只查询途经角色的关系，不加载整张继承关系表
+func walkRoleParents(ctx context.Context, db *gorm.DB, start []uint, up bool) ([]uint, error) {
+	from, to := "parent_id", "role_id"
+	if up {
+		from, to = to, from
+	}
+	return walkGraph(start, func(level []uint) ([]uint, error) {
+		var ids []uint
+		err := db.WithContext(ctx).Table("role_parents").Where(from+" IN ?", level).Pluck(to, &ids).Error
+		return ids, err
+	})
+}
+
+// userRoleIDs 用户直接分配的角色及其继承的所有上级角色
+func userRoleIDs(ctx context.Context, db *gorm.DB, userID uint) ([]uint, error) {
+	var roleIds []uint
+	err := db.WithContext(ctx).Table("user_roles").Where("user_id = ?", userID).Pluck("role_id", &roleIds).Error
+	if err != nil || len(roleIds) == 0 {
+		return nil, err
+	}
+	return walkRoleParents(ctx, db, roleIds, true)
+}
@@ -0,0 +1,126 @@
+package rbac
+
+import (
+	"context"
+	"gin-admin/internal/model/rbac"
+	cache2 "gin-admin/pkg/components/cache"
+	"gin-admin/pkg/consts"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestContext 基于内存 SQLite 和内存缓存的 RBAC 服务
+func newTestContext(t *testing.T) (*Context, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	// 内存库每个连接都是独立的数据库
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(
+		&rbac.User{},
+		&rbac.Role{},
+		&rbac.Permission{},
+		&rbac.Resource{},
+		&rbac.RoleResource{},
+		&rbac.PasswordHistory{},
+		&rbac.Department{},
+		&rbac.Menu{},
+	))
+	return NewContext(db, cache2.NewShardedMemoryCache(0)), db
+}
+
+// createTestRoles 按名称创建启用的角色
+func createTestRoles(t *testing.T, rc *Context, names ...string) []*rbac.Role {
+	roles := make([]*rbac.Role, 0, len(names))
+	for _, name := range names {
+		role := &rbac.Role{Name: name, Status: consts.ROLESTATUS_ACTIVE}
+		require.NoError(t, rc.RoleService.Create(context.Background(), role))
+		roles = append(roles, role)
+	}
+	return roles
+}
+
+// createTestUserWithRoles 创建用户并直接分配角色
+func createTestUserWithRoles(t *testing.T, rc *Context, username string, roles ...*rbac.Role) *rbac.User {
+	user := &rbac.User{Username: username, Email: username + "@example.com", Status: consts.UserStatusActive}
+	user.SetPassword("Passw0rd!")
+	for _, role := range roles {
+		user.Roles = append(user.Roles, *role)
+	}
+	require.NoError(t, rc.UserService.Create(context.Background(), user))
+	return user
+}
+
+func TestRoleInheritance(t *testing.T) {
+	rc, db := newTestContext(t)
+	ctx := context.Background()
+	// admin <- manager <- staff，auditor 与 staff 同为 manager 的下级
+	r := createTestRoles(t, rc, "admin", "manager", "staff", "auditor")
+	admin, manager, staff, auditor := r[0], r[1], r[2], r[3]
+	require.NoError(t, rc.RoleService.SetParents(ctx, manager, []uint{admin.ID}))
+	require.NoError(t, rc.RoleService.SetParents(ctx, staff, []uint{manager.ID}))
+	require.NoError(t, rc.RoleService.SetParents(ctx, auditor, []uint{manager.ID, manager.ID}))
+
+	user := createTestUserWithRoles(t, rc, "alice", staff)
+	roleIds, err := userRoleIDs(ctx, db, user.ID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{staff.ID, manager.ID, admin.ID}, roleIds)
+
+	descendants, err := rc.RoleService.DescendantIDs(ctx, admin.ID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{admin.ID, manager.ID, staff.ID, auditor.ID}, descendants)
+
+	// 删除中间角色后，下级角色不再继承其上级
+	require.NoError(t, rc.RoleService.DeleteRole(ctx, manager.ID))
+	roleIds, err = userRoleIDs(ctx, db, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{staff.ID}, roleIds)
+	var edges int64
+	require.NoError(t, db.Table("role_parents").Where("role_id = ? OR parent_id = ?", manager.ID, manager.ID).Count(&edges).Error)
+	assert.Zero(t, edges)
+}
+
+func TestRoleCycle(t *testing.T) {
+	rc, db := newTestContext(t)
+	ctx := context.Background()
+	r := createTestRoles(t, rc, "a", "b", "c")
+	a, b, c := r[0], r[1], r[2]
+	// a <- b <- c
+	require.NoError(t, rc.RoleService.SetParents(ctx, b, []uint{a.ID}))
+	require.NoError(t, rc.RoleService.SetParents(ctx, c, []uint{b.ID}))
+
+	tests := []struct {
+		name    string
+		role    *rbac.Role
+		parents []uint
+	}{
+		{"自身", a, []uint{a.ID}},
+		{"直接下级", a, []uint{b.ID}},
+		{"间接下级", a, []uint{c.ID}},
+		{"混合合法上级", b, []uint{a.ID, c.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, rc.RoleService.SetParents(ctx, tt.role, tt.parents), ErrRoleCycle)
+		})
+	}
+
+	// 被拒绝的修改不写入任何继承关系
+	var edges []rbac.RoleParent
+	require.NoError(t, db.Table("role_parents").Find(&edges).Error)
+	assert.ElementsMatch(t, []rbac.RoleParent{{RoleID: b.ID, ParentID: a.ID}, {RoleID: c.ID, ParentID: b.ID}}, edges)
+
+	// 上级角色不存在时整体回滚
+	assert.ErrorIs(t, rc.RoleService.SetParents(ctx, c, []uint{a.ID, 999}), gorm.ErrRecordNotFound)
+	parents, err := walkRoleParents(ctx, db, []uint{c.ID}, true)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{c.ID, b.ID, a.ID}, parents)
+}
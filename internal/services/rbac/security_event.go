@@ -0,0 +1,25 @@
+package rbac
+
+import (
+	"gin-admin/internal/model/rbac"
+	_interface "gin-admin/pkg/interface"
+	"gorm.io/gorm"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/10 上午10:05
+* @Package: SecurityEvent Service
+ */
+
+// SecurityEventService 安全事件服务
+type SecurityEventService struct {
+	_interface.Service[rbac.SecurityEvent]
+}
+
+func NewSecurityEventService(db *gorm.DB, cache _interface.ICache) *SecurityEventService {
+	return &SecurityEventService{
+		Service: *_interface.NewService[rbac.SecurityEvent](db, cache),
+	}
+}
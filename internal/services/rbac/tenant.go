@@ -0,0 +1,57 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"gin-admin/internal/model/rbac"
+	"gin-admin/pkg/components/tenant"
+	"gin-admin/pkg/consts"
+	_interface "gin-admin/pkg/interface"
+	"gorm.io/gorm"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/17 上午9:30
+* @Package: Tenant Service
+ */
+
+var ErrTenantDisabled = errors.New("租户已被禁用")
+
+// TenantService 租户服务
+type TenantService struct {
+	_interface.Service[rbac.Tenant]
+}
+
+func NewTenantService(db *gorm.DB, cache _interface.ICache) *TenantService {
+	return &TenantService{
+		Service: *_interface.NewService[rbac.Tenant](db, cache),
+	}
+}
+
+// CheckActive 租户存在且已启用，平台租户始终可用；租户不存在时返回 gorm.ErrRecordNotFound
+func (ts *TenantService) CheckActive(ctx context.Context, id uint) error {
+	if id == tenant.PlatformID {
+		return nil
+	}
+	t, err := ts.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if t.Status != consts.TenantStatusActive {
+		return ErrTenantDisabled
+	}
+	return nil
+}
+
+// IsPlatformAdmin 是否为平台超级管理员（平台租户中拥有内置角色的用户），可以管理和切换到任意租户
+// 用户只会分配到本租户的角色，限定平台租户的角色即可排除其他租户的管理员
+func (ts *TenantService) IsPlatformAdmin(ctx context.Context, userID uint) (bool, error) {
+	var count int64
+	err := ts.DB.WithContext(tenant.WithID(ctx, tenant.PlatformID)).Model(&rbac.Role{}).
+		Joins("JOIN user_roles ur ON ur.role_id = roles.id").
+		Where("ur.user_id = ? AND roles.built_in = ?", userID, true).
+		Count(&count).Error
+	return count > 0, err
+}
@@ -4,11 +4,17 @@ import (
 	"cmp"
 	"context"
 	"errors"
+	"fmt"
 	"gin-admin/internal/model/rbac"
+	"gin-admin/pkg/components/password"
+	"gin-admin/pkg/components/tenant"
+	"gin-admin/pkg/consts"
+	"gin-admin/pkg/errcode"
 	_interface "gin-admin/pkg/interface"
 	"gorm.io/gorm"
 	"maps"
 	"slices"
+	"time"
 )
 
 /*
@@ -18,6 +24,11 @@ import (
 * @Package:
  */
 
+var (
+	ErrPasswordReused = errors.New("新密码不能与最近使用过的密码相同")
+	ErrEmailTaken     = errors.New("邮箱已存在")
+)
+
 // UserService 用户可以自己实现一些定制化的函数
 type UserService struct {
 	_interface.Service[rbac.User]
@@ -43,64 +54,239 @@ func (s *UserService) CheckAccountExist(ctx context.Context, username, email str
 	return nil
 }
 
+// GetUserPerms 用户的有效权限，按权限分组；被禁止访问的资源同样列出，Denied 为 true
 func (s *UserService) GetUserPerms(ctx context.Context, userID uint) ([]rbac.Permission, error) {
-	var rows []struct {
-		PermissionID   uint   `json:"permission_id"`
-		PermissionName string `json:"permission_name"`
-		PermissionCode string `json:"permission_code"`
-		ResourceID     uint   `json:"resource_id"`
-		Path           string `json:"path"`
-		Code           string `json:"code"`
-		Method         string `json:"method"`
-		Description    string `json:"description"`
-	}
-	err := s.DB.WithContext(ctx).Raw(`
-		SELECT DISTINCT
-			p.id   AS permission_id,
-			p.name AS permission_name,
-			p.code AS permission_code,
-			res.id AS resource_id,
-			res.path,
-			res.method,
-			res.code,
-			res.description
-		FROM permissions p
-		JOIN resources res ON p.id = res.permission_id
-		JOIN role_resources rr ON res.id = rr.resource_id
-		JOIN user_roles ur ON rr.role_id = ur.role_id
-		WHERE ur.user_id = ?
-		ORDER BY p.code, res.path, res.method
-	`, userID).Scan(&rows).Error
-
+	roleIds, err := userRoleIDs(ctx, s.DB, userID)
+	if err != nil || len(roleIds) == 0 {
+		return nil, err
+	}
+	grants, err := roleGrants(ctx, s.DB, roleIds)
 	if err != nil {
 		return nil, err
 	}
-
-	permMap := make(map[uint]rbac.Permission)
-	for _, row := range rows {
-		p, ok := permMap[row.PermissionID]
+	permIds := make([]uint, 0, len(grants))
+	for _, res := range grants {
+		if res.PermissionID != nil {
+			permIds = append(permIds, *res.PermissionID)
+		}
+	}
+	if len(permIds) == 0 {
+		return nil, nil
+	}
+	var perms []rbac.Permission
+	if err = s.DB.WithContext(ctx).Select("id", "name", "code").Where("id IN ?", permIds).Find(&perms).Error; err != nil {
+		return nil, err
+	}
+	permMap := make(map[uint]rbac.Permission, len(perms))
+	for _, p := range perms {
+		permMap[p.ID] = p
+	}
+	for _, res := range grants {
+		if res.PermissionID == nil {
+			continue
+		}
+		p, ok := permMap[*res.PermissionID]
 		if !ok {
-			p = rbac.Permission{
-				BaseModel: rbac.BaseModel{
-					ID: row.PermissionID,
-				},
-				Name: row.PermissionName,
-				Code: row.PermissionCode,
-			}
-			permMap[row.PermissionID] = p
+			continue
 		}
 		p.Resources = append(p.Resources, rbac.Resource{
 			BaseModel: rbac.BaseModel{
-				ID: row.PermissionID,
+				ID: res.ID,
 			},
-			Path:        row.Path,
-			Method:      row.Method,
-			Code:        row.Code,
-			Description: row.Description,
+			Path:        res.Path,
+			Method:      res.Method,
+			Code:        res.Code,
+			Description: res.Description,
+			Condition:   res.Condition,
+			Denied:      res.Denied,
 		})
-		permMap[row.PermissionID] = p
+		permMap[p.ID] = p
 	}
 	return slices.SortedFunc(maps.Values(permMap), func(permission rbac.Permission, permission2 rbac.Permission) int {
 		return cmp.Compare(permission.ID, permission2.ID)
 	}), nil
 }
+
+// SetPassword 设置新密码，不能与最近 keep 次使用过的密码（含当前密码）相同，keep 为 0 时不检查
+func (s *UserService) SetPassword(ctx context.Context, userID uint, plain string, keep int) error {
+	if err := SetPasswordTx(s.DB.WithContext(ctx), userID, plain, keep); err != nil {
+		return err
+	}
+	return s.ClearCache(ctx)
+}
+
+// SetPasswordTx 在调用方的事务中设置新密码，事务提交后由调用方清除用户缓存
+func SetPasswordTx(tx *gorm.DB, userID uint, plain string, keep int) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		if keep > 0 {
+			reused, err := passwordReused(tx, userID, plain, keep)
+			if err != nil {
+				return err
+			}
+			if reused {
+				return ErrPasswordReused
+			}
+		}
+		hashed, err := password.Hash(plain)
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&rbac.User{}).Where("id = ?", userID).
+			Updates(map[string]interface{}{"password": hashed, "password_changed_at": time.Now()}).Error
+		if err != nil {
+			return err
+		}
+		if err = tx.Create(&rbac.PasswordHistory{UserID: userID, Password: hashed}).Error; err != nil {
+			return err
+		}
+		// 只保留需要比对的历史密码
+		if keep < 1 {
+			keep = 1
+		}
+		var expired []uint
+		err = tx.Model(&rbac.PasswordHistory{}).Where("user_id = ?", userID).
+			Order("id DESC").Offset(keep).Pluck("id", &expired).Error
+		if err != nil || len(expired) == 0 {
+			return err
+		}
+		return tx.Delete(&rbac.PasswordHistory{}, expired).Error
+	})
+}
+
+// passwordReused 新密码是否与当前密码或最近 keep 次的历史密码相同
+func passwordReused(tx *gorm.DB, userID uint, plain string, keep int) (bool, error) {
+	var current string
+	if err := tx.Model(&rbac.User{}).Where("id = ?", userID).Pluck("password", &current).Error; err != nil {
+		return false, err
+	}
+	var history []string
+	err := tx.Model(&rbac.PasswordHistory{}).Where("user_id = ?", userID).
+		Order("id DESC").Limit(keep).Pluck("password", &history).Error
+	if err != nil {
+		return false, err
+	}
+	for _, hashed := range append(history, current) {
+		if ok, _, _ := password.Verify(hashed, plain); ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RehashPassword 登录成功后用当前的哈希参数重新计算密码哈希
+// 只在哈希未被其他请求修改时更新，避免覆盖并发的改密操作
+func (s *UserService) RehashPassword(ctx context.Context, user *rbac.User, plain string) error {
+	hashed, err := password.Hash(plain)
+	if err != nil {
+		return err
+	}
+	err = s.DB.WithContext(ctx).Model(&rbac.User{}).
+		Where("id = ? AND password = ?", user.ID, user.Password).
+		Update("password", hashed).Error
+	if err != nil {
+		return err
+	}
+	user.Password = hashed
+	return s.ClearCache(ctx)
+}
+
+// MarkEmailVerified 邮箱验证通过，待验证的用户同时启用
+// 只在邮箱未被修改时生效，旧邮箱的验证链接不能验证新邮箱
+func (s *UserService) MarkEmailVerified(ctx context.Context, userID uint, email string) (bool, error) {
+	result := s.DB.WithContext(ctx).Model(&rbac.User{}).
+		Where("id = ? AND email = ?", userID, email).
+		Updates(map[string]interface{}{
+			"email_verified_at": time.Now(),
+			"status":            gorm.Expr("CASE WHEN status = ? THEN ? ELSE status END", consts.UserStatusPending, consts.UserStatusActive),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	return true, s.ClearCache(ctx)
+}
+
+// ApplyPendingEmail 新邮箱验证通过，替换原邮箱并标记已验证
+// 只在待验证邮箱未被再次修改时生效，返回是否替换成功
+func (s *UserService) ApplyPendingEmail(ctx context.Context, userID uint, email string) (bool, error) {
+	exist, err := s.Exists(ctx, _interface.WithScopes(func(db *gorm.DB) *gorm.DB {
+		return db.Where("email = ? AND id <> ?", email, userID)
+	}))
+	if err != nil {
+		return false, err
+	}
+	if exist {
+		return false, ErrEmailTaken
+	}
+	result := s.DB.WithContext(ctx).Model(&rbac.User{}).
+		Where("id = ? AND pending_email = ?", userID, email).
+		Updates(map[string]interface{}{
+			"email":             email,
+			"pending_email":     "",
+			"email_verified_at": time.Now(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	return true, s.ClearCache(ctx)
+}
+
+// LockUser 锁定用户（异常登录或安全封禁），需管理员解锁
+// 同时清除之前临时锁定留下的到期时间，否则旧的到期时间一过即视为已解锁
+func (s *UserService) LockUser(ctx context.Context, userID uint) error {
+	return s.UpdateByID(ctx, userID, map[string]interface{}{"status": consts.UserStatusLocked, "locked_until": nil})
+}
+
+// LockUserUntil 临时锁定用户，到期后自动解锁
+func (s *UserService) LockUserUntil(ctx context.Context, userID uint, until time.Time) error {
+	return s.UpdateByID(ctx, userID, map[string]interface{}{"status": consts.UserStatusLocked, "locked_until": until})
+}
+
+// UnlockUser 解除锁定
+func (s *UserService) UnlockUser(ctx context.Context, userID uint) error {
+	return s.UpdateByID(ctx, userID, map[string]interface{}{"status": consts.UserStatusActive, "locked_until": nil})
+}
+
+// TenantContext 跨租户查找用户所属的租户，返回进入该租户的上下文
+// 用于邮件中的链接等只凭令牌确定用户、请求不携带租户的场景
+func (s *UserService) TenantContext(ctx context.Context, userID uint) (context.Context, error) {
+	var user rbac.User
+	err := s.DB.WithContext(tenant.SkipScope(ctx)).Select("id", "tenant_id").Take(&user, userID).Error
+	if err != nil {
+		return ctx, err
+	}
+	return tenant.WithID(ctx, user.TenantID), nil
+}
+
+// CheckUserStatus 检查用户是否允许访问，禁用或锁定时返回对应错误码
+func (s *UserService) CheckUserStatus(ctx context.Context, userID uint) error {
+	user, err := s.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if e := UserStatusError(user, time.Now()); e != nil {
+		return e
+	}
+	return nil
+}
+
+// UserStatusError 待验证邮箱、禁用或锁定中的用户返回对应错误码，否则返回 nil
+func UserStatusError(user *rbac.User, now time.Time) *errcode.Error {
+	switch {
+	case user.Status == consts.UserStatusPending:
+		return errcode.New(errcode.EmailNotVerified, errcode.GetMessage(errcode.EmailNotVerified))
+	case user.Status == consts.UserStatusDisabled:
+		return errcode.New(errcode.UserDisabled, errcode.GetMessage(errcode.UserDisabled))
+	case user.Locked(now):
+		if user.LockedUntil != nil {
+			return errcode.New(errcode.UserLocked, fmt.Sprintf("账号已被锁定，请于 %s 后重试", user.LockedUntil.Format("2006-01-02 15:04:05")))
+		}
+		return errcode.New(errcode.UserLocked, errcode.GetMessage(errcode.UserLocked))
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"gin-admin/internal/model/rbac"
+	_interface "gin-admin/pkg/interface"
+	"gorm.io/gorm"
+	"time"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/12 下午3:10
+* @Package: 第三方账号关联
+ */
+
+// UserIdentityService 第三方账号关联服务
+type UserIdentityService struct {
+	_interface.Service[rbac.UserIdentity]
+}
+
+func NewUserIdentityService(db *gorm.DB, cache _interface.ICache) *UserIdentityService {
+	return &UserIdentityService{
+		Service: *_interface.NewService[rbac.UserIdentity](db, cache),
+	}
+}
+
+// FindBySubject 按提供方和 subject 查找关联，不存在时返回 nil
+func (s *UserIdentityService) FindBySubject(ctx context.Context, provider, subject string) (*rbac.UserIdentity, error) {
+	var identity rbac.UserIdentity
+	err := s.DB.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).Take(&identity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// TouchLogin 记录通过第三方登录的时间，同步提供方最新的邮箱
+func (s *UserIdentityService) TouchLogin(ctx context.Context, id uint, email string) error {
+	return s.UpdateByID(ctx, id, map[string]interface{}{"email": email, "last_login_at": time.Now()})
+}
@@ -0,0 +1,36 @@
+package rbac
+
+import (
+	"context"
+	"gin-admin/pkg/consts"
+	"gin-admin/pkg/errcode"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockUser(t *testing.T) {
+	rc, _ := newTestContext(t)
+	ctx := context.Background()
+	user := createTestUserWithRoles(t, rc, "alice")
+
+	// 曾被临时锁定过，到期时间早已过去
+	require.NoError(t, rc.UserService.LockUserUntil(ctx, user.ID, time.Now().Add(-time.Hour)))
+	require.NoError(t, rc.UserService.CheckUserStatus(ctx, user.ID))
+
+	// 安全封禁不受旧的到期时间影响，需管理员解锁
+	require.NoError(t, rc.UserService.LockUser(ctx, user.ID))
+	locked, err := rc.UserService.FindByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, consts.UserStatusLocked, locked.Status)
+	assert.Nil(t, locked.LockedUntil)
+	assert.True(t, locked.Locked(time.Now().Add(24*time.Hour)))
+	var e *errcode.Error
+	require.ErrorAs(t, rc.UserService.CheckUserStatus(ctx, user.ID), &e)
+	assert.Equal(t, errcode.UserLocked, e.Code)
+
+	require.NoError(t, rc.UserService.UnlockUser(ctx, user.ID))
+	assert.NoError(t, rc.UserService.CheckUserStatus(ctx, user.ID))
+}
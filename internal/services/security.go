@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"gin-admin/internal/model/rbac"
+	"gin-admin/pkg/components/jwt"
+	"gin-admin/pkg/components/tenant"
+	"gin-admin/pkg/consts"
+	"github.com/sirupsen/logrus"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/10 上午10:05
+* @Package: 安全事件处理
+ */
+
+// onRefreshTokenReuse 处理 refresh token 重用：按配置锁定账号，并持久化安全事件
+// jwt 组件已按处置级别撤销了会话，这里只负责 jwt 组件无法完成的部分
+func (s *ServiceContext) onRefreshTokenReuse(ctx context.Context, e jwt.ReuseEvent) {
+	logrus.Warnf("refresh token reuse detected: user=%d session=%s ip=%s action=%s", e.UserID, e.SessionID, e.IP, e.Response)
+	// 刷新接口不携带用户所属租户，按令牌中的租户锁定账号和记录事件
+	ctx = tenant.WithID(ctx, e.TenantID)
+	if e.Response == jwt.ReuseLockUser {
+		if err := s.Rbac.UserService.LockUser(ctx, e.UserID); err != nil {
+			logrus.Errorf("failed to lock user %d after refresh token reuse :%s", e.UserID, err.Error())
+		}
+	}
+	s.saveSecurityEvent(ctx, &rbac.SecurityEvent{
+		Type:      consts.SecurityEventRefreshTokenReuse,
+		UserID:    e.UserID,
+		Username:  e.Username,
+		SessionID: e.SessionID,
+		DeviceID:  e.DeviceID,
+		IP:        e.IP,
+		UserAgent: e.UserAgent,
+		Action:    e.Response,
+	})
+}
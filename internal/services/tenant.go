@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+	"gin-admin/internal/model/rbac"
+	"gin-admin/pkg/components/tenant"
+	"gin-admin/pkg/consts"
+	"gorm.io/gorm"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/17 上午9:30
+* @Package: 租户开通
+ */
+
+// TenantAdmin 新租户的管理员账号
+type TenantAdmin struct {
+	Username string
+	Email    string
+	Password string
+}
+
+// CreateTenant 创建租户，并在租户内创建超级管理员角色（绑定所有资源）和管理员账号，任一步失败整体回滚
+func (s *ServiceContext) CreateTenant(ctx context.Context, t *rbac.Tenant, admin TenantAdmin) error {
+	if t.Status == consts.TenantStatusUnknown {
+		t.Status = consts.TenantStatusActive
+	}
+	err := s.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(t).Error; err != nil {
+			return err
+		}
+		return NewRbacService().InitializeAdmin(tx.WithContext(tenant.WithID(ctx, t.ID)), &RBACInitConfig{
+			AdminUsername: admin.Username,
+			AdminPassword: admin.Password,
+			AdminEmail:    admin.Email,
+			AdminRoleName: s.Config.RBAC.AdminRole.Name,
+			AdminRoleDesc: s.Config.RBAC.AdminRole.Description,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return s.Rbac.TenantService.ClearCache(ctx)
+}
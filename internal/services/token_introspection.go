@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"gin-admin/pkg/components/jwt"
+	"strings"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/13 下午3:40
+* @Package: 令牌内省（RFC 7662）与撤销（RFC 7009）
+ */
+
+// token_type_hint 取值
+const (
+	TokenTypeHintAccess  = "access_token"
+	TokenTypeHintRefresh = "refresh_token"
+)
+
+// AuthenticateClient 校验内省 / 撤销接口的客户端凭证，比较时间与是否命中无关
+func (s *ServiceContext) AuthenticateClient(clientID, clientSecret string) bool {
+	if clientID == "" || clientSecret == "" {
+		return false
+	}
+	id, secret := sha256.Sum256([]byte(clientID)), sha256.Sum256([]byte(clientSecret))
+	matched := 0
+	for _, client := range s.Config.Security.TokenClients {
+		wantID, wantSecret := sha256.Sum256([]byte(client.ClientID)), sha256.Sum256([]byte(client.ClientSecret))
+		matched |= subtle.ConstantTimeCompare(id[:], wantID[:]) & subtle.ConstantTimeCompare(secret[:], wantSecret[:])
+	}
+	return matched == 1
+}
+
+// ParseToken 解析本系统签发的 access token 或 refresh token，按 hint 决定尝试顺序
+// 返回的令牌均已校验签名、有效期和会话状态；内省不是令牌持有人的活动，不更新会话活跃时间
+func (s *ServiceContext) ParseToken(ctx context.Context, token, hint string) (*jwt.CustomClaims, error) {
+	parsers := []func(context.Context, string) (*jwt.CustomClaims, error){s.Jwt.InspectAccessToken, s.Jwt.ParseRefreshToken}
+	if hint == TokenTypeHintRefresh {
+		parsers[0], parsers[1] = parsers[1], parsers[0]
+	}
+	var err error
+	for _, parse := range parsers {
+		var claims *jwt.CustomClaims
+		if claims, err = parse(ctx, token); err == nil && claims != nil {
+			return claims, nil
+		}
+	}
+	if err == nil {
+		err = jwt.ErrInvalidToken
+	}
+	return nil, err
+}
+
+// TokenScope 令牌持有人可访问的资源 Code，空格分隔
+func (s *ServiceContext) TokenScope(ctx context.Context, userID uint) (string, error) {
+	resources, err := s.Rbac.ResourceService.GetUserResources(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	codes := make([]string, 0, len(resources))
+	for _, res := range resources {
+		if res.Code != "" {
+			codes = append(codes, res.Code)
+		}
+	}
+	return strings.Join(codes, " "), nil
+}
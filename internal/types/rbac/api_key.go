@@ -0,0 +1,26 @@
+package rbac
+
+import (
+	"gin-admin/internal/model/rbac"
+	"time"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/13 上午10:00
+* @Package:
+ */
+
+// CreateAPIKeyRequest 创建 API Key
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=50" example:"ci-deploy" description:"名称"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,required" example:"user:manage:list" description:"可访问的资源 Code，只能选择自己拥有的资源"`
+	ExpiresAt *time.Time `json:"expires_at" example:"2026-01-01T00:00:00Z" description:"过期时间，不传表示永不过期"`
+}
+
+// APIKeyCreatedResponse 创建成功后返回明文 Key，只返回这一次
+type APIKeyCreatedResponse struct {
+	rbac.APIKey
+	Key string `json:"key" example:"ga_3f9c2a1b_xxxxxxxx" description:"API Key 明文，请妥善保存，之后无法再次查看"`
+}
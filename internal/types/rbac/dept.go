@@ -0,0 +1,32 @@
+package rbac
+
+import "gin-admin/pkg/consts"
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/16 上午10:20
+* @Package:
+ */
+
+type ListDeptRequest struct {
+	Name     string `form:"name,optional" json:"name" binding:"-" example:"研发部"`
+	Status   uint8  `form:"status,optional" json:"status" binding:"-" example:"1"`
+	Page     int    `form:"page,default=1" json:"page" binding:"required" example:"1" default:"1"`
+	PageSize int    `form:"pageSize,default=10" json:"pageSize" binding:"required" example:"10" default:"10"`
+}
+
+// UpsertDeptRequest 创建、编辑部门
+type UpsertDeptRequest struct {
+	Name     string            `json:"name" binding:"required,max=50" example:"研发部" description:"部门名称"`
+	ParentID uint              `json:"parent_id" example:"0" description:"上级部门ID，0 表示顶级部门"`
+	Sort     int               `json:"sort" example:"0" description:"排序，同级部门按从小到大排列"`
+	LeaderID *uint             `json:"leader_id" example:"1" description:"负责人用户ID，不传表示没有负责人"`
+	Status   consts.DeptStatus `json:"status" binding:"omitempty,oneof=1 2" example:"1" description:"部门状态（1:启用 2:禁用），创建时不传默认启用，编辑时不传不修改"`
+}
+
+// MoveDeptRequest 移动部门，下级部门随之移动
+type MoveDeptRequest struct {
+	ParentID uint `json:"parent_id" example:"0" description:"新的上级部门ID，0 表示移动为顶级部门"`
+	Sort     int  `json:"sort" example:"0" description:"在新的上级部门下的排序"`
+}
@@ -0,0 +1,23 @@
+package rbac
+
+import "gin-admin/pkg/consts"
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/18 上午10:00
+* @Package:
+ */
+
+// UpsertMenuRequest 创建、编辑菜单
+type UpsertMenuRequest struct {
+	Name       string            `json:"name" binding:"required,max=50" example:"用户管理" description:"菜单名称"`
+	ParentID   uint              `json:"parent_id" example:"0" description:"上级菜单ID，0 表示顶级菜单"`
+	Icon       string            `json:"icon" binding:"max=100" example:"user" description:"图标"`
+	Path       string            `json:"path" binding:"max=200" example:"/system/users" description:"前端路由地址"`
+	Component  string            `json:"component" binding:"max=200" example:"system/user/index" description:"前端组件路径，目录不填"`
+	Sort       int               `json:"sort" example:"0" description:"排序，同级菜单按从小到大排列"`
+	Hidden     bool              `json:"hidden" example:"false" description:"不在导航中显示，前端仍注册路由"`
+	Permission string            `json:"permission" binding:"max=100" example:"user:manage" description:"所需权限，权限分组编码或资源编码，不填表示登录即可见"`
+	Status     consts.MenuStatus `json:"status" binding:"omitempty,oneof=1 2" example:"1" description:"菜单状态（1:启用 2:禁用），创建时不传默认启用，编辑时不传不修改"`
+}
@@ -0,0 +1,56 @@
+package rbac
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/11 下午2:30
+* @Package:
+ */
+
+// MFAChallengeResponse 登录需要两步验证时返回的凭证
+type MFAChallengeResponse struct {
+	MFARequired   bool   `json:"mfa_required" example:"true" description:"需要两步验证，此时不会返回令牌"`
+	SetupRequired bool   `json:"setup_required" example:"false" description:"角色要求开启两步验证但尚未绑定验证器，需先调用 /users/login/mfa/setup"`
+	MFAToken      string `json:"mfa_token" description:"两步验证凭证"`
+	ExpiresIn     int64  `json:"expires_in" example:"300" description:"凭证有效期（秒）"`
+}
+
+// MFALoginRequest 使用两步验证凭证换取令牌
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required" description:"登录返回的两步验证凭证"`
+	Code     string `json:"code" binding:"required" example:"123456" description:"验证器口令或恢复码"`
+}
+
+// MFALoginSetupRequest 登录过程中绑定验证器
+type MFALoginSetupRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required" description:"登录返回的两步验证凭证"`
+}
+
+// MFALoginResponse 两步验证通过后的令牌，首次绑定时附带恢复码
+type MFALoginResponse struct {
+	TokenResponse
+	RecoveryCodes []string `json:"recovery_codes,omitempty" description:"恢复码，仅首次绑定时返回"`
+}
+
+// MFACodeRequest 需要验证口令的操作
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required" example:"123456" description:"验证器口令（关闭、重新生成恢复码时也可使用恢复码）"`
+}
+
+// MFASetupResponse 待绑定的密钥
+type MFASetupResponse struct {
+	Secret     string `json:"secret" example:"JBSWY3DPEHPK3PXP" description:"base32 密钥，无法扫码时手动输入"`
+	OtpauthURI string `json:"otpauth_uri" example:"otpauth://totp/gin-admin:johndoe?secret=JBSWY3DPEHPK3PXP&issuer=gin-admin" description:"二维码内容"`
+}
+
+// MFAStatusResponse 两步验证状态
+type MFAStatusResponse struct {
+	Enabled                bool  `json:"enabled" description:"是否已开启"`
+	Required               bool  `json:"required" description:"所属角色是否要求开启"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining" description:"剩余可用恢复码数量"`
+}
+
+// MFARecoveryCodesResponse 恢复码明文，只在生成时返回一次
+type MFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
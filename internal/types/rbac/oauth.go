@@ -0,0 +1,60 @@
+package rbac
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/12 下午3:10
+* @Package:
+ */
+
+// OAuthProviderResponse 可用的第三方登录方式
+type OAuthProviderResponse struct {
+	Name        string `json:"name" example:"google" description:"提供方标识，用于拼接登录地址"`
+	DisplayName string `json:"display_name" example:"Google" description:"展示名称"`
+}
+
+// OAuthLoginURLResponse 第三方登录授权地址
+type OAuthLoginURLResponse struct {
+	AuthURL string `json:"auth_url" description:"跳转到提供方的授权地址"`
+}
+
+// OAuthCallbackRequest 提供方回调参数
+type OAuthCallbackRequest struct {
+	Code             string `form:"code"`
+	State            string `form:"state" binding:"required"`
+	Error            string `form:"error"`
+	ErrorDescription string `form:"error_description"`
+}
+
+// TokenIntrospectionRequest 令牌内省 / 撤销请求（application/x-www-form-urlencoded）
+type TokenIntrospectionRequest struct {
+	Token         string `form:"token" binding:"required" description:"待检查或撤销的令牌"`
+	TokenTypeHint string `form:"token_type_hint" example:"access_token" description:"令牌类型提示：access_token | refresh_token"`
+}
+
+// TokenIntrospectionResponse 令牌内省结果（RFC 7662），令牌无效时只返回 active=false
+type TokenIntrospectionResponse struct {
+	Active    bool                `json:"active"`
+	Scope     string              `json:"scope,omitempty" example:"user:manage:list user:manage:profile" description:"令牌持有人可访问的资源 Code，空格分隔"`
+	Username  string              `json:"username,omitempty" example:"johndoe"`
+	TokenType string              `json:"token_type,omitempty" example:"access_token"`
+	Exp       int64               `json:"exp,omitempty" example:"1735660800"`
+	Iat       int64               `json:"iat,omitempty" example:"1735657200"`
+	Sub       string              `json:"sub,omitempty" example:"1"`
+	Iss       string              `json:"iss,omitempty" example:"gin-admin"`
+	Jti       string              `json:"jti,omitempty"`
+	Sid       string              `json:"sid,omitempty" description:"会话ID"`
+	TenantID  uint                `json:"tenant_id,omitempty" example:"1" description:"用户所属租户，平台租户不返回"`
+	Act       *TokenActorResponse `json:"act,omitempty" description:"模拟登录时的实际操作人（RFC 8693）"`
+}
+
+// TokenActorResponse 模拟登录的实际操作人
+type TokenActorResponse struct {
+	Sub string `json:"sub" example:"1" description:"模拟登录的管理员ID"`
+}
+
+// OAuthErrorResponse OAuth 标准错误响应（RFC 6749 5.2）
+type OAuthErrorResponse struct {
+	Error            string `json:"error" example:"invalid_client"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
@@ -20,6 +20,10 @@ type UpsertRoleRequest struct {
 	Name        string            `json:"name,omitempty" example:"管理员"`
 	Description string            `json:"description,omitempty" example:"系统管理员"`
 	Status      consts.RoleStatus `json:"status" example:"1"`
+	RequireMFA  bool              `json:"require_mfa" example:"false" description:"该角色的用户必须开启两步验证"`
+	// 不传时创建为全部数据，编辑时不修改
+	DataScope        consts.DataScope `json:"data_scope" binding:"omitempty,oneof=1 2 3 4 5" example:"1" description:"数据权限（1:全部 2:自定义部门 3:本部门及下级 4:本部门 5:仅本人）"`
+	DataScopeDeptIDs []uint           `json:"data_scope_dept_ids" binding:"required_if=DataScope 2" description:"自定义数据权限的部门ID"`
 }
 
 type RoleOptions struct {
@@ -29,4 +33,16 @@ type RoleOptions struct {
 
 type AssignResource struct {
 	ResourceIds []uint `json:"resource_ids"`
+	// 键为资源ID，只能是 resource_ids 中的资源，不在其中的资源无条件授权
+	Conditions map[uint]string `json:"conditions" description:"资源的授权条件表达式，如 cidr(ip, \"10.0.0.0/8\")"`
+}
+
+// AssignDeny 设置禁止访问的资源，传空数组表示取消全部禁止
+type AssignDeny struct {
+	ResourceIds []uint `json:"resource_ids" description:"禁止访问的资源ID"`
+}
+
+// AssignParent 设置上级角色，传空数组表示取消继承
+type AssignParent struct {
+	ParentIds []uint `json:"parent_ids" description:"上级角色ID"`
 }
@@ -0,0 +1,18 @@
+package rbac
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/10 上午10:05
+* @Package:
+ */
+
+// ListSecurityEventRequest 安全事件查询参数
+type ListSecurityEventRequest struct {
+	UserID uint   `form:"user_id,optional" json:"user_id" binding:"-" example:"1" description:"用户ID"`
+	Type   string `form:"type,optional" json:"type" binding:"-" example:"refresh_token_reuse" description:"事件类型"`
+	// 查询某个管理员模拟登录期间的操作
+	ImpersonatorID uint `form:"impersonator_id,optional" json:"impersonator_id" binding:"-" example:"1" description:"模拟登录的管理员ID"`
+	Page           int  `form:"page,default=1" json:"page" binding:"required" example:"1" default:"1"`
+	PageSize       int  `form:"pageSize,default=10" json:"pageSize" binding:"required" example:"10" default:"10"`
+}
@@ -0,0 +1,24 @@
+package rbac
+
+import "time"
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/8 下午3:20
+* @Package:
+ */
+
+// SessionItem 在线会话（设备）信息
+type SessionItem struct {
+	SessionID  string    `json:"session_id" example:"6f1c9a2e-..." description:"会话ID"`
+	DeviceID   string    `json:"device_id" example:"web-chrome" description:"设备ID"`
+	IP         string    `json:"ip" example:"127.0.0.1" description:"登录/最近刷新IP"`
+	UserAgent  string    `json:"user_agent" description:"客户端 User-Agent"`
+	CreatedAt  time.Time `json:"created_at" description:"登录时间"`
+	LastSeenAt time.Time `json:"last_seen_at" description:"最近活跃时间"`
+	ExpiresAt  time.Time `json:"expires_at" description:"会话过期时间"`
+	Current    bool      `json:"current" description:"是否为当前请求所在会话"`
+	// 管理员模拟登录产生的会话，用户可以在设备列表中看到并踢下线
+	ImpersonatorID uint `json:"impersonator_id,omitempty" example:"1" description:"模拟登录的管理员ID"`
+}
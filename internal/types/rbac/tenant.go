@@ -0,0 +1,33 @@
+package rbac
+
+import "gin-admin/pkg/consts"
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/17 上午9:30
+* @Package:
+ */
+
+type ListTenantRequest struct {
+	Name     string `form:"name,optional" json:"name" binding:"-" example:"示例公司"`
+	Code     string `form:"code,optional" json:"code" binding:"-" example:"acme"`
+	Status   uint8  `form:"status,optional" json:"status" binding:"-" example:"1"`
+	Page     int    `form:"page,default=1" json:"page" binding:"required" example:"1" default:"1"`
+	PageSize int    `form:"pageSize,default=10" json:"pageSize" binding:"required" example:"10" default:"10"`
+}
+
+// CreateTenantRequest 创建租户，同时创建租户的超级管理员角色和管理员账号
+type CreateTenantRequest struct {
+	Name          string `json:"name" binding:"required,max=100" example:"示例公司" description:"租户名称"`
+	Code          string `json:"code" binding:"required,max=50,alphanum" example:"acme" description:"租户编码，全局唯一"`
+	AdminUsername string `json:"admin_username" binding:"required,max=50" example:"admin" description:"租户管理员用户名"`
+	AdminEmail    string `json:"admin_email" binding:"required,email" example:"admin@acme.com" description:"租户管理员邮箱"`
+	AdminPassword string `json:"admin_password" binding:"required,password" example:"Correct#Horse9" description:"租户管理员密码，需符合密码策略"`
+}
+
+// UpdateTenantRequest 修改租户名称和状态，禁用后租户下的所有账号都无法访问
+type UpdateTenantRequest struct {
+	Name   string              `json:"name" binding:"omitempty,max=100" example:"示例公司" description:"租户名称，不传不修改"`
+	Status consts.TenantStatus `json:"status" binding:"omitempty,oneof=1 2" example:"1" description:"租户状态（1:启用 2:禁用），不传不修改"`
+}
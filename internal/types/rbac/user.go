@@ -2,7 +2,9 @@ package rbac
 
 import (
 	"gin-admin/internal/model/rbac"
+	"gin-admin/pkg/components/uploader"
 	"gin-admin/pkg/consts"
+	"time"
 )
 
 /*
@@ -15,7 +17,7 @@ import (
 // UserRegisterRequest 用户注册请求
 type UserRegisterRequest struct {
 	Username string `json:"username" binding:"required" example:"johndoe" description:"用户名"`
-	Password string `json:"password" binding:"required" example:"password123" description:"密码"`
+	Password string `json:"password" binding:"required,password" example:"Correct#Horse9" description:"密码，需符合密码策略"`
 	Email    string `json:"email" binding:"required,email" example:"john@example.com" description:"邮箱"`
 }
 
@@ -44,6 +46,7 @@ type ListUserRequest struct {
 	Email    string `form:"email,optional" json:"email" binding:"-" example:"john@example.com"`
 	Status   uint8  `form:"status,optional" json:"status" binding:"-" example:"1"`
 	Gender   uint8  `form:"gender,optional" json:"gender" binding:"-" example:"1"`
+	DeptID   uint   `form:"dept_id,optional" json:"dept_id" binding:"-" example:"1" description:"所属部门，包含下级部门的用户"`
 	Page     int    `form:"page,default=1" json:"page" binding:"required" example:"1" default:"1"`
 	PageSize int    `form:"pageSize,default=10" json:"pageSize" binding:"required" example:"10" default:"10"`
 }
@@ -58,6 +61,9 @@ type UpsertUserRequest struct {
 	Email    string        `json:"email" binding:"required" example:"john@example.com"`
 	Gender   consts.Gender `json:"gender" binding:"required" example:"1"`
 	Roles    []uint        `json:"roles" binding:"required"`
+	DeptID   uint          `json:"dept_id" example:"1" description:"所属部门ID，0 表示未分配"`
+	// 创建时必填；编辑时不传则不修改
+	Password string `json:"password" binding:"omitempty,password" example:"Correct#Horse9" description:"密码，需符合密码策略"`
 }
 
 type OptionParams struct {
@@ -68,3 +74,82 @@ type UserOptions struct {
 	Status            []Option            `json:"status"`
 	SupplementOptions map[string][]Option `json:"supplement_options"`
 }
+
+// ForgotPasswordRequest 找回密码
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email" example:"john@example.com" description:"注册邮箱"`
+}
+
+// ChangePasswordRequest 修改自己的密码
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required" description:"原密码"`
+	Password    string `json:"password" binding:"required,password" example:"Correct#Horse9" description:"新密码，需符合密码策略"`
+}
+
+// UpdateProfileRequest 修改自己的资料
+type UpdateProfileRequest struct {
+	Gender consts.Gender `json:"gender" binding:"oneof=0 1 2" example:"1" description:"性别"`
+	Email  string        `json:"email" binding:"required,email" example:"john@example.com" description:"邮箱，修改后新邮箱验证通过才生效"`
+	// 修改邮箱时必填
+	Password string `json:"password" description:"当前密码"`
+}
+
+// AvatarResponse 上传头像
+type AvatarResponse struct {
+	Avatar uploader.FilePath `json:"avatar" description:"头像URL"`
+}
+
+// VerifyEmailRequest 验证邮箱
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required" description:"验证邮件中的令牌"`
+}
+
+// ResendVerificationRequest 重新发送验证邮件
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email" example:"john@example.com" description:"注册邮箱"`
+}
+
+// ResetPasswordRequest 重置密码
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required" description:"重置邮件中的令牌"`
+	Password string `json:"password" binding:"required,password" example:"Correct#Horse9" description:"新密码，需符合密码策略"`
+}
+
+// PasswordPolicyResponse 密码策略
+type PasswordPolicyResponse struct {
+	MinLength     int  `json:"min_length" example:"8" description:"最小长度"`
+	MaxLength     int  `json:"max_length" example:"64" description:"最大长度"`
+	RequireUpper  bool `json:"require_upper" description:"需包含大写字母"`
+	RequireLower  bool `json:"require_lower" description:"需包含小写字母"`
+	RequireDigit  bool `json:"require_digit" description:"需包含数字"`
+	RequireSymbol bool `json:"require_symbol" description:"需包含特殊字符"`
+	HistorySize   int  `json:"history_size" example:"5" description:"不能与最近 N 次使用过的密码相同，0 表示不限制"`
+	MaxAgeDays    int  `json:"max_age_days" example:"90" description:"密码有效期（天），0 表示永不过期"`
+}
+
+// PasswordExpiredResponse 密码已过期时登录返回的改密凭证
+type PasswordExpiredResponse struct {
+	PasswordExpired bool   `json:"password_expired" example:"true" description:"密码已过期，此时不会返回令牌，需先调用 /users/password/expired 修改密码"`
+	ChangeToken     string `json:"change_token" description:"改密凭证"`
+	ExpiresIn       int64  `json:"expires_in" example:"600" description:"凭证有效期（秒）"`
+}
+
+// ChangeExpiredPasswordRequest 使用改密凭证修改过期密码
+type ChangeExpiredPasswordRequest struct {
+	ChangeToken string `json:"change_token" binding:"required" description:"登录返回的改密凭证"`
+	Password    string `json:"password" binding:"required,password" example:"Correct#Horse9" description:"新密码，需符合密码策略"`
+}
+
+// RefreshTokenRequest 刷新令牌请求
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" description:"刷新令牌，不传时使用 Cookie 中的刷新令牌"`
+}
+
+// ImpersonationResponse 模拟登录响应
+type ImpersonationResponse struct {
+	TokenResponse
+	UserID           uint      `json:"user_id" example:"2" description:"被模拟的用户ID"`
+	Username         string    `json:"username" example:"johndoe" description:"被模拟的用户名"`
+	ImpersonatorID   uint      `json:"impersonator_id" example:"1" description:"模拟登录的管理员ID"`
+	SessionExpiresAt time.Time `json:"session_expires_at" description:"模拟会话的结束时间，到期后不能刷新"`
+}
@@ -25,3 +25,9 @@ func NewCache(client *redis.Client) _interface.ICache {
 	}
 	return cache
 }
+
+// IsShared 缓存是否在多个实例之间共享（Redis），内存缓存只在当前进程内有效
+func IsShared(c _interface.ICache) bool {
+	_, ok := c.(*redisCache)
+	return ok
+}
@@ -85,6 +85,29 @@ func (m *memoryCache) Set(ctx context.Context, key string, value interface{}, tt
 	return nil
 }
 
+// SetNX 键不存在或已过期时设置缓存
+func (m *memoryCache) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if item, exists := m.data[key]; exists && (item.expireAt.IsZero() || time.Now().Before(item.expireAt)) {
+		return false, nil
+	}
+	item := &memoryCacheItem{
+		value: data,
+	}
+	if ttl > 0 {
+		item.expireAt = time.Now().Add(ttl)
+	}
+	m.data[key] = item
+	return true, nil
+}
+
 // Delete 删除缓存
 func (m *memoryCache) Delete(ctx context.Context, keys ...string) error {
 	m.mu.Lock()
@@ -48,6 +48,15 @@ func (r *redisCache) Set(ctx context.Context, key string, value interface{}, ttl
 	return r.client.Set(ctx, key, data, ttl).Err()
 }
 
+// SetNX 键不存在时设置缓存
+func (r *redisCache) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, fmt.Errorf("序列化失败: %w", err)
+	}
+	return r.client.SetNX(ctx, key, data, ttl).Result()
+}
+
 // Delete 删除缓存
 func (r *redisCache) Delete(ctx context.Context, keys ...string) error {
 	return r.client.Del(ctx, keys...).Err()
@@ -185,6 +185,27 @@ func (c *shardedMemoryCache) Set(ctx context.Context, key string, value interfac
 	return nil
 }
 
+// SetNX 键不存在或已过期时设置缓存
+func (c *shardedMemoryCache) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	shard := c.getShard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	h := fastHash(key)
+	if item, exists := shard.data[h]; exists && item.key == key {
+		if expireAt := atomic.LoadInt64(&item.expireAt); expireAt == 0 || time.Now().UnixNano() <= expireAt {
+			return false, nil
+		}
+	}
+	item := &shardCacheItem{key: key}
+	item.value.Store(value)
+	if ttl > 0 {
+		item.expireAt = time.Now().Add(ttl).UnixNano()
+	}
+	shard.data[h] = item
+	return true, nil
+}
+
 // Delete 删除缓存
 func (c *shardedMemoryCache) Delete(ctx context.Context, keys ...string) error {
 	shardKeys := make(map[*memoryShard][]string)
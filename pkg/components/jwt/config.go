@@ -9,10 +9,142 @@ import "time"
 * @Package:
  */
 
+// 支持的签名算法
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
 // Config jwt 配置
 type Config struct {
-	Secret             string        `mapstructure:"secret" validate:"required"`
-	AccessTokenExpire  time.Duration `mapstructure:"access_token_expire" validate:"required,min=30s"`   // Access Token 过期时间（秒），至少 60 秒
-	RefreshTokenExpire time.Duration `mapstructure:"refresh_token_expire" validate:"required,min=600s"` // Refresh Token 过期时间（秒），至少 600 秒
-	Issuer             string        `mapstructure:"issuer" validate:"required"`                        // 签发者
+	Secret             string        `mapstructure:"secret" validate:"required_if=Algorithm HS256"`                                 // HS256 对称密钥，非对称算法下可不配置
+	Algorithm          string        `mapstructure:"algorithm" validate:"omitempty,oneof=HS256 RS256 ES256 EdDSA"`                  // 签名算法，默认 HS256
+	Keys               []KeyConfig   `mapstructure:"keys" validate:"omitempty,dive"`                                                // 非对称密钥列表，第一个带私钥的为签名密钥，其余仅用于验签
+	KeyRotation        time.Duration `mapstructure:"key_rotation" validate:"omitempty,min=1h"`                                      // 非对称密钥自动轮换周期，0 表示不自动轮换
+	KeyRingSecret      string        `mapstructure:"key_ring_secret" validate:"omitempty,min=32"`                                   // 自动生成密钥环的加密密钥，私钥加密后才写入共享缓存
+	AccessTokenExpire  time.Duration `mapstructure:"access_token_expire" validate:"required,min=30s"`                               // Access Token 过期时间（秒），至少 60 秒
+	RefreshTokenExpire time.Duration `mapstructure:"refresh_token_expire" validate:"required,min=600s"`                             // Refresh Token 过期时间（秒），至少 600 秒
+	Issuer             string        `mapstructure:"issuer" validate:"required"`                                                    // 签发者
+	SessionLimit       SessionLimit  `mapstructure:"session_limit"`                                                                 // 同一用户在线设备数限制
+	SessionStore       string        `mapstructure:"session_store" validate:"omitempty,oneof=cache db"`                             // 会话存储，默认 cache
+	ReuseResponse      string        `mapstructure:"reuse_response" validate:"omitempty,oneof=revoke_session revoke_all lock_user"` // 检测到 refresh token 重用时的处置级别，默认 revoke_session
+	IdleTimeout        time.Duration `mapstructure:"idle_timeout" validate:"omitempty,min=1m"`                                      // 会话空闲超时，超过该时间无活动需重新登录，0 表示不限制
+	MaxSessionLifetime time.Duration `mapstructure:"max_session_lifetime" validate:"omitempty,min=10m"`                             // 会话绝对有效期（从登录起算），0 表示等于 refresh_token_expire
+}
+
+// refresh token 重用时的处置级别
+const (
+	ReuseRevokeSession = "revoke_session" // 只撤销当前会话
+	ReuseRevokeAll     = "revoke_all"     // 撤销用户所有会话
+	ReuseLockUser      = "lock_user"      // 撤销用户所有会话并锁定账号（由 ReuseHandler 完成锁定）
+)
+
+// sessionLifetime 会话绝对有效期，token 的过期时间不会超过会话结束时间
+func (c Config) sessionLifetime() time.Duration {
+	if c.MaxSessionLifetime > 0 {
+		return c.MaxSessionLifetime
+	}
+	return c.RefreshTokenExpire
+}
+
+// activityInterval 最近活跃时间的最小写入间隔，避免每个请求都写存储
+func (c Config) activityInterval() time.Duration {
+	interval := time.Minute
+	if c.IdleTimeout > 0 && c.IdleTimeout/10 < interval {
+		interval = c.IdleTimeout / 10
+	}
+	return interval
+}
+
+// reuseResponse 获取重用处置级别，未配置时只撤销当前会话
+func (c Config) reuseResponse() string {
+	if c.ReuseResponse == "" {
+		return ReuseRevokeSession
+	}
+	return c.ReuseResponse
+}
+
+// 会话存储类型
+const (
+	SessionStoreCache = "cache" // 缓存存储（Redis，未配置时为进程内内存）
+	SessionStoreDB    = "db"    // 数据库存储，重启不丢失，多副本共享
+)
+
+// 超出在线设备数限制时的处理策略
+const (
+	SessionLimitEvictOldest = "evict_oldest" // 踢掉最早登录的会话
+	SessionLimitReject      = "reject"       // 拒绝本次登录
+)
+
+// SessionLimit 在线设备数限制策略
+type SessionLimit struct {
+	MaxSessions   int            `mapstructure:"max_sessions" validate:"omitempty,min=0"`                 // 最大在线会话数，0 表示不限制
+	Strategy      string         `mapstructure:"strategy" validate:"omitempty,oneof=evict_oldest reject"` // 超出限制时的策略，默认 evict_oldest
+	RoleOverrides map[string]int `mapstructure:"role_overrides" validate:"omitempty,dive,min=0"`          // 按角色覆盖最大会话数（如服务账号），0 表示不限制
+}
+
+// resolve 根据用户角色计算生效的最大会话数
+// 用户命中多个角色覆盖时取最宽松的值，未命中时使用默认值
+func (l SessionLimit) resolve(roles []string) int {
+	limit, matched := 0, false
+	for _, role := range roles {
+		n, ok := l.RoleOverrides[role]
+		if !ok {
+			continue
+		}
+		if n == 0 {
+			return 0
+		}
+		if !matched || n > limit {
+			limit, matched = n, true
+		}
+	}
+	if matched {
+		return limit
+	}
+	return l.MaxSessions
+}
+
+// strategy 获取超限策略，未配置时默认踢掉最早的会话
+func (l SessionLimit) strategy() string {
+	if l.Strategy == "" {
+		return SessionLimitEvictOldest
+	}
+	return l.Strategy
+}
+
+// KeyConfig 非对称密钥配置
+// PrivateKey / PublicKey 支持直接填写 PEM 内容或 PEM 文件路径
+type KeyConfig struct {
+	Kid        string `mapstructure:"kid" validate:"required"` // 密钥ID，写入 token header 的 kid
+	PrivateKey string `mapstructure:"private_key"`             // 私钥，用于签名
+	PublicKey  string `mapstructure:"public_key"`              // 公钥，仅验签的历史密钥可只配置公钥
+}
+
+// algorithm 获取签名算法，未配置时默认 HS256
+func (c Config) algorithm() string {
+	if c.Algorithm == "" {
+		return AlgorithmHS256
+	}
+	return c.Algorithm
+}
+
+// DynamicKeys 是否使用自动生成的轮换密钥签名（非对称算法下开启轮换或未配置私钥）
+// 轮换密钥环（含私钥）保存在缓存中，多实例部署时必须使用共享缓存（Redis），否则各实例各自生成密钥；
+// 配置 KeyRingSecret 后私钥加密保存，能读取缓存的人无法伪造 token
+func (c Config) DynamicKeys() bool {
+	if c.algorithm() == AlgorithmHS256 {
+		return false
+	}
+	if c.KeyRotation > 0 {
+		return true
+	}
+	for _, k := range c.Keys {
+		if k.PrivateKey != "" {
+			return false
+		}
+	}
+	return true
 }
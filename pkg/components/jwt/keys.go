@@ -0,0 +1,539 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	_interface "gin-admin/pkg/interface"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/8 上午10:12
+* @Package: 签名密钥管理（多 kid + 自动轮换）
+ */
+
+const (
+	cacheKeyKeyRing     = "jwt:keys"      // 轮换密钥环（所有实例共享）
+	cacheKeyKeyRingLock = "jwt:keys:lock" // 轮换互斥锁，保证同一时刻只有一个实例生成新密钥
+	keyRingLockTTL      = 10 * time.Second
+	keyRingReloadLimit  = time.Second // 遇到未知 kid 时重新加载密钥环的最小间隔
+	sealedKeyPrefix     = "enc:v1:"   // 加密保存的私钥前缀
+)
+
+var errUnsealedKey = errors.New("unencrypted key is not accepted when key_ring_secret is configured")
+
+// signingKey 签名密钥
+type signingKey struct {
+	kid       string
+	method    jwt.SigningMethod
+	private   interface{} // HS256 为 []byte，其余为私钥
+	public    crypto.PublicKey
+	createdAt time.Time
+	retireAt  time.Time // 零值表示当前签名密钥；退役后仅用于验签，直到该时间
+}
+
+func (k *signingKey) expired(now time.Time) bool {
+	return !k.retireAt.IsZero() && now.After(k.retireAt)
+}
+
+// storedKey 缓存中持久化的轮换密钥
+type storedKey struct {
+	Kid        string    `json:"kid"`
+	PrivateKey string    `json:"private_key"` // PKCS8 PEM，配置了 KeyRingSecret 时为 AES-GCM 加密后的密文
+	CreatedAt  time.Time `json:"created_at"`
+	RetireAt   time.Time `json:"retire_at"`
+}
+
+// keyRing 轮换密钥环
+type keyRing struct {
+	Keys []storedKey `json:"keys"`
+}
+
+// KeyManager 签名密钥管理器
+// 1. HS256：使用 Config.Secret 对称签名
+// 2. RS256/ES256/EdDSA + 静态密钥：使用配置中的第一个私钥签名，所有配置的密钥都可验签
+// 3. 开启 KeyRotation 或未配置私钥：自动生成密钥并按周期轮换，密钥环存放在共享缓存中，
+// 多实例共用；被替换的旧密钥继续用于验签，直到其签发的 token 全部过期（RefreshTokenExpire）
+type KeyManager struct {
+	method    jwt.SigningMethod
+	secret    []byte
+	static    []*signingKey
+	signer    *signingKey // 静态签名密钥
+	cache     _interface.ICache
+	rotation  time.Duration
+	retention time.Duration
+	aead      cipher.AEAD // 密钥环的加密器，未配置 KeyRingSecret 时为 nil
+
+	mu       sync.RWMutex
+	dynamic  []*signingKey
+	loadedAt time.Time
+	group    singleflight.Group
+}
+
+// NewKeyManager 根据配置创建密钥管理器
+func NewKeyManager(cfg Config, cache _interface.ICache) (*KeyManager, error) {
+	alg := cfg.algorithm()
+	method := jwt.GetSigningMethod(alg)
+	if method == nil {
+		return nil, fmt.Errorf("unsupported jwt algorithm %s", alg)
+	}
+	m := &KeyManager{
+		method:    method,
+		cache:     cache,
+		rotation:  cfg.KeyRotation,
+		retention: cfg.RefreshTokenExpire,
+	}
+	if alg == AlgorithmHS256 {
+		if cfg.Secret == "" {
+			return nil, errors.New("jwt secret is required for HS256")
+		}
+		m.secret = []byte(cfg.Secret)
+		return m, nil
+	}
+	for _, kc := range cfg.Keys {
+		key, err := loadStaticKey(alg, kc)
+		if err != nil {
+			return nil, fmt.Errorf("load jwt key %s: %w", kc.Kid, err)
+		}
+		key.method = method
+		m.static = append(m.static, key)
+		if m.signer == nil && key.private != nil {
+			m.signer = key
+		}
+	}
+	if m.dynamicEnabled() && cache == nil {
+		return nil, errors.New("jwt key rotation requires a cache")
+	}
+	if cfg.KeyRingSecret != "" {
+		sum := sha256.Sum256([]byte(cfg.KeyRingSecret))
+		block, err := aes.NewCipher(sum[:])
+		if err != nil {
+			return nil, err
+		}
+		if m.aead, err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// dynamicEnabled 是否使用自动生成的轮换密钥签名
+func (m *KeyManager) dynamicEnabled() bool {
+	return m.secret == nil && (m.rotation > 0 || m.signer == nil)
+}
+
+// Sign 使用当前签名密钥签发 token
+func (m *KeyManager) Sign(ctx context.Context, claims jwt.Claims) (string, error) {
+	if m.secret != nil {
+		return jwt.NewWithClaims(m.method, claims).SignedString(m.secret)
+	}
+	key := m.signer
+	if m.dynamicEnabled() {
+		var err error
+		if key, err = m.current(ctx); err != nil {
+			return "", err
+		}
+	}
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.private)
+}
+
+// Keyfunc 验签函数：校验算法一致并按 kid 查找公钥
+func (m *KeyManager) Keyfunc(ctx context.Context) jwt.Keyfunc {
+	return func(t *jwt.Token) (interface{}, error) {
+		// 防止算法混淆攻击（例如用公钥当 HMAC 密钥）
+		if t.Method.Alg() != m.method.Alg() {
+			return nil, ErrInvalidToken
+		}
+		if m.secret != nil {
+			return m.secret, nil
+		}
+		kid, _ := t.Header["kid"].(string)
+		key := m.lookup(ctx, kid)
+		if key == nil {
+			return nil, ErrKeyNotFound
+		}
+		return key.public, nil
+	}
+}
+
+// JWKS 返回所有仍可用于验签的公钥
+func (m *KeyManager) JWKS(ctx context.Context) JSONWebKeySet {
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	if m.secret != nil {
+		// 对称密钥不能公开
+		return set
+	}
+	if m.dynamicEnabled() {
+		if _, err := m.current(ctx); err != nil {
+			logrus.Errorf("failed to load jwt signing keys: %v", err)
+		}
+	}
+	now := time.Now()
+	m.mu.RLock()
+	keys := append(append([]*signingKey{}, m.dynamic...), m.static...)
+	m.mu.RUnlock()
+	for _, k := range keys {
+		if k.expired(now) {
+			continue
+		}
+		jwk, err := newJSONWebKey(k.kid, k.method.Alg(), k.public)
+		if err != nil {
+			logrus.Errorf("failed to encode jwk %s: %v", k.kid, err)
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// lookup 按 kid 查找验签密钥，本地未命中时从共享缓存重新加载（其他实例可能已轮换）
+func (m *KeyManager) lookup(ctx context.Context, kid string) *signingKey {
+	if key := m.find(kid); key != nil {
+		return key
+	}
+	if !m.dynamicEnabled() {
+		return nil
+	}
+	m.mu.RLock()
+	recent := time.Since(m.loadedAt) < keyRingReloadLimit
+	m.mu.RUnlock()
+	if !recent {
+		_, _, _ = m.group.Do("reload", func() (interface{}, error) {
+			return nil, m.reload(ctx)
+		})
+	}
+	return m.find(kid)
+}
+
+func (m *KeyManager) find(kid string) *signingKey {
+	now := time.Now()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, list := range [][]*signingKey{m.dynamic, m.static} {
+		for _, k := range list {
+			if k.kid == kid && !k.expired(now) {
+				return k
+			}
+		}
+	}
+	return nil
+}
+
+// current 获取当前轮换签名密钥，到期时触发轮换
+func (m *KeyManager) current(ctx context.Context) (*signingKey, error) {
+	if key := m.active(); key != nil && !m.due(key) {
+		return key, nil
+	}
+	_, err, _ := m.group.Do("rotate", func() (interface{}, error) {
+		return nil, m.rotate(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if key := m.active(); key != nil {
+		return key, nil
+	}
+	return nil, ErrKeyNotFound
+}
+
+func (m *KeyManager) active() *signingKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for i := len(m.dynamic) - 1; i >= 0; i-- {
+		if m.dynamic[i].retireAt.IsZero() {
+			return m.dynamic[i]
+		}
+	}
+	return nil
+}
+
+func (m *KeyManager) due(key *signingKey) bool {
+	return m.rotation > 0 && time.Since(key.createdAt) >= m.rotation
+}
+
+// rotate 轮换签名密钥
+func (m *KeyManager) rotate(ctx context.Context) error {
+	// 其他实例可能已经完成轮换
+	if err := m.reload(ctx); err != nil {
+		return err
+	}
+	if key := m.active(); key != nil && !m.due(key) {
+		return nil
+	}
+	// 锁与过期时间原子写入，持有锁的实例异常退出时锁到期自动释放
+	locked, err := m.cache.SetNX(ctx, cacheKeyKeyRingLock, 1, keyRingLockTTL)
+	if err != nil {
+		return err
+	}
+	if !locked {
+		// 其他实例正在轮换，等待其完成
+		return m.waitRotation(ctx)
+	}
+	defer func() {
+		_ = m.cache.Delete(context.Background(), cacheKeyKeyRingLock)
+	}()
+	// 拿到锁后再确认一次
+	if err = m.reload(ctx); err != nil {
+		return err
+	}
+	if key := m.active(); key != nil && !m.due(key) {
+		return nil
+	}
+
+	now := time.Now()
+	fresh, err := m.generate(now)
+	if err != nil {
+		return err
+	}
+	m.mu.RLock()
+	old := m.dynamic
+	m.mu.RUnlock()
+	keys := make([]*signingKey, 0, len(old)+1)
+	for _, k := range old {
+		if k.expired(now) {
+			continue
+		}
+		retired := *k
+		if retired.retireAt.IsZero() {
+			retired.retireAt = now.Add(m.retention)
+		}
+		keys = append(keys, &retired)
+	}
+	keys = append(keys, fresh)
+	if err = m.save(ctx, keys); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.dynamic = keys
+	m.loadedAt = now
+	m.mu.Unlock()
+	logrus.Infof("jwt signing key rotated, new kid: %s", fresh.kid)
+	return nil
+}
+
+func (m *KeyManager) waitRotation(ctx context.Context) error {
+	for i := 0; i < 50; i++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+		if err := m.reload(ctx); err != nil {
+			return err
+		}
+		if key := m.active(); key != nil && !m.due(key) {
+			return nil
+		}
+	}
+	return errors.New("timeout waiting for jwt key rotation")
+}
+
+// reload 从共享缓存加载密钥环
+func (m *KeyManager) reload(ctx context.Context) error {
+	var ring keyRing
+	if err := m.cache.Get(ctx, cacheKeyKeyRing, &ring); err != nil && !errors.Is(err, _interface.ErrKeyNotFound) {
+		return err
+	}
+	keys := make([]*signingKey, 0, len(ring.Keys))
+	for _, sk := range ring.Keys {
+		key, err := m.decode(sk)
+		if err != nil {
+			logrus.Errorf("failed to decode jwt key %s: %v", sk.Kid, err)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	m.mu.Lock()
+	m.dynamic = keys
+	m.loadedAt = time.Now()
+	m.mu.Unlock()
+	return nil
+}
+
+// save 持久化密钥环到共享缓存
+func (m *KeyManager) save(ctx context.Context, keys []*signingKey) error {
+	ring := keyRing{Keys: make([]storedKey, 0, len(keys))}
+	for _, k := range keys {
+		der, err := x509.MarshalPKCS8PrivateKey(k.private)
+		if err != nil {
+			return err
+		}
+		sealed, err := m.seal(k.kid, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+		if err != nil {
+			return err
+		}
+		ring.Keys = append(ring.Keys, storedKey{
+			Kid:        k.kid,
+			PrivateKey: sealed,
+			CreatedAt:  k.createdAt,
+			RetireAt:   k.retireAt,
+		})
+	}
+	return m.cache.Set(ctx, cacheKeyKeyRing, ring, 0)
+}
+
+// seal 加密私钥，kid 作为附加数据，密文不能挪用到其他 kid；未配置 KeyRingSecret 时原样返回
+func (m *KeyManager) seal(kid string, data []byte) (string, error) {
+	if m.aead == nil {
+		return string(data), nil
+	}
+	nonce := make([]byte, m.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := m.aead.Seal(nonce, nonce, data, []byte(kid))
+	return sealedKeyPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// open 解密私钥；配置了 KeyRingSecret 时拒绝未加密的私钥，避免能写入缓存的人塞入自己的密钥
+func (m *KeyManager) open(kid, stored string) ([]byte, error) {
+	encoded, sealed := strings.CutPrefix(stored, sealedKeyPrefix)
+	if !sealed {
+		if m.aead != nil {
+			return nil, errUnsealedKey
+		}
+		return []byte(stored), nil
+	}
+	if m.aead == nil {
+		return nil, errors.New("key is encrypted but key_ring_secret is not configured")
+	}
+	data, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(data) < m.aead.NonceSize() {
+		return nil, errors.New("invalid encrypted key")
+	}
+	nonce, ciphertext := data[:m.aead.NonceSize()], data[m.aead.NonceSize():]
+	return m.aead.Open(nil, nonce, ciphertext, []byte(kid))
+}
+
+func (m *KeyManager) decode(sk storedKey) (*signingKey, error) {
+	data, err := m.open(sk.Kid, sk.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid pem")
+	}
+	priv, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("invalid private key")
+	}
+	return &signingKey{
+		kid:       sk.Kid,
+		method:    m.method,
+		private:   priv,
+		public:    signer.Public(),
+		createdAt: sk.CreatedAt,
+		retireAt:  sk.RetireAt,
+	}, nil
+}
+
+// generate 生成新的签名密钥
+func (m *KeyManager) generate(now time.Time) (*signingKey, error) {
+	var (
+		priv crypto.Signer
+		err  error
+	)
+	switch m.method.Alg() {
+	case AlgorithmRS256:
+		priv, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgorithmES256:
+		priv, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgorithmEdDSA:
+		_, priv, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		err = fmt.Errorf("unsupported jwt algorithm %s", m.method.Alg())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &signingKey{
+		kid:       now.UTC().Format("20060102") + "-" + uuid.New().String()[:8],
+		method:    m.method,
+		private:   priv,
+		public:    priv.Public(),
+		createdAt: now,
+	}, nil
+}
+
+// loadStaticKey 加载配置中的密钥
+func loadStaticKey(alg string, kc KeyConfig) (*signingKey, error) {
+	key := &signingKey{kid: kc.Kid}
+	if kc.PrivateKey != "" {
+		data, err := readPEM(kc.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		var priv crypto.Signer
+		switch alg {
+		case AlgorithmRS256:
+			priv, err = jwt.ParseRSAPrivateKeyFromPEM(data)
+		case AlgorithmES256:
+			priv, err = jwt.ParseECPrivateKeyFromPEM(data)
+		case AlgorithmEdDSA:
+			var k crypto.PrivateKey
+			if k, err = jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+				priv = k.(crypto.Signer)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		key.private = priv
+		key.public = priv.Public()
+		return key, nil
+	}
+	if kc.PublicKey == "" {
+		return nil, errors.New("private_key or public_key is required")
+	}
+	data, err := readPEM(kc.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	switch alg {
+	case AlgorithmRS256:
+		key.public, err = jwt.ParseRSAPublicKeyFromPEM(data)
+	case AlgorithmES256:
+		key.public, err = jwt.ParseECPublicKeyFromPEM(data)
+	case AlgorithmEdDSA:
+		key.public, err = jwt.ParseEdPublicKeyFromPEM(data)
+	}
+	return key, err
+}
+
+// readPEM 支持 PEM 内容或文件路径
+func readPEM(value string) ([]byte, error) {
+	if strings.HasPrefix(strings.TrimSpace(value), "-----BEGIN") {
+		return []byte(value), nil
+	}
+	return os.ReadFile(value)
+}
@@ -0,0 +1,176 @@
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	cache2 "gin-admin/pkg/components/cache"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func asymmetricConfig(alg string) Config {
+	return Config{
+		Algorithm:          alg,
+		Issuer:             "test",
+		AccessTokenExpire:  time.Minute,
+		RefreshTokenExpire: time.Hour,
+	}
+}
+
+func rsaPEM(t *testing.T) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+}
+
+func TestStaticRSAKey(t *testing.T) {
+	ctx := context.Background()
+	cfg := asymmetricConfig(AlgorithmRS256)
+	cfg.Keys = []KeyConfig{{Kid: "rsa-1", PrivateKey: rsaPEM(t)}}
+	svc := NewJwtService(cfg, cache2.NewShardedMemoryCache(0))
+
+	tp, err := svc.GenerateTokenPair(ctx, 1, "user", "email")
+	require.NoError(t, err)
+
+	token, _, err := new(jwt.Parser).ParseUnverified(tp.AccessToken, &CustomClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, "RS256", token.Method.Alg())
+	assert.Equal(t, "rsa-1", token.Header["kid"])
+
+	claims, err := svc.ParseAccessToken(ctx, tp.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), claims.UserID)
+
+	set := svc.JWKS(ctx)
+	require.Len(t, set.Keys, 1)
+	assert.Equal(t, "RSA", set.Keys[0].Kty)
+	assert.Equal(t, "rsa-1", set.Keys[0].Kid)
+}
+
+// TestVerifyWithJWKS 其他服务仅凭公钥集合即可验签
+func TestVerifyWithJWKS(t *testing.T) {
+	ctx := context.Background()
+	for _, alg := range []string{AlgorithmRS256, AlgorithmES256, AlgorithmEdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			svc := NewJwtService(asymmetricConfig(alg), cache2.NewShardedMemoryCache(0))
+			tp, err := svc.GenerateTokenPair(ctx, 7, "user", "email")
+			require.NoError(t, err)
+
+			set := svc.JWKS(ctx)
+			require.Len(t, set.Keys, 1)
+			token, err := jwt.ParseWithClaims(tp.AccessToken, &CustomClaims{}, set.Keyfunc())
+			require.NoError(t, err)
+			assert.True(t, token.Valid)
+			assert.Equal(t, uint(7), token.Claims.(*CustomClaims).UserID)
+		})
+	}
+}
+
+func TestKeyRotationKeepsOldKeys(t *testing.T) {
+	ctx := context.Background()
+	memCache := cache2.NewShardedMemoryCache(0)
+	cfg := asymmetricConfig(AlgorithmES256)
+	cfg.KeyRotation = time.Hour
+	svc := NewJwtService(cfg, memCache)
+
+	tp1, err := svc.GenerateTokenPair(ctx, 1, "user", "email")
+	require.NoError(t, err)
+
+	// 模拟轮换周期已到
+	km := svc.keys
+	km.mu.Lock()
+	km.dynamic[0].createdAt = time.Now().Add(-2 * time.Hour)
+	km.mu.Unlock()
+	require.NoError(t, km.save(ctx, km.dynamic))
+
+	tp2, err := svc.GenerateTokenPair(ctx, 1, "user", "email")
+	require.NoError(t, err)
+
+	kid := func(s string) string {
+		token, _, err := new(jwt.Parser).ParseUnverified(s, &CustomClaims{})
+		require.NoError(t, err)
+		return token.Header["kid"].(string)
+	}
+	assert.NotEqual(t, kid(tp1.AccessToken), kid(tp2.AccessToken))
+
+	// 旧密钥签发的 token 仍可验签
+	_, err = svc.ParseAccessToken(ctx, tp1.AccessToken)
+	assert.NoError(t, err)
+	_, err = svc.ParseAccessToken(ctx, tp2.AccessToken)
+	assert.NoError(t, err)
+	assert.Len(t, svc.JWKS(ctx).Keys, 2)
+
+	// 共享同一缓存的其他实例可以验签新密钥签发的 token
+	other := NewJwtService(cfg, memCache)
+	_, err = other.ParseAccessToken(ctx, tp2.AccessToken)
+	assert.NoError(t, err)
+}
+
+func TestRejectAlgorithmConfusion(t *testing.T) {
+	ctx := context.Background()
+	svc := NewJwtService(asymmetricConfig(AlgorithmRS256), cache2.NewShardedMemoryCache(0))
+	_, err := svc.GenerateTokenPair(ctx, 1, "user", "email")
+	require.NoError(t, err)
+
+	// 使用 HS256 伪造的 token 必须被拒绝
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, CustomClaims{
+		UserID:    1,
+		TokenType: TokenTypeAccess,
+	}).SignedString([]byte("guess"))
+	require.NoError(t, err)
+	_, err = svc.ParseAccessToken(ctx, forged)
+	assert.Error(t, err)
+}
+
+func TestHS256HasNoPublicKeys(t *testing.T) {
+	svc, _ := getJwtSvr()
+	assert.Empty(t, svc.JWKS(context.Background()).Keys)
+}
+
+// TestKeyRingEncrypted 配置 key_ring_secret 后缓存中只有加密的私钥
+func TestKeyRingEncrypted(t *testing.T) {
+	ctx := context.Background()
+	memCache := cache2.NewShardedMemoryCache(0)
+	cfg := asymmetricConfig(AlgorithmEdDSA)
+	cfg.KeyRingSecret = "key-ring-secret-at-least-32-chars!"
+	svc := NewJwtService(cfg, memCache)
+	tp, err := svc.GenerateTokenPair(ctx, 1, "user", "email")
+	require.NoError(t, err)
+
+	var ring keyRing
+	require.NoError(t, memCache.Get(ctx, cacheKeyKeyRing, &ring))
+	require.Len(t, ring.Keys, 1)
+	assert.True(t, strings.HasPrefix(ring.Keys[0].PrivateKey, sealedKeyPrefix))
+	assert.NotContains(t, ring.Keys[0].PrivateKey, "PRIVATE KEY")
+
+	// 相同密钥的实例可以解密密钥环
+	_, err = NewJwtService(cfg, memCache).ParseAccessToken(ctx, tp.AccessToken)
+	assert.NoError(t, err)
+
+	// 密钥不同或未配置密钥的实例无法解密
+	for _, secret := range []string{"another-key-ring-secret-32-chars!!", ""} {
+		other := cfg
+		other.KeyRingSecret = secret
+		_, err = NewJwtService(other, memCache).ParseAccessToken(ctx, tp.AccessToken)
+		assert.Error(t, err)
+	}
+
+	// 能写入缓存的人换上未加密的私钥，配置了密钥的实例不接受
+	plain := cfg
+	plain.KeyRingSecret = ""
+	attacker := NewJwtService(plain, cache2.NewShardedMemoryCache(0))
+	forged, err := attacker.GenerateTokenPair(ctx, 1, "user", "email")
+	require.NoError(t, err)
+	require.NoError(t, attacker.keys.cache.Get(ctx, cacheKeyKeyRing, &ring))
+	require.NoError(t, memCache.Set(ctx, cacheKeyKeyRing, ring, 0))
+	_, err = NewJwtService(cfg, memCache).ParseAccessToken(ctx, forged.AccessToken)
+	assert.Error(t, err)
+}
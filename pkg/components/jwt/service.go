@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	_interface "gin-admin/pkg/interface"
+	"sort"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
 	"golang.org/x/sync/singleflight"
 )
 
@@ -19,11 +21,21 @@ type Service interface {
 	GenerateTokenPair(ctx context.Context, userID uint, username, email string, opts ...TokenOption) (*TokenPair, error)
 	// ParseAccessToken 解析accessToken
 	ParseAccessToken(ctx context.Context, tokenString string) (*CustomClaims, error)
-	// RefreshToken 刷新token
-	RefreshToken(ctx context.Context, refreshToken string) (*TokenPair, error)
+	// InspectAccessToken 与 ParseAccessToken 相同但不更新会话活跃时间，用于令牌内省等非持有人发起的查询
+	InspectAccessToken(ctx context.Context, tokenString string) (*CustomClaims, error)
+	// ParseRefreshToken 解析refreshToken，只有会话当前持有的 refresh token 有效（不触发轮换）
+	ParseRefreshToken(ctx context.Context, tokenString string) (*CustomClaims, error)
+	// RefreshToken 刷新token，可通过 WithClientInfo 更新会话的客户端信息
+	RefreshToken(ctx context.Context, refreshToken string, opts ...TokenOption) (*TokenPair, error)
 	// RevokeSession 撤销登录session
 	RevokeSession(ctx context.Context, sessionId string) error
 	RevokeUserAllSessions(ctx context.Context, userID uint) error
+	// GetSession 获取会话信息，不存在时返回 nil
+	GetSession(ctx context.Context, sessionId string) *SessionInfo
+	// ListUserSessions 获取用户的在线会话（设备）列表
+	ListUserSessions(ctx context.Context, userID uint) ([]*SessionInfo, error)
+	// JWKS 当前可用于验签的公钥集合
+	JWKS(ctx context.Context) JSONWebKeySet
 }
 
 // =======================
@@ -32,15 +44,27 @@ type Service interface {
 
 type JWTService struct {
 	config         Config
+	keys           *KeyManager
 	sessionManager SessionManager
+	reuseHandler   ReuseHandler
 	refreshGroup   singleflight.Group // 防止并发刷新同一个 refresh token
 }
 
-func NewJwtService(cfg Config, cache _interface.ICache) *JWTService {
-	return &JWTService{
+func NewJwtService(cfg Config, cache _interface.ICache, opts ...ServiceOption) *JWTService {
+	keys, err := NewKeyManager(cfg, cache)
+	if err != nil {
+		// 密钥配置错误属于启动期致命错误
+		panic(err)
+	}
+	s := &JWTService{
 		config:         cfg,
+		keys:           keys,
 		sessionManager: NewCacheSessionManager(cache),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *JWTService) GenerateTokenPair(ctx context.Context, userID uint, username, email string, opts ...TokenOption) (*TokenPair, error) {
@@ -52,45 +76,78 @@ func (s *JWTService) GenerateTokenPair(ctx context.Context, userID uint, usernam
 	for _, opt := range opts {
 		opt(tokenOpts)
 	}
+	now := time.Now()
+	lifetime := s.config.sessionLifetime()
+	if tokenOpts.SessionLifetime > 0 && tokenOpts.SessionLifetime < lifetime {
+		lifetime = tokenOpts.SessionLifetime
+	}
+	tokenOpts.sessionExpiresAt = now.Add(lifetime)
 
 	// 生成 access token
-	accessToken, err := s.generateAccessToken(userID, username, email, tokenOpts)
+	accessToken, err := s.generateAccessToken(ctx, userID, username, email, tokenOpts)
 	if err != nil {
 		return nil, err
 	}
 
 	// 生成 refresh token
-	refreshToken, err := s.generateRefreshToken(userID, username, tokenOpts)
+	refreshToken, err := s.generateRefreshToken(ctx, userID, username, tokenOpts)
 	if err != nil {
 		return nil, err
 	}
 
-	// 保存 session 状态
-	err = s.sessionManager.SaveSession(ctx, SessionInfo{
+	// 保存 session 状态，同时执行在线设备数限制（模拟会话不占用被模拟用户的设备数，也不会挤掉其会话）
+	limit := s.config.SessionLimit.resolve(tokenOpts.Roles)
+	if tokenOpts.ImpersonatorID != 0 {
+		limit = 0
+	}
+	err = s.sessionManager.SaveSessionWithLimit(ctx, SessionInfo{
 		SessionID:        tokenOpts.SessionID,
 		UserID:           userID,
 		Username:         username,
 		RefreshTokenHash: Hash(refreshToken),
-		ExpiresAt:        time.Now().Add(s.config.RefreshTokenExpire),
-	})
+		DeviceID:         tokenOpts.DeviceID,
+		IP:               tokenOpts.ClientIP,
+		UserAgent:        tokenOpts.UserAgent,
+		CreatedAt:        now,
+		LastSeenAt:       now,
+		ExpiresAt:        tokenOpts.sessionExpiresAt,
+		IdleTimeout:      s.config.IdleTimeout,
+		ImpersonatorID:   tokenOpts.ImpersonatorID,
+	}, limit, s.config.SessionLimit.strategy())
 	if err != nil {
 		return nil, err
 	}
 
+	return s.tokenPair(accessToken, refreshToken, tokenOpts), nil
+}
+
+// tokenPair 组装令牌对
+func (s *JWTService) tokenPair(accessToken, refreshToken string, opts *TokenOptions) *TokenPair {
+	now := time.Now()
+	expiresAt := tokenExpiresAt(now, s.config.AccessTokenExpire, opts)
 	return &TokenPair{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
-		ExpiresIn:    int64(s.config.AccessTokenExpire.Seconds()),
-		ExpiresAt:    time.Now().Add(s.config.AccessTokenExpire),
-		TokenType:    "Bearer",
-	}, nil
+		ExpiresIn:    int64(expiresAt.Sub(now).Seconds()),
+		ExpiresAt:    expiresAt,
+		TokenType:    TokenPrefix,
+	}
+}
+
+// tokenExpiresAt token 过期时间，不超过会话的绝对过期时间
+func tokenExpiresAt(now time.Time, ttl time.Duration, opts *TokenOptions) time.Time {
+	expiresAt := now.Add(ttl)
+	if !opts.sessionExpiresAt.IsZero() && opts.sessionExpiresAt.Before(expiresAt) {
+		return opts.sessionExpiresAt
+	}
+	return expiresAt
 }
 
 // =======================
 // Access Token
 // =======================
 
-func (s *JWTService) generateAccessToken(userID uint, username, email string, opts *TokenOptions) (string, error) {
+func (s *JWTService) generateAccessToken(ctx context.Context, userID uint, username, email string, opts *TokenOptions) (string, error) {
 	now := time.Now()
 	claims := CustomClaims{
 		UserID:    userID,
@@ -99,8 +156,11 @@ func (s *JWTService) generateAccessToken(userID uint, username, email string, op
 		TokenType: TokenTypeAccess,
 		DeviceID:  opts.DeviceID,
 		SessionID: opts.SessionID,
+		// 模拟登录标记随 token 轮换保留
+		ImpersonatorID: opts.ImpersonatorID,
+		TenantID:       opts.TenantID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(s.config.AccessTokenExpire)),
+			ExpiresAt: jwt.NewNumericDate(tokenExpiresAt(now, s.config.AccessTokenExpire, opts)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    s.config.Issuer,
@@ -109,14 +169,14 @@ func (s *JWTService) generateAccessToken(userID uint, username, email string, op
 		},
 	}
 
-	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.config.Secret))
+	return s.keys.Sign(ctx, claims)
 }
 
 // =======================
 // Refresh Token + Rotation
 // =======================
 
-func (s *JWTService) generateRefreshToken(userID uint, username string, opts *TokenOptions) (token string, err error) {
+func (s *JWTService) generateRefreshToken(ctx context.Context, userID uint, username string, opts *TokenOptions) (token string, err error) {
 	now := time.Now()
 	claims := CustomClaims{
 		UserID:    userID,
@@ -124,8 +184,11 @@ func (s *JWTService) generateRefreshToken(userID uint, username string, opts *To
 		TokenType: TokenTypeRefresh,
 		DeviceID:  opts.DeviceID,
 		SessionID: opts.SessionID,
+		// 模拟登录标记随 token 轮换保留
+		ImpersonatorID: opts.ImpersonatorID,
+		TenantID:       opts.TenantID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(s.config.RefreshTokenExpire)),
+			ExpiresAt: jwt.NewNumericDate(tokenExpiresAt(now, s.config.RefreshTokenExpire, opts)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    s.config.Issuer,
@@ -134,7 +197,7 @@ func (s *JWTService) generateRefreshToken(userID uint, username string, opts *To
 		},
 	}
 
-	token, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.config.Secret))
+	token, err = s.keys.Sign(ctx, claims)
 	if err != nil {
 		return "", err
 	}
@@ -147,10 +210,17 @@ func (s *JWTService) generateRefreshToken(userID uint, username string, opts *To
 // =======================
 
 func (s *JWTService) ParseAccessToken(ctx context.Context, tokenString string) (*CustomClaims, error) {
+	return s.parseAccessToken(ctx, tokenString, true)
+}
+
+func (s *JWTService) InspectAccessToken(ctx context.Context, tokenString string) (*CustomClaims, error) {
+	return s.parseAccessToken(ctx, tokenString, false)
+}
+
+// parseAccessToken 校验 access token 及其会话，touch 为 true 时同时记录会话活跃
+func (s *JWTService) parseAccessToken(ctx context.Context, tokenString string, touch bool) (*CustomClaims, error) {
 	// 解析 token
-	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, func(t *jwt.Token) (interface{}, error) {
-		return []byte(s.config.Secret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, s.keys.Keyfunc(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -161,8 +231,11 @@ func (s *JWTService) ParseAccessToken(ctx context.Context, tokenString string) (
 		}
 		// 验证 Session 是否有效
 		session := s.sessionManager.GetSession(ctx, claims.SessionID)
-		if session == nil || session.Revoked {
-			return nil, ErrSessionInvalid
+		if err = s.checkSession(ctx, session); err != nil {
+			return nil, err
+		}
+		if touch {
+			s.touch(ctx, session)
 		}
 
 		return claims, nil
@@ -170,17 +243,45 @@ func (s *JWTService) ParseAccessToken(ctx context.Context, tokenString string) (
 	return nil, nil
 }
 
+// =======================
+// Parse Refresh Token
+// =======================
+
+func (s *JWTService) ParseRefreshToken(ctx context.Context, tokenString string) (*CustomClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, s.keys.Keyfunc(ctx))
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := token.Claims.(*CustomClaims)
+	if !ok || !token.Valid || claims.TokenType != TokenTypeRefresh {
+		return nil, ErrInvalidToken
+	}
+	session := s.sessionManager.GetSession(ctx, claims.SessionID)
+	if err = s.checkSession(ctx, session); err != nil {
+		return nil, err
+	}
+	// 已轮换掉的旧 refresh token 视为无效（这里不做重用处置，重用只在刷新时判定）
+	if !SecureCompare(Hash(tokenString), session.RefreshTokenHash) {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
 // =======================
 // Refresh Token → 新 Token Pair
 // =======================
 
-func (s *JWTService) RefreshToken(ctx context.Context, refreshToken string) (*TokenPair, error) {
+func (s *JWTService) RefreshToken(ctx context.Context, refreshToken string, opts ...TokenOption) (*TokenPair, error) {
+	tokenOpts := &TokenOptions{}
+	for _, opt := range opts {
+		opt(tokenOpts)
+	}
 	// 使用 singleflight 防止并发刷新同一个 refresh token
 	// 场景：前端同时发送多个请求，Access Token 都过期了
 	// 问题：多个请求同时刷新会导致 token rotation 检测为 stolen
 	// 解决：使用 refresh token 作为 key，确保同时只有一个刷新请求执行
 	result, err, _ := s.refreshGroup.Do(refreshToken, func() (interface{}, error) {
-		return s.doRefreshToken(ctx, refreshToken)
+		return s.doRefreshToken(ctx, refreshToken, ClientInfo{IP: tokenOpts.ClientIP, UserAgent: tokenOpts.UserAgent})
 	})
 
 	if err != nil {
@@ -191,10 +292,8 @@ func (s *JWTService) RefreshToken(ctx context.Context, refreshToken string) (*To
 }
 
 // doRefreshToken
-func (s *JWTService) doRefreshToken(ctx context.Context, refreshToken string) (*TokenPair, error) {
-	token, err := jwt.ParseWithClaims(refreshToken, &CustomClaims{}, func(t *jwt.Token) (interface{}, error) {
-		return []byte(s.config.Secret), nil
-	})
+func (s *JWTService) doRefreshToken(ctx context.Context, refreshToken string, client ClientInfo) (*TokenPair, error) {
+	token, err := jwt.ParseWithClaims(refreshToken, &CustomClaims{}, s.keys.Keyfunc(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -207,50 +306,102 @@ func (s *JWTService) doRefreshToken(ctx context.Context, refreshToken string) (*
 	}
 	// 从 Redis 获取 session
 	session := s.sessionManager.GetSession(ctx, claims.SessionID)
-	if session == nil || session.Revoked {
+	if err = s.checkSession(ctx, session); err != nil {
 		// session 不存在或者已经被注销
-		return nil, ErrSessionInvalid
+		return nil, err
 	}
 	// 校验 refresh token hash
 	if !SecureCompare(Hash(refreshToken), session.RefreshTokenHash) {
 		// 说明 refresh token 不存在或者被窃取盗用
-		_ = s.sessionManager.RemoveSession(ctx, claims.SessionID)
+		s.handleReuse(ctx, claims, client)
 		return nil, ErrRefreshTokenStolen
 	}
 
-	// Rotation：生成新 Refresh Token（保持 SessionID 不变）
-	newRefreshToken, err := s.generateRefreshToken(claims.UserID, claims.Username, &TokenOptions{
-		SessionID: claims.SessionID,
-		DeviceID:  claims.DeviceID,
-	})
+	// Rotation：生成新 Refresh Token（保持 SessionID 不变，过期时间不超过会话绝对过期时间）
+	tokenOpts := &TokenOptions{
+		SessionID:        claims.SessionID,
+		DeviceID:         claims.DeviceID,
+		ImpersonatorID:   claims.ImpersonatorID,
+		TenantID:         claims.TenantID,
+		sessionExpiresAt: session.ExpiresAt,
+	}
+	newRefreshToken, err := s.generateRefreshToken(ctx, claims.UserID, claims.Username, tokenOpts)
 	if err != nil {
 		return nil, err
 	}
 
 	// 生成新的 Access Token
-	accessToken, err := s.generateAccessToken(
-		claims.UserID, claims.Username, claims.Email, &TokenOptions{
-			SessionID: claims.SessionID,
-			DeviceID:  claims.DeviceID,
-		},
-	)
+	accessToken, err := s.generateAccessToken(ctx, claims.UserID, claims.Username, claims.Email, tokenOpts)
 	if err != nil {
 		return nil, err
 	}
 
 	// 更新 session 里的 refresh hash
-	err = s.sessionManager.UpdateRefreshHash(ctx, claims.SessionID, Hash(newRefreshToken))
+	err = s.sessionManager.UpdateRefreshHash(ctx, claims.SessionID, Hash(newRefreshToken), client)
 	if err != nil {
 		return nil, err
 	}
 
-	return &TokenPair{
-		AccessToken:  accessToken,
-		RefreshToken: newRefreshToken,
-		ExpiresIn:    int64(s.config.AccessTokenExpire.Seconds()),
-		ExpiresAt:    time.Now().Add(s.config.AccessTokenExpire),
-		TokenType:    TokenPrefix,
-	}, nil
+	return s.tokenPair(accessToken, newRefreshToken, tokenOpts), nil
+}
+
+// handleReuse 按配置的处置级别撤销会话，并通知 ReuseHandler
+func (s *JWTService) handleReuse(ctx context.Context, claims *CustomClaims, client ClientInfo) {
+	response := s.config.reuseResponse()
+	var err error
+	switch response {
+	case ReuseRevokeAll, ReuseLockUser:
+		err = s.sessionManager.RemoveUserSessions(ctx, claims.UserID)
+	default:
+		err = s.sessionManager.RemoveSession(ctx, claims.SessionID)
+	}
+	if err != nil {
+		logrus.Errorf("failed to revoke sessions after refresh token reuse :%s", err.Error())
+	}
+	if s.reuseHandler == nil {
+		return
+	}
+	s.reuseHandler(ctx, ReuseEvent{
+		UserID:     claims.UserID,
+		TenantID:   claims.TenantID,
+		Username:   claims.Username,
+		SessionID:  claims.SessionID,
+		DeviceID:   claims.DeviceID,
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		Response:   response,
+		DetectedAt: time.Now(),
+	})
+}
+
+// checkSession 校验会话状态，被挤下线、空闲超时的会话返回专用错误以便前端给出明确提示
+func (s *JWTService) checkSession(ctx context.Context, session *SessionInfo) error {
+	if session == nil {
+		return ErrSessionInvalid
+	}
+	if session.Revoked {
+		if session.RevokeReason == RevokeReasonEvicted {
+			return ErrSessionEvicted
+		}
+		return ErrSessionInvalid
+	}
+	// 空闲超时的会话保留到过期自动清理，之后使用该会话的请求都能收到同样的提示
+	// 会话不再被 touch，一旦超时会一直处于超时状态，不占在线设备名额，也不出现在在线会话列表中
+	if session.Idle(time.Now()) {
+		return ErrSessionIdleTimeout
+	}
+	return nil
+}
+
+// touch 更新会话最近活跃时间，按 activityInterval 节流
+func (s *JWTService) touch(ctx context.Context, session *SessionInfo) {
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) < s.config.activityInterval() {
+		return
+	}
+	if err := s.sessionManager.Touch(ctx, session, now); err != nil {
+		logrus.Errorf("failed to touch session :%s", err.Error())
+	}
 }
 
 // =======================
@@ -264,3 +415,38 @@ func (s *JWTService) RevokeSession(ctx context.Context, sessionId string) error
 func (s *JWTService) RevokeUserAllSessions(ctx context.Context, userID uint) error {
 	return s.sessionManager.RemoveUserSessions(ctx, userID)
 }
+
+// =======================
+// 在线会话（设备）查询
+// =======================
+
+func (s *JWTService) GetSession(ctx context.Context, sessionId string) *SessionInfo {
+	return s.sessionManager.GetSession(ctx, sessionId)
+}
+
+// ListUserSessions 按最近活跃时间倒序返回用户的在线会话
+func (s *JWTService) ListUserSessions(ctx context.Context, userID uint) ([]*SessionInfo, error) {
+	sessions, err := s.sessionManager.GetUserSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	list := make([]*SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		if !session.Revoked && !session.Idle(now) {
+			list = append(list, session)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].LastSeenAt.After(list[j].LastSeenAt)
+	})
+	return list, nil
+}
+
+// =======================
+// JWKS
+// =======================
+
+func (s *JWTService) JWKS(ctx context.Context) JSONWebKeySet {
+	return s.keys.JWKS(ctx)
+}
@@ -3,16 +3,18 @@ package jwt
 import (
 	"context"
 	cache2 "gin-admin/pkg/components/cache"
+	_interface "gin-admin/pkg/interface"
 	"sync"
 	"testing"
 	"time"
 
+	jwtv4 "github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 // getJwtSvr 创建测试用的 JWT Service
-func getJwtSvr() (Service, cache2.ICache) {
+func getJwtSvr() (Service, _interface.ICache) {
 	// 使用分片内存缓存进行测试
 	memCache := cache2.NewShardedMemoryCache(0)
 
@@ -137,6 +139,55 @@ func TestRefreshTokenStolen(t *testing.T) {
 	assert.Equal(t, ErrRefreshTokenStolen, err)
 }
 
+func TestRefreshTokenReuseResponse(t *testing.T) {
+	tests := []struct {
+		response      string
+		otherSessions int // 重用后同一用户其他会话剩余数量
+	}{
+		{ReuseRevokeSession, 1},
+		{ReuseRevokeAll, 0},
+		{ReuseLockUser, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.response, func(t *testing.T) {
+			var events []ReuseEvent
+			cfg := Config{
+				Secret:             "test-secret-key-32-chars-minimum",
+				Issuer:             "test",
+				AccessTokenExpire:  time.Minute,
+				RefreshTokenExpire: time.Hour,
+				ReuseResponse:      tt.response,
+			}
+			svc := NewJwtService(cfg, cache2.NewShardedMemoryCache(0), WithReuseHandler(func(ctx context.Context, event ReuseEvent) {
+				events = append(events, event)
+			}))
+			ctx := context.Background()
+
+			tp, err := svc.GenerateTokenPair(ctx, 1, "user", "email", WithDeviceID("mobile"))
+			require.NoError(t, err)
+			_, err = svc.GenerateTokenPair(ctx, 1, "user", "email")
+			require.NoError(t, err)
+
+			_, err = svc.RefreshToken(ctx, tp.RefreshToken)
+			require.NoError(t, err)
+			_, err = svc.RefreshToken(ctx, tp.RefreshToken, WithClientInfo("6.6.6.6", "attacker"))
+			assert.ErrorIs(t, err, ErrRefreshTokenStolen)
+
+			sessions, err := svc.ListUserSessions(ctx, 1)
+			require.NoError(t, err)
+			assert.Len(t, sessions, tt.otherSessions)
+
+			require.Len(t, events, 1)
+			assert.Equal(t, uint(1), events[0].UserID)
+			assert.Equal(t, "mobile", events[0].DeviceID)
+			assert.Equal(t, "6.6.6.6", events[0].IP)
+			assert.Equal(t, "attacker", events[0].UserAgent)
+			assert.Equal(t, tt.response, events[0].Response)
+			assert.NotEmpty(t, events[0].SessionID)
+		})
+	}
+}
+
 // ==============================================================================
 // 并发测试
 // ==============================================================================
@@ -490,3 +541,370 @@ func BenchmarkConcurrentParse(b *testing.B) {
 		}
 	})
 }
+
+// ==============================================================================
+// 在线会话测试
+// ==============================================================================
+
+func TestListUserSessions(t *testing.T) {
+	svc, _ := getJwtSvr()
+
+	ctx := context.Background()
+
+	tp, err := svc.GenerateTokenPair(ctx, 1, "user", "email",
+		WithDeviceID("mobile"), WithClientInfo("10.0.0.1", "ios"))
+	require.NoError(t, err)
+	_, err = svc.GenerateTokenPair(ctx, 1, "user", "email",
+		WithDeviceID("desktop"), WithClientInfo("10.0.0.2", "chrome"))
+	require.NoError(t, err)
+
+	sessions, err := svc.ListUserSessions(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	// 最近活跃的排在前面
+	assert.Equal(t, "desktop", sessions[0].DeviceID)
+	assert.Equal(t, "10.0.0.2", sessions[0].IP)
+	assert.Equal(t, "chrome", sessions[0].UserAgent)
+	assert.False(t, sessions[0].CreatedAt.IsZero())
+
+	// 刷新时同步客户端信息
+	claims, err := svc.ParseAccessToken(ctx, tp.AccessToken)
+	require.NoError(t, err)
+	before := svc.GetSession(ctx, claims.SessionID)
+	require.NotNil(t, before)
+	_, err = svc.RefreshToken(ctx, tp.RefreshToken, WithClientInfo("10.0.0.3", "ios-2"))
+	require.NoError(t, err)
+	after := svc.GetSession(ctx, claims.SessionID)
+	require.NotNil(t, after)
+	assert.Equal(t, "10.0.0.3", after.IP)
+	assert.Equal(t, "ios-2", after.UserAgent)
+	assert.Equal(t, "mobile", after.DeviceID)
+	assert.False(t, after.LastSeenAt.Before(before.LastSeenAt))
+
+	require.NoError(t, svc.RevokeSession(ctx, claims.SessionID))
+	sessions, err = svc.ListUserSessions(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1)
+}
+
+// ==============================================================================
+// 在线设备数限制测试
+// ==============================================================================
+
+func newLimitedJwtSvr(limit SessionLimit) Service {
+	cfg := Config{
+		Secret:             "test-secret-key-32-chars-minimum",
+		Issuer:             "test",
+		AccessTokenExpire:  time.Minute,
+		RefreshTokenExpire: time.Hour,
+		SessionLimit:       limit,
+	}
+	return NewJwtService(cfg, cache2.NewShardedMemoryCache(0))
+}
+
+func TestSessionLimitEvictOldest(t *testing.T) {
+	svc := newLimitedJwtSvr(SessionLimit{MaxSessions: 2})
+	ctx := context.Background()
+
+	first, err := svc.GenerateTokenPair(ctx, 1, "user", "email")
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	_, err = svc.GenerateTokenPair(ctx, 1, "user", "email")
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	third, err := svc.GenerateTokenPair(ctx, 1, "user", "email")
+	require.NoError(t, err)
+
+	sessions, err := svc.ListUserSessions(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 2)
+
+	// 最早的会话被挤下线，返回专用错误
+	_, err = svc.ParseAccessToken(ctx, first.AccessToken)
+	assert.ErrorIs(t, err, ErrSessionEvicted)
+	_, err = svc.RefreshToken(ctx, first.RefreshToken)
+	assert.ErrorIs(t, err, ErrSessionEvicted)
+
+	_, err = svc.ParseAccessToken(ctx, third.AccessToken)
+	assert.NoError(t, err)
+}
+
+func TestSessionLimitReject(t *testing.T) {
+	svc := newLimitedJwtSvr(SessionLimit{MaxSessions: 1, Strategy: SessionLimitReject})
+	ctx := context.Background()
+
+	first, err := svc.GenerateTokenPair(ctx, 1, "user", "email")
+	require.NoError(t, err)
+	_, err = svc.GenerateTokenPair(ctx, 1, "user", "email")
+	assert.ErrorIs(t, err, ErrSessionLimitExceeded)
+
+	_, err = svc.ParseAccessToken(ctx, first.AccessToken)
+	assert.NoError(t, err)
+
+	// 其他用户不受影响
+	_, err = svc.GenerateTokenPair(ctx, 2, "other", "email")
+	assert.NoError(t, err)
+}
+
+func TestSessionLimitRoleOverride(t *testing.T) {
+	svc := newLimitedJwtSvr(SessionLimit{
+		MaxSessions:   1,
+		Strategy:      SessionLimitReject,
+		RoleOverrides: map[string]int{"service": 3, "robot": 0},
+	})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err := svc.GenerateTokenPair(ctx, 1, "svc", "email", WithRoles("user", "service"))
+		require.NoError(t, err)
+	}
+	_, err := svc.GenerateTokenPair(ctx, 1, "svc", "email", WithRoles("user", "service"))
+	assert.ErrorIs(t, err, ErrSessionLimitExceeded)
+
+	// 覆盖值为 0 表示不限制
+	for i := 0; i < 5; i++ {
+		_, err = svc.GenerateTokenPair(ctx, 2, "bot", "email", WithRoles("robot"))
+		require.NoError(t, err)
+	}
+}
+
+func TestSessionLimitConcurrentLogin(t *testing.T) {
+	svc := newLimitedJwtSvr(SessionLimit{MaxSessions: 3, Strategy: SessionLimitReject})
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	success := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := svc.GenerateTokenPair(ctx, 1, "user", "email"); err == nil {
+				mu.Lock()
+				success++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 3, success)
+	sessions, err := svc.ListUserSessions(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 3)
+}
+
+func TestSessionLockExpires(t *testing.T) {
+	cache := cache2.NewShardedMemoryCache(0)
+	m := NewCacheSessionManager(cache).(*CacheSessionManager)
+	ctx := context.Background()
+
+	unlock, err := m.lockUser(ctx, 1)
+	require.NoError(t, err)
+	// 锁创建时即带过期时间，持有者异常退出也不会永久阻塞该用户登录
+	ttl, err := cache.TTL(ctx, m.userSessionsLockKey(1))
+	require.NoError(t, err)
+	assert.True(t, ttl > 0 && ttl <= sessionLockTTL)
+
+	_, err = m.lockUser(ctx, 1)
+	assert.ErrorIs(t, err, errSessionLockTimeout)
+
+	unlock()
+	unlock, err = m.lockUser(ctx, 1)
+	require.NoError(t, err)
+	unlock()
+}
+
+// ==============================================================================
+// 空闲超时与会话绝对有效期测试
+// ==============================================================================
+
+func TestSessionIdleTimeout(t *testing.T) {
+	cfg := Config{
+		Secret:             "test-secret-key-32-chars-minimum",
+		Issuer:             "test",
+		AccessTokenExpire:  time.Minute,
+		RefreshTokenExpire: time.Hour,
+		IdleTimeout:        200 * time.Millisecond,
+	}
+	svc := NewJwtService(cfg, cache2.NewShardedMemoryCache(0))
+	ctx := context.Background()
+
+	tp, err := svc.GenerateTokenPair(ctx, 1, "user", "email")
+	require.NoError(t, err)
+
+	// 持续活跃时不会超时
+	for i := 0; i < 4; i++ {
+		time.Sleep(100 * time.Millisecond)
+		_, err = svc.ParseAccessToken(ctx, tp.AccessToken)
+		require.NoError(t, err)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	_, err = svc.ParseAccessToken(ctx, tp.AccessToken)
+	assert.ErrorIs(t, err, ErrSessionIdleTimeout)
+	// 之后的请求继续返回空闲超时，而不是通用的会话无效
+	_, err = svc.ParseAccessToken(ctx, tp.AccessToken)
+	assert.ErrorIs(t, err, ErrSessionIdleTimeout)
+	_, err = svc.RefreshToken(ctx, tp.RefreshToken)
+	assert.ErrorIs(t, err, ErrSessionIdleTimeout)
+
+	// 空闲超时同样作用于刷新
+	tp, err = svc.GenerateTokenPair(ctx, 1, "user", "email")
+	require.NoError(t, err)
+	time.Sleep(300 * time.Millisecond)
+	sessions, err := svc.ListUserSessions(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+	_, err = svc.RefreshToken(ctx, tp.RefreshToken)
+	assert.ErrorIs(t, err, ErrSessionIdleTimeout)
+}
+
+func TestInspectAccessTokenDoesNotTouch(t *testing.T) {
+	cfg := Config{
+		Secret:             "test-secret-key-32-chars-minimum",
+		Issuer:             "test",
+		AccessTokenExpire:  time.Minute,
+		RefreshTokenExpire: time.Hour,
+		IdleTimeout:        200 * time.Millisecond,
+	}
+	svc := NewJwtService(cfg, cache2.NewShardedMemoryCache(0))
+	ctx := context.Background()
+
+	tp, err := svc.GenerateTokenPair(ctx, 1, "user", "email")
+	require.NoError(t, err)
+
+	time.Sleep(150 * time.Millisecond)
+	claims, err := svc.InspectAccessToken(ctx, tp.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), claims.UserID)
+
+	// 内省不算作活跃，会话按最后一次真实使用的时间超时
+	time.Sleep(100 * time.Millisecond)
+	_, err = svc.InspectAccessToken(ctx, tp.AccessToken)
+	assert.ErrorIs(t, err, ErrSessionIdleTimeout)
+}
+
+func TestSessionMaxLifetime(t *testing.T) {
+	cfg := Config{
+		Secret:             "test-secret-key-32-chars-minimum",
+		Issuer:             "test",
+		AccessTokenExpire:  time.Minute,
+		RefreshTokenExpire: time.Hour,
+		MaxSessionLifetime: 30 * time.Minute,
+	}
+	svc := NewJwtService(cfg, cache2.NewShardedMemoryCache(0))
+	ctx := context.Background()
+
+	tp, err := svc.GenerateTokenPair(ctx, 1, "user", "email")
+	require.NoError(t, err)
+	session, err := svc.ListUserSessions(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, session, 1)
+	end := session[0].ExpiresAt
+	assert.WithinDuration(t, time.Now().Add(30*time.Minute), end, time.Second)
+
+	// 轮换后的 refresh token 也不会超过会话绝对过期时间
+	rotated, err := svc.RefreshToken(ctx, tp.RefreshToken)
+	require.NoError(t, err)
+	claims := &CustomClaims{}
+	_, _, err = new(jwtv4.Parser).ParseUnverified(rotated.RefreshToken, claims)
+	require.NoError(t, err)
+	assert.False(t, claims.ExpiresAt.Time.After(end))
+}
+
+func TestImpersonationSession(t *testing.T) {
+	svc := newLimitedJwtSvr(SessionLimit{MaxSessions: 1, Strategy: SessionLimitReject})
+	ctx := context.Background()
+
+	_, err := svc.GenerateTokenPair(ctx, 1, "user", "email")
+	require.NoError(t, err)
+
+	// 模拟会话不受在线设备数限制，有效期只能缩短
+	tp, err := svc.GenerateTokenPair(ctx, 1, "user", "email", WithImpersonator(9), WithSessionLifetime(10*time.Minute))
+	require.NoError(t, err)
+	claims, err := svc.ParseAccessToken(ctx, tp.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, uint(9), claims.ImpersonatorID)
+
+	session := svc.GetSession(ctx, claims.SessionID)
+	require.NotNil(t, session)
+	assert.Equal(t, uint(9), session.ImpersonatorID)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), session.ExpiresAt, time.Second)
+
+	// 刷新后仍然带有模拟标记
+	rotated, err := svc.RefreshToken(ctx, tp.RefreshToken)
+	require.NoError(t, err)
+	claims, err = svc.ParseAccessToken(ctx, rotated.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, uint(9), claims.ImpersonatorID)
+}
+
+func TestImpersonationKeepsUserSessions(t *testing.T) {
+	svc, cache := getJwtSvr()
+	ctx := context.Background()
+
+	tp, err := svc.GenerateTokenPair(ctx, 1, "user", "email")
+	require.NoError(t, err)
+	_, err = svc.GenerateTokenPair(ctx, 1, "user", "email", WithImpersonator(9), WithSessionLifetime(time.Second))
+	require.NoError(t, err)
+
+	// 短期的模拟会话不会缩短在线集合的过期时间
+	ttl, err := cache.TTL(ctx, "jwt:user:1:sessions")
+	require.NoError(t, err)
+	assert.Greater(t, ttl, 50*time.Minute)
+
+	// 模拟会话到期后，退出所有设备仍能撤销正常登录的会话
+	time.Sleep(1100 * time.Millisecond)
+	sessions, err := svc.ListUserSessions(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	require.NoError(t, svc.RevokeUserAllSessions(ctx, 1))
+	_, err = svc.RefreshToken(ctx, tp.RefreshToken)
+	assert.Error(t, err)
+}
+
+func TestTenantClaims(t *testing.T) {
+	svc, _ := getJwtSvr()
+	ctx := context.Background()
+
+	tp, err := svc.GenerateTokenPair(ctx, 1, "user", "email", WithTenant(3))
+	require.NoError(t, err)
+	claims, err := svc.ParseAccessToken(ctx, tp.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), claims.TenantID)
+
+	// 刷新后仍属于同一租户
+	rotated, err := svc.RefreshToken(ctx, tp.RefreshToken)
+	require.NoError(t, err)
+	claims, err = svc.ParseAccessToken(ctx, rotated.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), claims.TenantID)
+}
+
+func TestParseRefreshToken(t *testing.T) {
+	svc, _ := getJwtSvr()
+	ctx := context.Background()
+
+	tp, err := svc.GenerateTokenPair(ctx, 1, "user", "email")
+	require.NoError(t, err)
+	claims, err := svc.ParseRefreshToken(ctx, tp.RefreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, TokenTypeRefresh, claims.TokenType)
+
+	// access token 不能作为 refresh token 解析
+	_, err = svc.ParseRefreshToken(ctx, tp.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// 轮换后旧的 refresh token 失效，但不会触发重用处置
+	rotated, err := svc.RefreshToken(ctx, tp.RefreshToken)
+	require.NoError(t, err)
+	_, err = svc.ParseRefreshToken(ctx, tp.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = svc.ParseRefreshToken(ctx, rotated.RefreshToken)
+	assert.NoError(t, err)
+
+	require.NoError(t, svc.RevokeSession(ctx, claims.SessionID))
+	_, err = svc.ParseRefreshToken(ctx, rotated.RefreshToken)
+	assert.Error(t, err)
+}
@@ -2,21 +2,36 @@ package jwt
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	_interface "gin-admin/pkg/interface"
 	"github.com/sirupsen/logrus"
+	"sort"
 	"time"
 )
 
+var errSessionLockTimeout = errors.New("acquire user session lock timeout")
+
+const (
+	sessionLockTTL     = 5 * time.Second       // 用户会话锁的最长持有时间，防止进程异常退出导致死锁
+	sessionLockWait    = 2 * time.Second       // 等待用户会话锁的最长时间
+	sessionLockBackoff = 20 * time.Millisecond // 重试间隔
+)
+
 // SessionManager 会话管理器接口
-// TODO
-// 1. 提供查看用户在线设备列表
-// 2. 可限制用户在线设备数量
 type SessionManager interface {
 	SaveSession(ctx context.Context, s SessionInfo) error
+	// SaveSessionWithLimit 保存会话，同时保证用户在线会话数不超过 limit（<=0 表示不限制）
+	// 超限时按 strategy 踢掉最早登录的会话或返回 ErrSessionLimitExceeded，整个过程对同一用户是原子的
+	SaveSessionWithLimit(ctx context.Context, s SessionInfo, limit int, strategy string) error
 	GetSession(ctx context.Context, sessionID interface{}) *SessionInfo
 	RemoveSession(ctx context.Context, sessionID string) error
-	UpdateRefreshHash(ctx context.Context, sessionID, hash string) error
+	// UpdateRefreshHash token 轮换时更新 refresh hash，同时刷新最近活跃时间和客户端信息
+	UpdateRefreshHash(ctx context.Context, sessionID, hash string, client ClientInfo) error
+	// Touch 更新会话最近活跃时间，不得覆盖 refresh hash 等其他字段（可能与轮换并发）
+	Touch(ctx context.Context, s *SessionInfo, at time.Time) error
+	// GetUserSessions 获取用户所有在线会话（在线设备列表）
+	GetUserSessions(ctx context.Context, userID uint) ([]*SessionInfo, error)
 	RemoveUserSessions(ctx context.Context, userID uint) error
 }
 type CacheSessionManager struct {
@@ -35,6 +50,15 @@ func (m *CacheSessionManager) userSessionsKey(uid uint) string {
 	return fmt.Sprintf("jwt:user:%+v:sessions", uid)
 }
 
+// sessionSeenKey 最近活跃时间单独存储，避免与 refresh hash 的轮换写入互相覆盖
+func (m *CacheSessionManager) sessionSeenKey(id interface{}) string {
+	return fmt.Sprintf("jwt:session:%+v:seen", id)
+}
+
+func (m *CacheSessionManager) userSessionsLockKey(uid uint) string {
+	return m.userSessionsKey(uid) + ":lock"
+}
+
 // SaveSession 保存 session
 func (m *CacheSessionManager) SaveSession(ctx context.Context, s SessionInfo) error {
 	if m.cache == nil {
@@ -43,12 +67,98 @@ func (m *CacheSessionManager) SaveSession(ctx context.Context, s SessionInfo) er
 
 	ttl := time.Until(s.ExpiresAt)
 	key := m.sessionKey(s.SessionID)
+	userKey := m.userSessionsKey(s.UserID)
 	pipe := m.cache.Pipeline()
 	pipe.Set(ctx, key, s, ttl)
-	pipe.SAdd(ctx, m.userSessionsKey(s.UserID), s.SessionID)
-	pipe.Expire(ctx, m.userSessionsKey(s.UserID), ttl)
-	err := pipe.Exec(ctx)
-	return err
+	pipe.SAdd(ctx, userKey, s.SessionID)
+	if err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	// 在线集合的过期时间只延长不缩短，避免短期会话（如模拟登录）到期时带走用户的其他会话
+	current, err := m.cache.TTL(ctx, userKey)
+	if err != nil {
+		return err
+	}
+	if current < ttl {
+		return m.cache.Expire(ctx, userKey, ttl)
+	}
+	return nil
+}
+
+// SaveSessionWithLimit 在用户会话锁内检查在线数量并保存 session
+func (m *CacheSessionManager) SaveSessionWithLimit(ctx context.Context, s SessionInfo, limit int, strategy string) error {
+	if m.cache == nil || limit <= 0 {
+		return m.SaveSession(ctx, s)
+	}
+
+	unlock, err := m.lockUser(ctx, s.UserID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	sessions, err := m.GetUserSessions(ctx, s.UserID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	active := make([]*SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		if !session.Revoked && !session.Idle(now) {
+			active = append(active, session)
+		}
+	}
+	if overflow := len(active) - limit + 1; overflow > 0 {
+		if strategy == SessionLimitReject {
+			return ErrSessionLimitExceeded
+		}
+		// 踢掉最早登录的会话，保留撤销标记以便被踢设备收到明确的提示
+		sort.Slice(active, func(i, j int) bool {
+			return active[i].CreatedAt.Before(active[j].CreatedAt)
+		})
+		for _, session := range active[:overflow] {
+			if err = m.evictSession(ctx, session); err != nil {
+				return err
+			}
+		}
+	}
+	return m.SaveSession(ctx, s)
+}
+
+// evictSession 标记会话被挤下线，并从用户在线集合中移除
+func (m *CacheSessionManager) evictSession(ctx context.Context, s *SessionInfo) error {
+	s.Revoked = true
+	s.RevokeReason = RevokeReasonEvicted
+	pipe := m.cache.Pipeline()
+	pipe.Set(ctx, m.sessionKey(s.SessionID), *s, time.Until(s.ExpiresAt))
+	pipe.SRem(ctx, m.userSessionsKey(s.UserID), s.SessionID)
+	return pipe.Exec(ctx)
+}
+
+// lockUser 获取用户级会话锁（基于 SetNX 的简单互斥锁，兼容内存缓存和 Redis）
+// 锁与过期时间原子写入，持有锁的进程异常退出时锁到期自动释放
+func (m *CacheSessionManager) lockUser(ctx context.Context, uid uint) (func(), error) {
+	key := m.userSessionsLockKey(uid)
+	deadline := time.Now().Add(sessionLockWait)
+	for {
+		locked, err := m.cache.SetNX(ctx, key, 1, sessionLockTTL)
+		if err != nil {
+			return nil, err
+		}
+		if locked {
+			return func() {
+				_ = m.cache.Delete(context.Background(), key)
+			}, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, errSessionLockTimeout
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(sessionLockBackoff):
+		}
+	}
 }
 
 func (m *CacheSessionManager) GetSession(ctx context.Context, sessionID interface{}) *SessionInfo {
@@ -62,9 +172,23 @@ func (m *CacheSessionManager) GetSession(ctx context.Context, sessionID interfac
 		logrus.Errorf("failed to get session from cache :%s", err.Error())
 		return nil
 	}
+	var seen int64
+	if err = m.cache.Get(ctx, m.sessionSeenKey(sessionID), &seen); err == nil {
+		if at := time.Unix(0, seen); at.After(s.LastSeenAt) {
+			s.LastSeenAt = at
+		}
+	}
 	return &s
 }
 
+// Touch 更新最近活跃时间
+func (m *CacheSessionManager) Touch(ctx context.Context, s *SessionInfo, at time.Time) error {
+	if m.cache == nil {
+		return nil
+	}
+	return m.cache.Set(ctx, m.sessionSeenKey(s.SessionID), at.UnixNano(), time.Until(s.ExpiresAt))
+}
+
 func (m *CacheSessionManager) RemoveSession(ctx context.Context, sessionID string) error {
 	if m.cache == nil {
 		return nil
@@ -75,13 +199,13 @@ func (m *CacheSessionManager) RemoveSession(ctx context.Context, sessionID strin
 		return nil
 	}
 	pipe := m.cache.Pipeline()
-	pipe.Del(ctx, m.sessionKey(sessionID))
+	pipe.Del(ctx, m.sessionKey(sessionID), m.sessionSeenKey(sessionID))
 	pipe.SRem(ctx, m.userSessionsKey(s.UserID), sessionID)
 	return pipe.Exec(ctx)
 }
 
 // UpdateRefreshHash 刷新token 更新 sessionId的新token 防重入
-func (m *CacheSessionManager) UpdateRefreshHash(ctx context.Context, sessionID, hash string) error {
+func (m *CacheSessionManager) UpdateRefreshHash(ctx context.Context, sessionID, hash string, client ClientInfo) error {
 	if m.cache == nil {
 		return nil
 	}
@@ -91,6 +215,13 @@ func (m *CacheSessionManager) UpdateRefreshHash(ctx context.Context, sessionID,
 		return nil
 	}
 	s.RefreshTokenHash = hash
+	s.LastSeenAt = time.Now()
+	if client.IP != "" {
+		s.IP = client.IP
+	}
+	if client.UserAgent != "" {
+		s.UserAgent = client.UserAgent
+	}
 	ttl := time.Until(s.ExpiresAt)
 	return m.cache.Set(ctx, m.sessionKey(sessionID), *s, ttl)
 }
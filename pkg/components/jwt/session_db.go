@@ -0,0 +1,259 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/9 下午3:20
+* @Package: 基于数据库的会话存储，重启不丢失，多副本共享
+ */
+
+// defaultSessionCleanupInterval 过期会话的默认清理周期
+const defaultSessionCleanupInterval = time.Hour
+
+// SessionRecord 会话表
+type SessionRecord struct {
+	ID               uint          `gorm:"primarykey"`
+	SessionID        string        `gorm:"size:64;not null;uniqueIndex"`
+	UserID           uint          `gorm:"not null;index:idx_sessions_user_expires,priority:1"`
+	Username         string        `gorm:"size:50"`
+	RefreshTokenHash string        `gorm:"size:64;not null"`
+	DeviceID         string        `gorm:"size:128"`
+	IP               string        `gorm:"size:64"`
+	UserAgent        string        `gorm:"size:255"`
+	CreatedAt        time.Time     `gorm:"not null"`
+	LastSeenAt       time.Time     `gorm:"not null"`
+	ExpiresAt        time.Time     `gorm:"not null;index:idx_sessions_user_expires,priority:2;index"`
+	IdleTimeout      time.Duration `gorm:"not null;default:0"`
+	Revoked          bool          `gorm:"not null;default:false"`
+	RevokeReason     string        `gorm:"size:32"`
+	ImpersonatorID   uint          `gorm:"not null;default:0"`
+}
+
+func (SessionRecord) TableName() string {
+	return "sessions"
+}
+
+// SessionLock 用户会话锁表，每个用户一行，登录时锁定该行以串行化同一用户的在线会话数检查
+// 用户还没有任何会话时 sessions 表中没有可锁的行，因此单独建表
+type SessionLock struct {
+	UserID   uint      `gorm:"primarykey;autoIncrement:false"`
+	LockedAt time.Time `gorm:"not null"`
+}
+
+func (SessionLock) TableName() string {
+	return "session_locks"
+}
+
+func newSessionRecord(s SessionInfo) *SessionRecord {
+	return &SessionRecord{
+		SessionID:        s.SessionID,
+		UserID:           s.UserID,
+		Username:         s.Username,
+		RefreshTokenHash: s.RefreshTokenHash,
+		DeviceID:         s.DeviceID,
+		IP:               s.IP,
+		UserAgent:        s.UserAgent,
+		CreatedAt:        s.CreatedAt,
+		LastSeenAt:       s.LastSeenAt,
+		ExpiresAt:        s.ExpiresAt,
+		IdleTimeout:      s.IdleTimeout,
+		Revoked:          s.Revoked,
+		RevokeReason:     s.RevokeReason,
+		ImpersonatorID:   s.ImpersonatorID,
+	}
+}
+
+func (r SessionRecord) info() *SessionInfo {
+	return &SessionInfo{
+		SessionID:        r.SessionID,
+		UserID:           r.UserID,
+		Username:         r.Username,
+		RefreshTokenHash: r.RefreshTokenHash,
+		DeviceID:         r.DeviceID,
+		IP:               r.IP,
+		UserAgent:        r.UserAgent,
+		CreatedAt:        r.CreatedAt,
+		LastSeenAt:       r.LastSeenAt,
+		ExpiresAt:        r.ExpiresAt,
+		IdleTimeout:      r.IdleTimeout,
+		Revoked:          r.Revoked,
+		RevokeReason:     r.RevokeReason,
+		ImpersonatorID:   r.ImpersonatorID,
+	}
+}
+
+// DBSessionManager 基于 GORM 的会话管理器
+// 需要先迁移 SessionRecord（已在 migrates 的 session 分组中注册）
+type DBSessionManager struct {
+	db *gorm.DB
+}
+
+func NewDBSessionManager(db *gorm.DB) *DBSessionManager {
+	return &DBSessionManager{db: db}
+}
+
+// SaveSession 保存 session，相同 SessionID 时覆盖
+func (m *DBSessionManager) SaveSession(ctx context.Context, s SessionInfo) error {
+	return m.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "session_id"}},
+		UpdateAll: true,
+	}).Create(newSessionRecord(s)).Error
+}
+
+// SaveSessionWithLimit 在事务内锁定用户的会话锁记录后检查在线会话数量并保存 session
+func (m *DBSessionManager) SaveSessionWithLimit(ctx context.Context, s SessionInfo, limit int, strategy string) error {
+	if limit <= 0 {
+		return m.SaveSession(ctx, s)
+	}
+	return m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 先写入用户的锁记录：MySQL/PostgreSQL 持有该行的排他锁，SQLite 持有整库写锁，直到事务结束
+		// 同一用户的并发登录在此排队，不会同时通过数量检查
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"locked_at"}),
+		}).Create(&SessionLock{UserID: s.UserID, LockedAt: time.Now()}).Error
+		if err != nil {
+			return err
+		}
+		var active []SessionRecord
+		err = tx.Where("user_id = ? AND revoked = ? AND expires_at > ?", s.UserID, false, time.Now()).
+			Order("created_at ASC").
+			Find(&active).Error
+		if err != nil {
+			return err
+		}
+		// 空闲超时的会话已不可用，不占名额
+		now := time.Now()
+		alive := active[:0]
+		for _, r := range active {
+			if !r.info().Idle(now) {
+				alive = append(alive, r)
+			}
+		}
+		active = alive
+		if overflow := len(active) - limit + 1; overflow > 0 {
+			if strategy == SessionLimitReject {
+				return ErrSessionLimitExceeded
+			}
+			// 踢掉最早登录的会话，保留撤销标记以便被踢设备收到明确的提示
+			ids := make([]string, 0, overflow)
+			for _, r := range active[:overflow] {
+				ids = append(ids, r.SessionID)
+			}
+			err = tx.Model(&SessionRecord{}).Where("session_id IN ?", ids).Updates(map[string]interface{}{
+				"revoked":       true,
+				"revoke_reason": RevokeReasonEvicted,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Create(newSessionRecord(s)).Error
+	})
+}
+
+func (m *DBSessionManager) GetSession(ctx context.Context, sessionID interface{}) *SessionInfo {
+	var r SessionRecord
+	err := m.db.WithContext(ctx).
+		Where("session_id = ? AND expires_at > ?", fmt.Sprint(sessionID), time.Now()).
+		Take(&r).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.Errorf("failed to get session from db :%s", err.Error())
+		}
+		return nil
+	}
+	return r.info()
+}
+
+func (m *DBSessionManager) RemoveSession(ctx context.Context, sessionID string) error {
+	return m.db.WithContext(ctx).Where("session_id = ?", sessionID).Delete(&SessionRecord{}).Error
+}
+
+// UpdateRefreshHash 刷新token 更新 sessionId的新token，已撤销的会话不会被更新
+func (m *DBSessionManager) UpdateRefreshHash(ctx context.Context, sessionID, hash string, client ClientInfo) error {
+	updates := map[string]interface{}{
+		"refresh_token_hash": hash,
+		"last_seen_at":       time.Now(),
+	}
+	if client.IP != "" {
+		updates["ip"] = client.IP
+	}
+	if client.UserAgent != "" {
+		updates["user_agent"] = client.UserAgent
+	}
+	return m.db.WithContext(ctx).Model(&SessionRecord{}).
+		Where("session_id = ? AND revoked = ?", sessionID, false).
+		Updates(updates).Error
+}
+
+// Touch 只更新最近活跃时间
+func (m *DBSessionManager) Touch(ctx context.Context, s *SessionInfo, at time.Time) error {
+	return m.db.WithContext(ctx).Model(&SessionRecord{}).
+		Where("session_id = ?", s.SessionID).
+		Update("last_seen_at", at).Error
+}
+
+// GetUserSessions 获取用户所有未过期、未撤销的会话
+func (m *DBSessionManager) GetUserSessions(ctx context.Context, userID uint) ([]*SessionInfo, error) {
+	var records []SessionRecord
+	err := m.db.WithContext(ctx).
+		Where("user_id = ? AND revoked = ? AND expires_at > ?", userID, false, time.Now()).
+		Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*SessionInfo, 0, len(records))
+	for _, r := range records {
+		list = append(list, r.info())
+	}
+	return list, nil
+}
+
+// RemoveUserSessions 删除用户所有 session（退出所有设备）
+func (m *DBSessionManager) RemoveUserSessions(ctx context.Context, userID uint) error {
+	return m.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&SessionRecord{}).Error
+}
+
+// CleanExpired 删除已过期的会话，返回删除行数
+func (m *DBSessionManager) CleanExpired(ctx context.Context) (int64, error) {
+	result := m.db.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&SessionRecord{})
+	return result.RowsAffected, result.Error
+}
+
+// StartCleanup 后台定期清理过期会话，ctx 取消时退出；interval <= 0 时使用默认周期
+func (m *DBSessionManager) StartCleanup(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultSessionCleanupInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				n, err := m.CleanExpired(ctx)
+				if err != nil {
+					logrus.Errorf("failed to clean expired sessions :%s", err.Error())
+					continue
+				}
+				if n > 0 {
+					logrus.Infof("cleaned %d expired sessions", n)
+				}
+			}
+		}
+	}()
+}
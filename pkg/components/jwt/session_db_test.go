@@ -0,0 +1,166 @@
+package jwt
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	cache2 "gin-admin/pkg/components/cache"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestDBSessionManager(t *testing.T) *DBSessionManager {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	// 内存库每个连接都是独立的数据库
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&SessionRecord{}, &SessionLock{}))
+	return NewDBSessionManager(db)
+}
+
+func TestDBSessionManagerCRUD(t *testing.T) {
+	m := newTestDBSessionManager(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	require.NoError(t, m.SaveSession(ctx, SessionInfo{
+		SessionID:        "s1",
+		UserID:           1,
+		Username:         "user",
+		RefreshTokenHash: "hash-1",
+		DeviceID:         "mobile",
+		CreatedAt:        now,
+		LastSeenAt:       now,
+		ExpiresAt:        now.Add(time.Hour),
+	}))
+	require.NoError(t, m.SaveSession(ctx, SessionInfo{
+		SessionID: "s2", UserID: 1, CreatedAt: now, LastSeenAt: now, ExpiresAt: now.Add(time.Hour),
+	}))
+
+	s := m.GetSession(ctx, "s1")
+	require.NotNil(t, s)
+	assert.Equal(t, "hash-1", s.RefreshTokenHash)
+	assert.Equal(t, "mobile", s.DeviceID)
+	assert.Nil(t, m.GetSession(ctx, "missing"))
+
+	// 轮换：更新 hash 和客户端信息，空值不覆盖
+	require.NoError(t, m.UpdateRefreshHash(ctx, "s1", "hash-2", ClientInfo{IP: "10.0.0.1"}))
+	s = m.GetSession(ctx, "s1")
+	require.NotNil(t, s)
+	assert.Equal(t, "hash-2", s.RefreshTokenHash)
+	assert.Equal(t, "10.0.0.1", s.IP)
+	assert.False(t, s.LastSeenAt.Before(now))
+
+	sessions, err := m.GetUserSessions(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 2)
+
+	require.NoError(t, m.RemoveSession(ctx, "s1"))
+	assert.Nil(t, m.GetSession(ctx, "s1"))
+
+	require.NoError(t, m.RemoveUserSessions(ctx, 1))
+	sessions, err = m.GetUserSessions(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestDBSessionManagerCleanExpired(t *testing.T) {
+	m := newTestDBSessionManager(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	require.NoError(t, m.SaveSession(ctx, SessionInfo{SessionID: "expired", UserID: 1, ExpiresAt: now.Add(-time.Minute)}))
+	require.NoError(t, m.SaveSession(ctx, SessionInfo{SessionID: "alive", UserID: 1, ExpiresAt: now.Add(time.Hour)}))
+
+	// 过期会话不可见
+	assert.Nil(t, m.GetSession(ctx, "expired"))
+
+	n, err := m.CleanExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.NotNil(t, m.GetSession(ctx, "alive"))
+}
+
+func TestJwtServiceWithDBSessionManager(t *testing.T) {
+	cfg := Config{
+		Secret:             "test-secret-key-32-chars-minimum",
+		Issuer:             "test",
+		AccessTokenExpire:  time.Minute,
+		RefreshTokenExpire: time.Hour,
+		SessionLimit:       SessionLimit{MaxSessions: 1},
+	}
+	svc := NewJwtService(cfg, cache2.NewShardedMemoryCache(0), WithSessionManager(newTestDBSessionManager(t)))
+	ctx := context.Background()
+
+	first, err := svc.GenerateTokenPair(ctx, 1, "user", "email")
+	require.NoError(t, err)
+
+	// Token Rotation
+	rotated, err := svc.RefreshToken(ctx, first.RefreshToken)
+	require.NoError(t, err)
+	_, err = svc.RefreshToken(ctx, first.RefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenStolen)
+
+	first, err = svc.GenerateTokenPair(ctx, 1, "user", "email")
+	require.NoError(t, err)
+	_, err = svc.RefreshToken(ctx, rotated.RefreshToken)
+	assert.ErrorIs(t, err, ErrSessionInvalid)
+
+	// 超出在线设备数，最早的会话被挤下线
+	time.Sleep(time.Millisecond)
+	second, err := svc.GenerateTokenPair(ctx, 1, "user", "email")
+	require.NoError(t, err)
+	_, err = svc.ParseAccessToken(ctx, first.AccessToken)
+	assert.ErrorIs(t, err, ErrSessionEvicted)
+	_, err = svc.ParseAccessToken(ctx, second.AccessToken)
+	assert.NoError(t, err)
+}
+
+func TestDBSessionManagerConcurrentLimit(t *testing.T) {
+	// 使用文件库和多个连接，让并发登录真正落在不同的事务中
+	dsn := fmt.Sprintf("file:%s?_busy_timeout=5000&_journal_mode=WAL", filepath.Join(t.TempDir(), "sessions.db"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&SessionRecord{}, &SessionLock{}))
+	m := NewDBSessionManager(db)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	success := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			now := time.Now()
+			err := m.SaveSessionWithLimit(ctx, SessionInfo{
+				SessionID: fmt.Sprintf("s%d", i), UserID: 1, CreatedAt: now, LastSeenAt: now, ExpiresAt: now.Add(time.Hour),
+			}, 3, SessionLimitReject)
+			if err == nil {
+				mu.Lock()
+				success++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 3, success)
+	sessions, err := m.GetUserSessions(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 3)
+}
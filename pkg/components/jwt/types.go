@@ -1,6 +1,7 @@
 package jwt
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -16,6 +17,14 @@ var (
 	ErrRefreshTokenStolen   = errors.New("refresh token stolen or reused")
 	ErrRefreshNotAllowed    = errors.New("refresh not allowed")
 	ErrUnsupportedTokenType = errors.New("unsupported token type")
+	ErrSessionEvicted       = errors.New("session evicted by a newer login")
+	ErrSessionLimitExceeded = errors.New("too many active sessions")
+	ErrSessionIdleTimeout   = errors.New("session idle timeout")
+)
+
+// 会话撤销原因
+const (
+	RevokeReasonEvicted = "evicted" // 超出在线设备数被新登录挤下线
 )
 
 type TokenType string
@@ -42,13 +51,28 @@ type CustomClaims struct {
 	TokenType TokenType `json:"token_type"` // access or refresh
 	DeviceID  string    `json:"device_id,omitempty"`
 	SessionID string    `json:"session_id,omitempty"`
+	// ImpersonatorID 模拟登录时发起模拟的管理员ID，正常登录为 0
+	ImpersonatorID uint `json:"impersonator_id,omitempty"`
+	// TenantID 用户所属租户，平台租户为 0
+	TenantID uint `json:"tenant_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // TokenOptions Token生成选项
 type TokenOptions struct {
-	DeviceID  string // 设备ID
-	SessionID string // 会话ID
+	DeviceID  string   // 设备ID
+	SessionID string   // 会话ID
+	ClientIP  string   // 客户端IP
+	UserAgent string   // 客户端 User-Agent
+	Roles     []string // 用户角色，用于匹配在线设备数的角色覆盖策略
+	// ImpersonatorID 模拟登录的管理员ID，模拟会话不占用被模拟用户的在线设备数
+	ImpersonatorID uint
+	// TenantID 用户所属租户
+	TenantID uint
+	// SessionLifetime 会话绝对有效期，只能比配置的更短，0 表示使用配置
+	SessionLifetime time.Duration
+
+	sessionExpiresAt time.Time // 会话结束时间，token 过期时间不超过该值
 }
 
 // TokenOption Token选项函数类型
@@ -68,6 +92,42 @@ func WithSessionID(sessionID string) TokenOption {
 	}
 }
 
+// WithClientInfo 设置客户端信息（IP、User-Agent），写入会话用于在线设备展示
+func WithClientInfo(ip, userAgent string) TokenOption {
+	return func(o *TokenOptions) {
+		o.ClientIP = ip
+		o.UserAgent = userAgent
+	}
+}
+
+// WithImpersonator 模拟登录，记录发起模拟的管理员ID
+func WithImpersonator(impersonatorID uint) TokenOption {
+	return func(o *TokenOptions) {
+		o.ImpersonatorID = impersonatorID
+	}
+}
+
+// WithTenant 设置用户所属租户，写入令牌并随轮换保留
+func WithTenant(tenantID uint) TokenOption {
+	return func(o *TokenOptions) {
+		o.TenantID = tenantID
+	}
+}
+
+// WithSessionLifetime 缩短会话绝对有效期，到期后不能再刷新
+func WithSessionLifetime(lifetime time.Duration) TokenOption {
+	return func(o *TokenOptions) {
+		o.SessionLifetime = lifetime
+	}
+}
+
+// WithRoles 设置用户角色，用于计算在线设备数限制
+func WithRoles(roles ...string) TokenOption {
+	return func(o *TokenOptions) {
+		o.Roles = roles
+	}
+}
+
 // TokenMetadata Token元数据
 type TokenMetadata struct {
 	UserID    uint
@@ -90,12 +150,54 @@ func WithSessionManager(manager SessionManager) ServiceOption {
 	}
 }
 
+// WithReuseHandler 设置 refresh token 重用事件处理函数，用于持久化安全事件、锁定账号等
+func WithReuseHandler(handler ReuseHandler) ServiceOption {
+	return func(s *JWTService) {
+		s.reuseHandler = handler
+	}
+}
+
+// ReuseEvent refresh token 重用（疑似被盗用）事件
+type ReuseEvent struct {
+	UserID     uint
+	TenantID   uint // 用户所属租户
+	Username   string
+	SessionID  string
+	DeviceID   string
+	IP         string // 提交重用 token 的客户端 IP
+	UserAgent  string // 提交重用 token 的客户端 User-Agent
+	Response   string // 已执行的处置级别
+	DetectedAt time.Time
+}
+
+// ReuseHandler refresh token 重用事件处理函数
+type ReuseHandler func(ctx context.Context, event ReuseEvent)
+
 // SessionInfo 会话信息
 type SessionInfo struct {
-	SessionID        string    `json:"session_id"`
-	UserID           uint      `json:"user_id"`
-	Username         string    `json:"username"`
-	RefreshTokenHash string    `json:"refresh_hash"`
-	ExpiresAt        time.Time `json:"expires_at"`
-	Revoked          bool      `json:"revoked"`
+	SessionID        string        `json:"session_id"`
+	UserID           uint          `json:"user_id"`
+	Username         string        `json:"username"`
+	RefreshTokenHash string        `json:"refresh_hash"`
+	DeviceID         string        `json:"device_id"`
+	IP               string        `json:"ip"`
+	UserAgent        string        `json:"user_agent"`
+	CreatedAt        time.Time     `json:"created_at"`
+	LastSeenAt       time.Time     `json:"last_seen_at"`
+	ExpiresAt        time.Time     `json:"expires_at"`             // 会话绝对过期时间
+	IdleTimeout      time.Duration `json:"idle_timeout,omitempty"` // 空闲超时，0 表示不限制
+	Revoked          bool          `json:"revoked"`
+	RevokeReason     string        `json:"revoke_reason,omitempty"`
+	ImpersonatorID   uint          `json:"impersonator_id,omitempty"` // 模拟登录的管理员ID
+}
+
+// Idle 会话是否已空闲超时
+func (s *SessionInfo) Idle(now time.Time) bool {
+	return s.IdleTimeout > 0 && now.Sub(s.LastSeenAt) > s.IdleTimeout
+}
+
+// ClientInfo 客户端信息，刷新 token 时同步到会话
+type ClientInfo struct {
+	IP        string
+	UserAgent string
 }
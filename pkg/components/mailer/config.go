@@ -0,0 +1,39 @@
+package mailer
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/12 上午10:20
+* @Package:
+ */
+
+const (
+	TypeSMTP = "smtp" // 通过 SMTP 服务器发送
+	TypeFile = "file" // 写入本地发件箱目录，用于开发、测试环境
+)
+
+// SMTPConfig SMTP 服务器配置
+type SMTPConfig struct {
+	Host     string `mapstructure:"host" validate:"required"`
+	Port     int    `mapstructure:"port" validate:"required"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	// ImplicitTLS 直接使用 TLS 连接（通常是 465 端口），否则在服务器支持时使用 STARTTLS
+	ImplicitTLS bool `mapstructure:"implicit_tls"`
+}
+
+// FileConfig 发件箱配置
+type FileConfig struct {
+	// Dir 邮件保存目录，每封邮件一个 .eml 文件
+	Dir string `mapstructure:"dir" default:"./outbox"`
+}
+
+// Config 邮件配置
+type Config struct {
+	// Type smtp | file，默认 file
+	Type string `mapstructure:"type" validate:"omitempty,oneof=smtp file"`
+	// From 发件人，如 "Gin Admin <noreply@example.com>"
+	From string      `mapstructure:"from" validate:"required"`
+	SMTP *SMTPConfig `mapstructure:"smtp" validate:"required_if=Type smtp,omitempty"`
+	File *FileConfig `mapstructure:"file" validate:"omitempty"`
+}
@@ -0,0 +1,97 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	_interface "gin-admin/pkg/interface"
+	"mime"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/12 上午10:20
+* @Package:
+ */
+
+var ErrNoRecipient = errors.New("mailer: no recipient")
+
+// NewMailer 根据配置创建邮件发送器
+func NewMailer(cfg Config) (_interface.IMailer, error) {
+	if _, err := mail.ParseAddress(cfg.From); err != nil {
+		return nil, fmt.Errorf("mailer: invalid from address %q: %w", cfg.From, err)
+	}
+	switch cfg.Type {
+	case TypeSMTP:
+		if cfg.SMTP == nil {
+			return nil, errors.New("mailer: smtp config is required")
+		}
+		return NewSMTPMailer(cfg.From, *cfg.SMTP), nil
+	case TypeFile, "":
+		dir := "./outbox"
+		if cfg.File != nil && cfg.File.Dir != "" {
+			dir = cfg.File.Dir
+		}
+		return NewFileMailer(cfg.From, dir), nil
+	}
+	return nil, fmt.Errorf("mailer: unsupported type %q", cfg.Type)
+}
+
+// buildMessage 生成 RFC 5322 格式的邮件，正文使用 base64 编码以支持中文
+func buildMessage(from string, msg *_interface.MailMessage, now time.Time) ([]byte, error) {
+	if len(msg.To) == 0 {
+		return nil, ErrNoRecipient
+	}
+	// 校验收件人地址，同时避免换行符注入额外的邮件头
+	for _, to := range msg.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return nil, fmt.Errorf("mailer: invalid recipient %q: %w", to, err)
+		}
+	}
+	contentType := "text/plain"
+	if msg.HTML {
+		contentType = "text/html"
+	}
+	var buf bytes.Buffer
+	buf.WriteString("From: " + from + "\r\n")
+	buf.WriteString("To: " + strings.Join(msg.To, ", ") + "\r\n")
+	buf.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", msg.Subject) + "\r\n")
+	buf.WriteString("Date: " + now.Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("Message-ID: " + messageID(from) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: " + contentType + "; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	buf.WriteString(wrapBase64([]byte(msg.Body)))
+	return buf.Bytes(), nil
+}
+
+func messageID(from string) string {
+	domain := "localhost"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if i := strings.LastIndex(addr.Address, "@"); i >= 0 {
+			domain = addr.Address[i+1:]
+		}
+	}
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
+
+// wrapBase64 按 RFC 2045 每行不超过 76 个字符
+func wrapBase64(data []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var sb strings.Builder
+	for len(encoded) > 76 {
+		sb.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	sb.WriteString(encoded + "\r\n")
+	return sb.String()
+}
@@ -0,0 +1,129 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_interface "gin-admin/pkg/interface"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readBody(t *testing.T, m *mail.Message) string {
+	raw, err := io.ReadAll(m.Body)
+	require.NoError(t, err)
+	body, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(raw), "\r\n", ""))
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewMailer(Config{Type: TypeFile, From: "Gin Admin <noreply@example.com>", File: &FileConfig{Dir: dir}})
+	require.NoError(t, err)
+
+	err = m.Send(context.Background(), &_interface.MailMessage{
+		To:      []string{"john@example.com"},
+		Subject: "重置密码",
+		Body:    "点击链接重置密码",
+	})
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	f, err := os.Open(files[0])
+	require.NoError(t, err)
+	defer f.Close()
+	msg, err := mail.ReadMessage(f)
+	require.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "重置密码", subject)
+	assert.Equal(t, "john@example.com", msg.Header.Get("To"))
+	assert.Equal(t, "点击链接重置密码", readBody(t, msg))
+}
+
+func TestBuildMessageRejectsInvalidRecipient(t *testing.T) {
+	m := NewFileMailer("noreply@example.com", t.TempDir())
+	err := m.Send(context.Background(), &_interface.MailMessage{Subject: "hi"})
+	assert.ErrorIs(t, err, ErrNoRecipient)
+
+	err = m.Send(context.Background(), &_interface.MailMessage{To: []string{"a@example.com\r\nBcc: evil@example.com"}})
+	assert.Error(t, err)
+}
+
+// fakeSMTPServer 只实现发送一封邮件所需的最少命令
+func fakeSMTPServer(t *testing.T) (port int, received chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+	received = make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { _, _ = conn.Write([]byte(s + "\r\n")) }
+		reply("220 fake ESMTP")
+		var data strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 fake")
+			case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"):
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				reply("250 queued")
+				received <- data.String()
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, received
+}
+
+func TestSMTPMailer(t *testing.T) {
+	port, received := fakeSMTPServer(t)
+	m, err := NewMailer(Config{Type: TypeSMTP, From: "noreply@example.com", SMTP: &SMTPConfig{Host: "127.0.0.1", Port: port}})
+	require.NoError(t, err)
+
+	err = m.Send(context.Background(), &_interface.MailMessage{To: []string{"john@example.com"}, Subject: "hi", Body: "hello"})
+	require.NoError(t, err)
+
+	msg, err := mail.ReadMessage(strings.NewReader(<-received))
+	require.NoError(t, err)
+	assert.Equal(t, "john@example.com", msg.Header.Get("To"))
+	assert.Equal(t, "hello", readBody(t, msg))
+	assert.NotEmpty(t, msg.Header.Get("Message-ID"))
+}
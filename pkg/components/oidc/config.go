@@ -0,0 +1,39 @@
+package oidc
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/12 下午3:10
+* @Package:
+ */
+
+// ProviderConfig 第三方登录提供方配置
+// 配置 Issuer 时通过 {issuer}/.well-known/openid-configuration 自动发现各端点；
+// 不支持 OIDC 发现的纯 OAuth2 提供方需手动配置 AuthURL、TokenURL、UserInfoURL
+type ProviderConfig struct {
+	// Name 提供方标识，用于回调地址 /users/oauth/{name}/callback 和身份关联
+	Name string `mapstructure:"name" validate:"required,alphanum"`
+	// DisplayName 登录按钮上显示的名称
+	DisplayName  string   `mapstructure:"display_name"`
+	Issuer       string   `mapstructure:"issuer" validate:"required_without=AuthURL,omitempty,url"`
+	ClientID     string   `mapstructure:"client_id" validate:"required"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	RedirectURL  string   `mapstructure:"redirect_url" validate:"required,url"`
+	Scopes       []string `mapstructure:"scopes"` // 默认 openid email profile
+	// 手动配置的端点，优先于自动发现的结果
+	AuthURL     string `mapstructure:"auth_url" validate:"omitempty,url"`
+	TokenURL    string `mapstructure:"token_url" validate:"required_with=AuthURL,omitempty,url"`
+	UserInfoURL string `mapstructure:"userinfo_url" validate:"omitempty,url"`
+	JWKSURL     string `mapstructure:"jwks_url" validate:"omitempty,url"`
+	// DefaultRole 首次登录自动创建用户时分配的角色名称
+	DefaultRole string `mapstructure:"default_role"`
+	// LinkByEmail 首次登录时若已存在相同（且已被提供方验证的）邮箱的用户，直接关联到该用户
+	LinkByEmail bool `mapstructure:"link_by_email"`
+}
+
+func (c ProviderConfig) scopes() []string {
+	if len(c.Scopes) == 0 {
+		return []string{"openid", "email", "profile"}
+	}
+	return c.Scopes
+}
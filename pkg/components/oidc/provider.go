@@ -0,0 +1,382 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jwtcomp "gin-admin/pkg/components/jwt"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/12 下午3:10
+* @Package: OIDC / OAuth2 授权码 + PKCE 登录客户端
+ */
+
+var (
+	ErrInvalidIDToken = errors.New("oidc: invalid id token")
+	ErrNonceMismatch  = errors.New("oidc: nonce mismatch")
+	ErrNoSubject      = errors.New("oidc: subject not found")
+)
+
+// Token 授权码换取的令牌
+type Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// Identity 第三方账号信息
+type Identity struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+	Claims            map[string]interface{}
+}
+
+// endpoints 提供方端点，来自发现文档或手动配置
+type endpoints struct {
+	Issuer        string `json:"issuer"`
+	Authorization string `json:"authorization_endpoint"`
+	Token         string `json:"token_endpoint"`
+	UserInfo      string `json:"userinfo_endpoint"`
+	JWKS          string `json:"jwks_uri"`
+}
+
+// Provider 单个第三方登录提供方，端点和公钥在首次使用时加载并缓存
+type Provider struct {
+	cfg    ProviderConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	endpoints *endpoints
+	jwks      jwtcomp.JSONWebKeySet
+}
+
+// Option Provider 可选项
+type Option func(*Provider)
+
+// WithHTTPClient 自定义 HTTP 客户端（代理、超时、测试）
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *Provider) {
+		p.client = client
+	}
+}
+
+func NewProvider(cfg ProviderConfig, opts ...Option) *Provider {
+	p := &Provider{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Config 提供方配置
+func (p *Provider) Config() ProviderConfig {
+	return p.cfg
+}
+
+// AuthCodeURL 生成跳转到提供方的授权地址
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	ep, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.cfg.ClientID)
+	params.Set("redirect_uri", p.cfg.RedirectURL)
+	params.Set("scope", strings.Join(p.cfg.scopes(), " "))
+	params.Set("state", state)
+	params.Set("code_challenge", CodeChallenge(verifier))
+	params.Set("code_challenge_method", "S256")
+	if nonce != "" {
+		params.Set("nonce", nonce)
+	}
+	sep := "?"
+	if strings.Contains(ep.Authorization, "?") {
+		sep = "&"
+	}
+	return ep.Authorization + sep + params.Encode(), nil
+}
+
+// Exchange 使用授权码和 PKCE verifier 换取令牌
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (*Token, error) {
+	ep, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("code_verifier", verifier)
+	if p.cfg.ClientSecret != "" {
+		form.Set("client_secret", p.cfg.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.Token, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	var token Token
+	if err = p.do(req, &token); err != nil {
+		return nil, fmt.Errorf("oidc: exchange code: %w", err)
+	}
+	if token.AccessToken == "" && token.IDToken == "" {
+		return nil, errors.New("oidc: empty token response")
+	}
+	return &token, nil
+}
+
+// Identity 获取第三方账号信息：有 id_token 时验签并校验 nonce，配置了 userinfo 端点时再补充用户信息
+func (p *Provider) Identity(ctx context.Context, token *Token, nonce string) (*Identity, error) {
+	ep, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	claims := map[string]interface{}{}
+	if token.IDToken != "" {
+		if claims, err = p.verifyIDToken(ctx, ep, token.IDToken, nonce); err != nil {
+			return nil, err
+		}
+	}
+	if ep.UserInfo != "" && token.AccessToken != "" {
+		info, err := p.userInfo(ctx, ep, token.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+		// userinfo 返回的 sub 必须与 id_token 一致（OIDC Core 5.3.2）
+		if sub, ok := claims["sub"]; ok && info["sub"] != sub {
+			return nil, errors.New("oidc: userinfo subject mismatch")
+		}
+		for k, v := range info {
+			if _, exist := claims[k]; !exist {
+				claims[k] = v
+			}
+		}
+	}
+	identity := &Identity{Claims: claims}
+	identity.Subject = claimString(claims, "sub")
+	if identity.Subject == "" {
+		// 部分纯 OAuth2 提供方使用数字 id
+		identity.Subject = claimString(claims, "id")
+	}
+	if identity.Subject == "" {
+		return nil, ErrNoSubject
+	}
+	identity.Email = claimString(claims, "email")
+	identity.Name = claimString(claims, "name")
+	identity.PreferredUsername = claimString(claims, "preferred_username")
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = v
+	case string:
+		identity.EmailVerified = v == "true"
+	}
+	return identity, nil
+}
+
+func (p *Provider) verifyIDToken(ctx context.Context, ep *endpoints, raw, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}))
+	_, err := parser.ParseWithClaims(raw, claims, p.keyfunc(ctx, ep))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidIDToken, err.Error())
+	}
+	if !claims.VerifyIssuer(ep.Issuer, true) {
+		return nil, fmt.Errorf("%w: issuer mismatch", ErrInvalidIDToken)
+	}
+	if !claims.VerifyAudience(p.cfg.ClientID, true) {
+		return nil, fmt.Errorf("%w: audience mismatch", ErrInvalidIDToken)
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("%w: missing exp", ErrInvalidIDToken)
+	}
+	if nonce != "" && claimString(claims, "nonce") != nonce {
+		return nil, ErrNonceMismatch
+	}
+	return claims, nil
+}
+
+// keyfunc 按 kid 查找公钥，找不到时重新拉取一次 JWKS（提供方轮换了密钥）
+func (p *Provider) keyfunc(ctx context.Context, ep *endpoints) jwt.Keyfunc {
+	return func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		if key := p.lookupKey(kid, t.Method.Alg()); key != nil {
+			return key.PublicKey()
+		}
+		if err := p.fetchJWKS(ctx, ep); err != nil {
+			return nil, err
+		}
+		if key := p.lookupKey(kid, t.Method.Alg()); key != nil {
+			return key.PublicKey()
+		}
+		return nil, jwtcomp.ErrKeyNotFound
+	}
+}
+
+func (p *Provider) lookupKey(kid, alg string) *jwtcomp.JSONWebKey {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, k := range p.jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if k.Alg != "" && k.Alg != alg {
+			continue
+		}
+		// 未声明 kid 时只有一把公钥才能确定使用哪个
+		if k.Kid == kid || (kid == "" && len(p.jwks.Keys) == 1) {
+			return &p.jwks.Keys[i]
+		}
+	}
+	return nil
+}
+
+func (p *Provider) fetchJWKS(ctx context.Context, ep *endpoints) error {
+	if ep.JWKS == "" {
+		return errors.New("oidc: jwks_uri not configured")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ep.JWKS, nil)
+	if err != nil {
+		return err
+	}
+	var set jwtcomp.JSONWebKeySet
+	if err = p.do(req, &set); err != nil {
+		return fmt.Errorf("oidc: fetch jwks: %w", err)
+	}
+	p.mu.Lock()
+	p.jwks = set
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *Provider) userInfo(ctx context.Context, ep *endpoints, accessToken string) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ep.UserInfo, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+	info := map[string]interface{}{}
+	if err = p.do(req, &info); err != nil {
+		return nil, fmt.Errorf("oidc: userinfo: %w", err)
+	}
+	return info, nil
+}
+
+// discover 加载端点：手动配置优先，其余从发现文档补齐；成功后缓存
+func (p *Provider) discover(ctx context.Context) (*endpoints, error) {
+	p.mu.Lock()
+	if p.endpoints != nil {
+		defer p.mu.Unlock()
+		return p.endpoints, nil
+	}
+	p.mu.Unlock()
+
+	ep := &endpoints{
+		Issuer:        p.cfg.Issuer,
+		Authorization: p.cfg.AuthURL,
+		Token:         p.cfg.TokenURL,
+		UserInfo:      p.cfg.UserInfoURL,
+		JWKS:          p.cfg.JWKSURL,
+	}
+	if p.cfg.Issuer != "" {
+		wellKnown := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+		if err != nil {
+			return nil, err
+		}
+		var doc endpoints
+		if err = p.do(req, &doc); err != nil {
+			return nil, fmt.Errorf("oidc: discovery: %w", err)
+		}
+		if strings.TrimSuffix(doc.Issuer, "/") != strings.TrimSuffix(p.cfg.Issuer, "/") {
+			return nil, fmt.Errorf("oidc: issuer mismatch, expected %s got %s", p.cfg.Issuer, doc.Issuer)
+		}
+		ep.Issuer = doc.Issuer
+		ep.Authorization = firstNonEmpty(ep.Authorization, doc.Authorization)
+		ep.Token = firstNonEmpty(ep.Token, doc.Token)
+		ep.UserInfo = firstNonEmpty(ep.UserInfo, doc.UserInfo)
+		ep.JWKS = firstNonEmpty(ep.JWKS, doc.JWKS)
+	}
+	if ep.Authorization == "" || ep.Token == "" {
+		return nil, errors.New("oidc: authorization or token endpoint not configured")
+	}
+	p.mu.Lock()
+	p.endpoints = ep
+	p.mu.Unlock()
+	return ep, nil
+}
+
+func (p *Provider) do(req *http.Request, dest interface{}) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, dest)
+}
+
+// RandomString 生成 state、nonce、PKCE verifier 使用的随机串
+func RandomString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// CodeChallenge PKCE S256 challenge（RFC 7636）
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func claimString(claims map[string]interface{}, key string) string {
+	switch v := claims[key].(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	case json.Number:
+		return v.String()
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,191 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	jwtcomp "gin-admin/pkg/components/jwt"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockIssuer 本地 OIDC 提供方：发现文档、JWKS、授权码换令牌（校验 PKCE）、userinfo
+type mockIssuer struct {
+	*httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+
+	mu    sync.Mutex
+	codes map[string]mockCode
+}
+
+type mockCode struct {
+	challenge string
+	nonce     string
+	subject   string
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	m := &mockIssuer{key: key, clientID: "client", codes: map[string]mockCode{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.URL,
+			"authorization_endpoint": m.URL + "/authorize",
+			"token_endpoint":         m.URL + "/token",
+			"userinfo_endpoint":      m.URL + "/userinfo",
+			"jwks_uri":               m.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(jwtcomp.JSONWebKeySet{Keys: []jwtcomp.JSONWebKey{{
+			Kty: "RSA", Kid: "k1", Use: "sig",
+			N: base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		m.mu.Lock()
+		code, ok := m.codes[r.PostForm.Get("code")]
+		delete(m.codes, r.PostForm.Get("code"))
+		m.mu.Unlock()
+		if !ok || CodeChallenge(r.PostForm.Get("code_verifier")) != code.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(Token{
+			AccessToken: "access-" + code.subject,
+			TokenType:   "Bearer",
+			IDToken:     m.idToken(t, code.subject, code.nonce, m.clientID),
+			ExpiresIn:   300,
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		sub := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer access-")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"sub": sub, "preferred_username": "john", "name": "John Doe",
+		})
+	})
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+// authorize 模拟用户在提供方完成登录，返回授权码
+func (m *mockIssuer) authorize(t *testing.T, authURL, subject string) (code, state string) {
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	q := u.Query()
+	require.Equal(t, "S256", q.Get("code_challenge_method"))
+	code = "code-" + subject
+	m.mu.Lock()
+	m.codes[code] = mockCode{challenge: q.Get("code_challenge"), nonce: q.Get("nonce"), subject: subject}
+	m.mu.Unlock()
+	return code, q.Get("state")
+}
+
+func (m *mockIssuer) idToken(t *testing.T, subject, nonce, aud string) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            m.URL,
+		"sub":            subject,
+		"aud":            aud,
+		"exp":            time.Now().Add(time.Minute).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          nonce,
+		"email":          subject + "@example.com",
+		"email_verified": true,
+	})
+	token.Header["kid"] = "k1"
+	raw, err := token.SignedString(m.key)
+	require.NoError(t, err)
+	return raw
+}
+
+func TestProviderAuthorizationCodeFlow(t *testing.T) {
+	issuer := newMockIssuer(t)
+	p := NewProvider(ProviderConfig{
+		Name:        "corp",
+		Issuer:      issuer.URL,
+		ClientID:    issuer.clientID,
+		RedirectURL: "http://localhost/callback",
+	})
+	ctx := context.Background()
+	verifier, err := RandomString()
+	require.NoError(t, err)
+
+	authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(authURL, issuer.URL+"/authorize?"))
+	assert.Contains(t, authURL, "scope=openid+email+profile")
+
+	code, state := issuer.authorize(t, authURL, "u-1")
+	assert.Equal(t, "state-1", state)
+
+	// PKCE verifier 不匹配
+	_, err = p.Exchange(ctx, code, "wrong-verifier")
+	assert.Error(t, err)
+
+	code, _ = issuer.authorize(t, authURL, "u-1")
+	token, err := p.Exchange(ctx, code, verifier)
+	require.NoError(t, err)
+
+	identity, err := p.Identity(ctx, token, "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, "u-1", identity.Subject)
+	assert.Equal(t, "u-1@example.com", identity.Email)
+	assert.True(t, identity.EmailVerified)
+	assert.Equal(t, "john", identity.PreferredUsername)
+	assert.Equal(t, "John Doe", identity.Name)
+
+	_, err = p.Identity(ctx, token, "other-nonce")
+	assert.ErrorIs(t, err, ErrNonceMismatch)
+}
+
+func TestProviderRejectsForeignIDToken(t *testing.T) {
+	issuer := newMockIssuer(t)
+	p := NewProvider(ProviderConfig{Name: "corp", Issuer: issuer.URL, ClientID: issuer.clientID, RedirectURL: "http://localhost/callback"})
+	ctx := context.Background()
+
+	// 签发给其他客户端的 id_token
+	_, err := p.Identity(ctx, &Token{IDToken: issuer.idToken(t, "u-1", "n", "other-client")}, "n")
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+
+	// 其他密钥签名
+	other := newMockIssuer(t)
+	forged := other.idToken(t, "u-1", "n", issuer.clientID)
+	_, err = p.Identity(ctx, &Token{IDToken: forged}, "n")
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+}
+
+func TestProviderManualEndpoints(t *testing.T) {
+	issuer := newMockIssuer(t)
+	// 纯 OAuth2：没有 issuer，只通过 userinfo 获取用户
+	p := NewProvider(ProviderConfig{
+		Name:        "oauth",
+		ClientID:    issuer.clientID,
+		RedirectURL: "http://localhost/callback",
+		AuthURL:     issuer.URL + "/authorize",
+		TokenURL:    issuer.URL + "/token",
+		UserInfoURL: issuer.URL + "/userinfo",
+	})
+	identity, err := p.Identity(context.Background(), &Token{AccessToken: "access-u-2"}, "")
+	require.NoError(t, err)
+	assert.Equal(t, "u-2", identity.Subject)
+	assert.Equal(t, "john", identity.PreferredUsername)
+}
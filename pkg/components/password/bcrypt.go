@@ -0,0 +1,49 @@
+package password
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/13 下午2:00
+* @Package:
+ */
+
+// bcryptAlgorithm 使用 bcrypt 自身的编码格式：$2a$<cost>$<salt+hash>
+type bcryptAlgorithm struct {
+	cost int
+}
+
+func (b *bcryptAlgorithm) costOrDefault() int {
+	if b.cost == 0 {
+		return DefaultBcryptCost
+	}
+	return b.cost
+}
+
+func (b *bcryptAlgorithm) hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), b.costOrDefault())
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+func (b *bcryptAlgorithm) verify(encoded, password string) (bool, bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, ErrInvalidHash
+	}
+	cost, err := bcrypt.Cost([]byte(encoded))
+	if err != nil {
+		return false, false, ErrInvalidHash
+	}
+	return true, cost != b.costOrDefault(), nil
+}
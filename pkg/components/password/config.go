@@ -0,0 +1,66 @@
+package password
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/13 下午2:00
+* @Package:
+ */
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+// Argon2Config argon2id 参数，默认值参考 OWASP 推荐（19 MiB, 2 次迭代, 1 并行度）
+type Argon2Config struct {
+	// Memory 内存开销，单位 KiB
+	Memory      uint32 `mapstructure:"memory" validate:"omitempty,min=8"`
+	Iterations  uint32 `mapstructure:"iterations" validate:"omitempty,min=1"`
+	Parallelism uint8  `mapstructure:"parallelism" validate:"omitempty,min=1"`
+	SaltLength  uint32 `mapstructure:"salt_length" validate:"omitempty,min=8"`
+	KeyLength   uint32 `mapstructure:"key_length" validate:"omitempty,min=16"`
+}
+
+// BcryptConfig bcrypt 参数
+type BcryptConfig struct {
+	Cost int `mapstructure:"cost" validate:"omitempty,min=4,max=31"`
+}
+
+// Config 密码哈希配置
+// 新密码使用 Algorithm 指定的算法；校验时支持所有算法，参数与当前配置不一致的哈希会在登录成功后重新计算
+type Config struct {
+	// Algorithm argon2id | bcrypt，默认 argon2id
+	Algorithm string       `mapstructure:"algorithm" validate:"omitempty,oneof=argon2id bcrypt"`
+	Argon2    Argon2Config `mapstructure:"argon2"`
+	Bcrypt    BcryptConfig `mapstructure:"bcrypt"`
+}
+
+var DefaultArgon2Config = Argon2Config{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+const DefaultBcryptCost = 12
+
+func (c Argon2Config) withDefaults() Argon2Config {
+	if c.Memory == 0 {
+		c.Memory = DefaultArgon2Config.Memory
+	}
+	if c.Iterations == 0 {
+		c.Iterations = DefaultArgon2Config.Iterations
+	}
+	if c.Parallelism == 0 {
+		c.Parallelism = DefaultArgon2Config.Parallelism
+	}
+	if c.SaltLength == 0 {
+		c.SaltLength = DefaultArgon2Config.SaltLength
+	}
+	if c.KeyLength == 0 {
+		c.KeyLength = DefaultArgon2Config.KeyLength
+	}
+	return c
+}
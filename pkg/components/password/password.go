@@ -0,0 +1,115 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/13 下午2:00
+* @Package: 密码哈希（PHC 字符串格式）
+ */
+
+var (
+	ErrUnknownAlgorithm = errors.New("password: unknown hash algorithm")
+	ErrInvalidHash      = errors.New("password: invalid hash format")
+)
+
+// algorithm 单个哈希算法
+type algorithm interface {
+	// hash 计算密码哈希，返回编码后的字符串
+	hash(password string) (string, error)
+	// verify 校验密码，outdated 表示哈希参数与当前配置不一致
+	verify(encoded, password string) (ok, outdated bool, err error)
+}
+
+// Hasher 密码哈希器
+type Hasher interface {
+	// Hash 使用当前配置的算法和参数计算密码哈希
+	Hash(password string) (string, error)
+	// Verify 校验密码，rehash 为 true 时表示应使用 Hash 重新计算并保存
+	Verify(encoded, password string) (ok, rehash bool, err error)
+}
+
+type hasher struct {
+	current    string
+	algorithms map[string]algorithm
+}
+
+// NewHasher 创建密码哈希器
+func NewHasher(cfg Config) Hasher {
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = AlgorithmArgon2id
+	}
+	return &hasher{
+		current: cfg.Algorithm,
+		algorithms: map[string]algorithm{
+			AlgorithmArgon2id: &argon2id{params: cfg.Argon2.withDefaults()},
+			AlgorithmBcrypt:   &bcryptAlgorithm{cost: cfg.Bcrypt.Cost},
+		},
+	}
+}
+
+func (h *hasher) Hash(password string) (string, error) {
+	alg, ok := h.algorithms[h.current]
+	if !ok {
+		return "", ErrUnknownAlgorithm
+	}
+	return alg.hash(password)
+}
+
+func (h *hasher) Verify(encoded, password string) (bool, bool, error) {
+	id := identify(encoded)
+	alg, ok := h.algorithms[id]
+	if !ok {
+		return false, false, ErrUnknownAlgorithm
+	}
+	matched, outdated, err := alg.verify(encoded, password)
+	if err != nil || !matched {
+		return false, false, err
+	}
+	return true, outdated || id != h.current, nil
+}
+
+// identify 识别哈希使用的算法
+// PHC 格式为 $<id>$...；bcrypt 沿用其自身的 $2a$/$2b$/$2y$ 格式
+func identify(encoded string) string {
+	parts := strings.SplitN(encoded, "$", 3)
+	if len(parts) < 3 || parts[0] != "" {
+		return ""
+	}
+	switch parts[1] {
+	case "2a", "2b", "2y":
+		return AlgorithmBcrypt
+	}
+	return parts[1]
+}
+
+var defaultHasher atomic.Value
+
+func init() {
+	defaultHasher.Store(NewHasher(Config{}))
+}
+
+// SetDefault 设置全局默认的哈希器，启动时根据配置调用
+func SetDefault(h Hasher) {
+	defaultHasher.Store(h)
+}
+
+// Default 全局默认的哈希器
+func Default() Hasher {
+	return defaultHasher.Load().(Hasher)
+}
+
+// Hash 使用默认哈希器计算密码哈希
+func Hash(password string) (string, error) {
+	return Default().Hash(password)
+}
+
+// Verify 使用默认哈希器校验密码
+func Verify(encoded, password string) (ok, rehash bool, err error) {
+	return Default().Verify(encoded, password)
+}
@@ -0,0 +1,86 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 测试使用较小的参数，避免拖慢测试
+var testArgon2 = Argon2Config{Memory: 64, Iterations: 1, Parallelism: 1}
+
+func TestArgon2idHashAndVerify(t *testing.T) {
+	h := NewHasher(Config{Algorithm: AlgorithmArgon2id, Argon2: testArgon2})
+	encoded, err := h.Hash("secret123")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encoded, "$argon2id$v=19$m=64,t=1,p=1$"))
+
+	ok, rehash, err := h.Verify(encoded, "secret123")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, rehash)
+
+	ok, _, err = h.Verify(encoded, "wrong")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// 同一密码每次的盐不同
+	other, err := h.Hash("secret123")
+	require.NoError(t, err)
+	assert.NotEqual(t, encoded, other)
+}
+
+func TestRehashWhenOutdated(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("secret123"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	// 算法变更：bcrypt -> argon2id
+	h := NewHasher(Config{Argon2: testArgon2})
+	ok, rehash, err := h.Verify(string(legacy), "secret123")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, rehash)
+
+	// bcrypt cost 变更
+	h = NewHasher(Config{Algorithm: AlgorithmBcrypt, Bcrypt: BcryptConfig{Cost: bcrypt.MinCost + 1}})
+	ok, rehash, err = h.Verify(string(legacy), "secret123")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, rehash)
+
+	// argon2 参数变更
+	old := NewHasher(Config{Argon2: testArgon2})
+	encoded, err := old.Hash("secret123")
+	require.NoError(t, err)
+	stronger := testArgon2
+	stronger.Iterations = 2
+	ok, rehash, err = NewHasher(Config{Argon2: stronger}).Verify(encoded, "secret123")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, rehash)
+
+	// 密码错误时不提示重新计算
+	ok, rehash, err = NewHasher(Config{Argon2: stronger}).Verify(encoded, "wrong")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.False(t, rehash)
+}
+
+func TestVerifyInvalidHash(t *testing.T) {
+	h := NewHasher(Config{Argon2: testArgon2})
+	for _, encoded := range []string{
+		"",
+		"plaintext",
+		"$md5$abc",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA",
+		"$argon2id$v=18$m=64,t=1,p=1$c2FsdHNhbHQ$aGFzaA",
+		"$2a$10$short",
+	} {
+		ok, _, err := h.Verify(encoded, "secret123")
+		assert.False(t, ok, encoded)
+		assert.Error(t, err, encoded)
+	}
+}
@@ -0,0 +1,133 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/13 下午4:30
+* @Package: 密码策略
+ */
+
+// PolicyConfig 密码策略配置
+type PolicyConfig struct {
+	// MinLength 最小长度，默认 8
+	MinLength int `mapstructure:"min_length" validate:"omitempty,min=1"`
+	// MaxLength 最大长度，默认 64（bcrypt 只使用前 72 字节）
+	MaxLength     int  `mapstructure:"max_length" validate:"omitempty,gtefield=MinLength"`
+	RequireUpper  bool `mapstructure:"require_upper"`
+	RequireLower  bool `mapstructure:"require_lower"`
+	RequireDigit  bool `mapstructure:"require_digit"`
+	RequireSymbol bool `mapstructure:"require_symbol"`
+	// Blocklist 额外禁止使用的密码（不区分大小写），内置常见弱密码始终生效
+	Blocklist []string `mapstructure:"blocklist"`
+	// HistorySize 不能与最近 N 次使用过的密码相同，0 表示不限制
+	HistorySize int `mapstructure:"history_size" validate:"omitempty,min=0"`
+	// MaxAge 密码有效期，超过后登录时要求先修改密码，0 表示永不过期
+	MaxAge time.Duration `mapstructure:"max_age"`
+}
+
+// PolicyError 密码不符合策略，Violations 为所有不满足的规则
+type PolicyError struct {
+	Violations []string
+}
+
+func (e *PolicyError) Error() string {
+	return "密码" + strings.Join(e.Violations, "，")
+}
+
+// Policy 密码策略
+type Policy struct {
+	cfg       PolicyConfig
+	blocklist map[string]struct{}
+}
+
+// NewPolicy 创建密码策略
+func NewPolicy(cfg PolicyConfig) *Policy {
+	if cfg.MinLength == 0 {
+		cfg.MinLength = 8
+	}
+	if cfg.MaxLength == 0 {
+		cfg.MaxLength = 64
+	}
+	blocklist := make(map[string]struct{}, len(commonPasswords)+len(cfg.Blocklist))
+	for _, list := range [][]string{commonPasswords, cfg.Blocklist} {
+		for _, p := range list {
+			blocklist[strings.ToLower(p)] = struct{}{}
+		}
+	}
+	return &Policy{cfg: cfg, blocklist: blocklist}
+}
+
+// Config 生效的策略配置（已填充默认值）
+func (p *Policy) Config() PolicyConfig {
+	return p.cfg
+}
+
+// Validate 校验密码是否符合策略，不符合时返回 *PolicyError
+func (p *Policy) Validate(password string) error {
+	var violations []string
+	length := utf8.RuneCountInString(password)
+	if length < p.cfg.MinLength {
+		violations = append(violations, fmt.Sprintf("长度不能少于 %d 位", p.cfg.MinLength))
+	}
+	if length > p.cfg.MaxLength {
+		violations = append(violations, fmt.Sprintf("长度不能超过 %d 位", p.cfg.MaxLength))
+	}
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+	if p.cfg.RequireUpper && !upper {
+		violations = append(violations, "需包含大写字母")
+	}
+	if p.cfg.RequireLower && !lower {
+		violations = append(violations, "需包含小写字母")
+	}
+	if p.cfg.RequireDigit && !digit {
+		violations = append(violations, "需包含数字")
+	}
+	if p.cfg.RequireSymbol && !symbol {
+		violations = append(violations, "需包含特殊字符")
+	}
+	if _, ok := p.blocklist[strings.ToLower(password)]; ok {
+		violations = append(violations, "过于常见，请更换")
+	}
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// Expired 密码是否已超过有效期
+func (p *Policy) Expired(changedAt, now time.Time) bool {
+	return p.cfg.MaxAge > 0 && now.Sub(changedAt) >= p.cfg.MaxAge
+}
+
+// commonPasswords 内置的常见弱密码
+var commonPasswords = []string{
+	"123456", "123456789", "12345678", "1234567890", "12345", "1234567", "123123", "111111", "000000",
+	"654321", "666666", "888888", "121212", "112233", "123321", "1q2w3e4r", "1q2w3e4r5t", "1qaz2wsx",
+	"qwerty", "qwerty123", "qwertyuiop", "asdfghjkl", "zxcvbnm", "abc123", "abc12345", "a123456",
+	"a12345678", "aa123456", "password", "password1", "password123", "passw0rd", "p@ssw0rd", "p@ssword",
+	"admin", "admin123", "admin888", "administrator", "root", "root123", "toor", "test", "test123",
+	"guest", "welcome", "welcome1", "letmein", "iloveyou", "monkey", "dragon", "sunshine", "princess",
+	"football", "baseball", "superman", "batman", "master", "shadow", "trustno1", "changeme", "default",
+	"secret", "qazwsx", "woaini", "woaini1314", "5201314", "147258369", "987654321", "11111111",
+	"88888888", "00000000", "12341234", "123qwe", "qwe123", "zaq12wsx", "!qaz2wsx", "1234qwer",
+}
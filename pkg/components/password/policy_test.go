@@ -0,0 +1,45 @@
+package password
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyValidate(t *testing.T) {
+	p := NewPolicy(PolicyConfig{
+		MinLength:     10,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		Blocklist:     []string{"Company@2025"},
+	})
+	assert.NoError(t, p.Validate("Correct#Horse9"))
+
+	err := p.Validate("short")
+	var pe *PolicyError
+	require.ErrorAs(t, err, &pe)
+	assert.ElementsMatch(t, []string{"长度不能少于 10 位", "需包含大写字母", "需包含数字", "需包含特殊字符"}, pe.Violations)
+
+	// 自定义黑名单不区分大小写
+	require.ErrorAs(t, p.Validate("company@2025"), &pe)
+	require.ErrorAs(t, NewPolicy(PolicyConfig{}).Validate("Password123"), &pe)
+	assert.Equal(t, []string{"过于常见，请更换"}, pe.Violations)
+}
+
+func TestPolicyDefaults(t *testing.T) {
+	p := NewPolicy(PolicyConfig{})
+	assert.Equal(t, 8, p.Config().MinLength)
+	assert.Equal(t, 64, p.Config().MaxLength)
+	assert.Error(t, p.Validate("abc"))
+	assert.NoError(t, p.Validate("plain words only"))
+
+	now := time.Now()
+	assert.False(t, p.Expired(now.AddDate(-10, 0, 0), now), "未配置有效期时永不过期")
+	p = NewPolicy(PolicyConfig{MaxAge: 24 * time.Hour})
+	assert.True(t, p.Expired(now.Add(-25*time.Hour), now))
+	assert.False(t, p.Expired(now.Add(-time.Hour), now))
+}
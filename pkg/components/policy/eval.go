@@ -0,0 +1,196 @@
+package policy
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/17 下午3:00
+* @Package: 条件表达式求值
+ */
+
+// node 类型在编译时已确定，求值结果为 string、float64 或 bool
+type node interface {
+	kind() Kind
+	eval(env Env) (interface{}, error)
+}
+
+type literalNode struct {
+	v interface{}
+	k Kind
+}
+
+func (n *literalNode) kind() Kind { return n.k }
+
+func (n *literalNode) eval(Env) (interface{}, error) { return n.v, nil }
+
+type varNode struct {
+	name string
+	k    Kind
+}
+
+func (n *varNode) kind() Kind { return n.k }
+
+func (n *varNode) eval(env Env) (interface{}, error) {
+	var v interface{}
+	var ok bool
+	if env != nil {
+		v, ok = env(n.name)
+	}
+	if !ok || v == nil {
+		switch n.k {
+		case KindString:
+			return "", nil
+		case KindNumber:
+			return float64(0), nil
+		default:
+			return false, nil
+		}
+	}
+	switch n.k {
+	case KindString:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case KindNumber:
+		rv := reflect.ValueOf(v)
+		switch {
+		case rv.CanInt():
+			return float64(rv.Int()), nil
+		case rv.CanUint():
+			return float64(rv.Uint()), nil
+		case rv.CanFloat():
+			return rv.Float(), nil
+		}
+	case KindBool:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("变量 %s 应为%s，实际为 %T", n.name, n.k, v)
+}
+
+type notNode struct {
+	x node
+}
+
+func (n *notNode) kind() Kind { return KindBool }
+
+func (n *notNode) eval(env Env) (interface{}, error) {
+	v, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return !v.(bool), nil
+}
+
+// logicalNode && 和 || 短路求值
+type logicalNode struct {
+	and         bool
+	left, right node
+}
+
+func (n *logicalNode) kind() Kind { return KindBool }
+
+func (n *logicalNode) eval(env Env) (interface{}, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if l.(bool) != n.and {
+		return l, nil
+	}
+	return n.right.eval(env)
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n *compareNode) kind() Kind { return KindBool }
+
+func (n *compareNode) eval(env Env) (interface{}, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return l == r, nil
+	case "!=":
+		return l != r, nil
+	}
+	var c int
+	switch lv := l.(type) {
+	case float64:
+		c = compare(lv, r.(float64))
+	case string:
+		c = compare(lv, r.(string))
+	}
+	switch n.op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+func compare[T float64 | string](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+type inNode struct {
+	x    node
+	list []interface{}
+}
+
+func (n *inNode) kind() Kind { return KindBool }
+
+func (n *inNode) eval(env Env) (interface{}, error) {
+	v, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range n.list {
+		if item == v {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// cidrNode 无效的 IP 视为不在网段内
+type cidrNode struct {
+	x       node
+	network *net.IPNet
+}
+
+func (n *cidrNode) kind() Kind { return KindBool }
+
+func (n *cidrNode) eval(env Env) (interface{}, error) {
+	v, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	ip := net.ParseIP(v.(string))
+	return ip != nil && n.network.Contains(ip), nil
+}
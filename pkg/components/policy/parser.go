@@ -0,0 +1,251 @@
+package policy
+
+import (
+	"net"
+	"strconv"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/17 下午3:00
+* @Package: 条件表达式语法分析和类型检查
+ */
+
+// 优先级从低到高：|| → && → ! → 比较 / in → 字面量、变量、函数、括号
+type parser struct {
+	tokens []token
+	cur    int
+	schema Schema
+	vars   map[string]struct{}
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.cur]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.cur]
+	if t.kind != tokEOF {
+		p.cur++
+	}
+	return t
+}
+
+// accept 当前是指定的运算符时前进一步
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokOp && t.text == op {
+		p.cur++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if p.accept(op) {
+		return nil
+	}
+	t := p.peek()
+	if t.kind == tokEOF {
+		return errorf(t.pos, "缺少 %q", op)
+	}
+	return errorf(t.pos, "应为 %q，实际为 %q", op, t.text)
+}
+
+func (p *parser) parseExpr(depth int) (node, error) {
+	if depth > maxDepth {
+		return nil, errorf(p.peek().pos, "嵌套层数不能超过 %d", maxDepth)
+	}
+	return p.parseBinary(depth, "||", p.parseAnd)
+}
+
+func (p *parser) parseAnd(depth int) (node, error) {
+	return p.parseBinary(depth, "&&", p.parseNot)
+}
+
+func (p *parser) parseBinary(depth int, op string, operand func(int) (node, error)) (node, error) {
+	left, err := operand(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		pos := p.peek().pos
+		if !p.accept(op) {
+			return left, nil
+		}
+		right, err := operand(depth)
+		if err != nil {
+			return nil, err
+		}
+		if left.kind() != KindBool || right.kind() != KindBool {
+			return nil, errorf(pos, "%s 两侧必须是布尔值", op)
+		}
+		left = &logicalNode{and: op == "&&", left: left, right: right}
+	}
+}
+
+func (p *parser) parseNot(depth int) (node, error) {
+	pos := p.peek().pos
+	if !p.accept("!") {
+		return p.parseCompare(depth)
+	}
+	if depth+1 > maxDepth {
+		return nil, errorf(pos, "嵌套层数不能超过 %d", maxDepth)
+	}
+	x, err := p.parseNot(depth + 1)
+	if err != nil {
+		return nil, err
+	}
+	if x.kind() != KindBool {
+		return nil, errorf(pos, "! 只能用于布尔值")
+	}
+	return &notNode{x: x}, nil
+}
+
+func (p *parser) parseCompare(depth int) (node, error) {
+	left, err := p.parsePrimary(depth)
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if t.kind == tokIdent && t.text == "in" {
+		p.next()
+		return p.parseIn(left, t.pos)
+	}
+	if t.kind != tokOp {
+		return left, nil
+	}
+	switch t.text {
+	case "==", "!=", "<", "<=", ">", ">=":
+	default:
+		return left, nil
+	}
+	p.next()
+	right, err := p.parsePrimary(depth)
+	if err != nil {
+		return nil, err
+	}
+	if left.kind() != right.kind() {
+		return nil, errorf(t.pos, "不能比较%s和%s", left.kind(), right.kind())
+	}
+	if t.text != "==" && t.text != "!=" && left.kind() == KindBool {
+		return nil, errorf(t.pos, "布尔值不能使用 %s", t.text)
+	}
+	return &compareNode{op: t.text, left: left, right: right}, nil
+}
+
+// parseIn 右侧只能是同类型字面量组成的列表
+func (p *parser) parseIn(left node, pos int) (node, error) {
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	n := &inNode{x: left}
+	for {
+		t := p.peek()
+		v, k, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		if k != left.kind() {
+			return nil, errorf(t.pos, "列表元素应为%s", left.kind())
+		}
+		n.list = append(n.list, v)
+		if !p.accept(",") {
+			break
+		}
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (p *parser) parseLiteral() (interface{}, Kind, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokString:
+		return t.text, KindString, nil
+	case t.kind == tokNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, 0, errorf(t.pos, "无效的数字 %q", t.text)
+		}
+		return f, KindNumber, nil
+	case t.kind == tokIdent && (t.text == "true" || t.text == "false"):
+		return t.text == "true", KindBool, nil
+	case t.kind == tokEOF:
+		return nil, 0, errorf(t.pos, "表达式不完整")
+	}
+	return nil, 0, errorf(t.pos, "应为字面量，实际为 %q", t.text)
+}
+
+func (p *parser) parsePrimary(depth int) (node, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokString, tokNumber:
+		v, k, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		return &literalNode{v: v, k: k}, nil
+	case tokIdent:
+		if t.text == "true" || t.text == "false" {
+			v, k, _ := p.parseLiteral()
+			return &literalNode{v: v, k: k}, nil
+		}
+		p.next()
+		if p.accept("(") {
+			return p.parseCall(t, depth)
+		}
+		k, ok := p.schema.lookup(t.text)
+		if !ok {
+			return nil, errorf(t.pos, "未知的变量 %s", t.text)
+		}
+		p.vars[t.text] = struct{}{}
+		return &varNode{name: t.text, k: k}, nil
+	case tokOp:
+		if t.text == "(" {
+			p.next()
+			x, err := p.parseExpr(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		}
+	case tokEOF:
+		return nil, errorf(t.pos, "表达式不完整")
+	}
+	return nil, errorf(t.pos, "意外的 %q", t.text)
+}
+
+// parseCall 目前只有 cidr(ip, "网段")
+func (p *parser) parseCall(fn token, depth int) (node, error) {
+	if fn.text != "cidr" {
+		return nil, errorf(fn.pos, "未知的函数 %s", fn.text)
+	}
+	x, err := p.parseExpr(depth + 1)
+	if err != nil {
+		return nil, err
+	}
+	if x.kind() != KindString {
+		return nil, errorf(fn.pos, "cidr 的第一个参数必须是字符串")
+	}
+	if err = p.expect(","); err != nil {
+		return nil, err
+	}
+	t := p.next()
+	if t.kind != tokString {
+		return nil, errorf(t.pos, "cidr 的第二个参数必须是网段字符串")
+	}
+	_, network, err := net.ParseCIDR(t.text)
+	if err != nil {
+		return nil, errorf(t.pos, "无效的网段 %q", t.text)
+	}
+	if err = p.expect(")"); err != nil {
+		return nil, err
+	}
+	return &cidrNode{x: x, network: network}, nil
+}
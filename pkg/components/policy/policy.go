@@ -0,0 +1,133 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/17 下午3:00
+* @Package: 授权条件表达式
+*
+* 一个只读、无副作用的小型表达式语言，结果必须为布尔值：
+*   字面量：字符串 "a" / 'a'、数字 1 / 1.5、true / false、列表 ["a", "b"]（仅用于 in）
+*   变量：由 Schema 声明，如 ip、time.hour、params.id；未声明的变量在编译时报错
+*   运算：|| && ! == != < <= > >= in，括号分组
+*   函数：cidr(ip, "10.0.0.0/8") 判断 IP 是否在网段内，网段在编译时校验
+* 例：time.hour >= 9 && time.hour < 18 && cidr(ip, "10.0.0.0/8")
+ */
+
+// MaxLength 表达式最大长度
+const MaxLength = 500
+
+// maxDepth 最大嵌套层数，防止恶意构造的表达式导致栈溢出
+const maxDepth = 32
+
+// Kind 值类型
+type Kind int
+
+const (
+	KindString Kind = iota + 1
+	KindNumber
+	KindBool
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindString:
+		return "字符串"
+	case KindNumber:
+		return "数字"
+	case KindBool:
+		return "布尔"
+	}
+	return "未知"
+}
+
+// Schema 可用的变量及其类型，"params.*" 表示 params 下的任意变量
+type Schema map[string]Kind
+
+func (s Schema) lookup(name string) (Kind, bool) {
+	if k, ok := s[name]; ok {
+		return k, true
+	}
+	if i := strings.LastIndex(name, "."); i > 0 && i < len(name)-1 {
+		k, ok := s[name[:i]+".*"]
+		return k, ok
+	}
+	return 0, false
+}
+
+// Env 求值时按变量名取值，字符串、数字（任意整数或浮点类型）或布尔值
+// 变量不存在时按类型的零值处理
+type Env func(name string) (interface{}, bool)
+
+// Error 表达式的语法或类型错误
+type Error struct {
+	Pos int // 从 1 开始的字符位置
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("第 %d 个字符处: %s", e.Pos, e.Msg)
+}
+
+func errorf(pos int, format string, args ...interface{}) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Program 编译后的表达式，可并发求值
+type Program struct {
+	src  string
+	root node
+	vars []string
+}
+
+// Compile 解析并校验表达式：语法、变量是否声明、运算的类型，以及结果是否为布尔值
+func Compile(src string, schema Schema) (*Program, error) {
+	if len([]rune(src)) > MaxLength {
+		return nil, fmt.Errorf("条件表达式不能超过 %d 个字符", MaxLength)
+	}
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, schema: schema, vars: map[string]struct{}{}}
+	root, err := p.parseExpr(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, errorf(t.pos, "多余的 %q", t.text)
+	}
+	if root.kind() != KindBool {
+		return nil, errorf(1, "表达式的结果必须是布尔值，实际为%s", root.kind())
+	}
+	prog := &Program{src: src, root: root}
+	for name := range p.vars {
+		prog.vars = append(prog.vars, name)
+	}
+	sort.Strings(prog.vars)
+	return prog, nil
+}
+
+// Eval 求值，变量类型与 Schema 不一致时返回错误
+func (p *Program) Eval(env Env) (bool, error) {
+	v, err := p.root.eval(env)
+	if err != nil {
+		return false, err
+	}
+	return v.(bool), nil
+}
+
+// Vars 表达式引用的变量，调用方可以只准备用到的变量
+func (p *Program) Vars() []string {
+	return p.vars
+}
+
+func (p *Program) String() string {
+	return p.src
+}
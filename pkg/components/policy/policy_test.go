@@ -0,0 +1,118 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSchema = Schema{
+	"ip":          KindString,
+	"time.hour":   KindNumber,
+	"user.status": KindNumber,
+	"claims.api":  KindBool,
+	"params.*":    KindString,
+}
+
+func envOf(vars map[string]interface{}) Env {
+	return func(name string) (interface{}, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	cases := map[string]string{
+		"":                         "不完整",
+		"time.hour":                "布尔值",
+		"foo == 1":                 "未知的变量",
+		"params == 'a'":            "未知的变量",
+		"time.hour == '9'":         "不能比较",
+		"ip > 1":                   "不能比较",
+		"claims.api < true":        "布尔值不能使用",
+		"time.hour >= 9 &&":        "不完整",
+		"(time.hour >= 9":          "缺少",
+		"time.hour in ['a']":       "列表元素",
+		"time.hour in []":          "应为字面量",
+		"cidr(ip, '10.0.0.0/33')":  "无效的网段",
+		"cidr(ip, params.net)":     "网段字符串",
+		"exec('rm')":               "未知的函数",
+		"ip == 'a":                 "结束引号",
+		"ip == 'a' ; true":         "无法识别",
+		"time.hour >= 9 time.hour": "多余",
+		"!time.hour":               "布尔值",
+		"time.hour < 9 < 10":       "多余",
+		strings.Repeat("(", 40) + "true" + strings.Repeat(")", 40): "嵌套",
+		strings.Repeat("!", 40) + "true":                           "嵌套",
+	}
+	for src, want := range cases {
+		_, err := Compile(src, testSchema)
+		require.Error(t, err, src)
+		assert.Contains(t, err.Error(), want, src)
+	}
+
+	_, err := Compile(strings.Repeat("a", MaxLength+1), testSchema)
+	assert.Error(t, err)
+}
+
+func TestEval(t *testing.T) {
+	env := envOf(map[string]interface{}{
+		"ip":          "10.1.2.3",
+		"time.hour":   10,
+		"user.status": uint8(1),
+		"claims.api":  false,
+		"params.id":   "42",
+	})
+	cases := map[string]bool{
+		"time.hour >= 9 && time.hour < 18":        true,
+		"time.hour >= 11 || time.hour < 10":       false,
+		"cidr(ip, '10.0.0.0/8')":                  true,
+		"cidr(ip, \"192.168.0.0/16\")":            false,
+		"user.status == 1":                        true,
+		"!claims.api":                             true,
+		"claims.api == false":                     true,
+		"params.id in ['1', '42']":                true,
+		"params.id in ['1']":                      false,
+		"params.missing == ''":                    true,
+		"time.hour in [9, 10, 11] && !(ip == '')": true,
+		"'b' > 'a'":                               true,
+		"1.5 <= 1":                                false,
+	}
+	for src, want := range cases {
+		prog, err := Compile(src, testSchema)
+		require.NoError(t, err, src)
+		got, err := prog.Eval(env)
+		require.NoError(t, err, src)
+		assert.Equal(t, want, got, src)
+	}
+}
+
+func TestEval_ShortCircuitAndVars(t *testing.T) {
+	prog, err := Compile("claims.api || user.status == 1 && cidr(ip, '::1/128')", testSchema)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"claims.api", "ip", "user.status"}, prog.Vars())
+
+	// 左侧已确定结果时不读取右侧的变量
+	called := map[string]bool{}
+	ok, err := prog.Eval(func(name string) (interface{}, bool) {
+		called[name] = true
+		return name == "claims.api", true
+	})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]bool{"claims.api": true}, called)
+}
+
+func TestEval_TypeMismatch(t *testing.T) {
+	prog, err := Compile("time.hour > 1", testSchema)
+	require.NoError(t, err)
+	_, err = prog.Eval(envOf(map[string]interface{}{"time.hour": "2"}))
+	assert.Error(t, err)
+
+	// 没有提供的变量按零值处理
+	ok, err := prog.Eval(nil)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
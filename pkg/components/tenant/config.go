@@ -0,0 +1,27 @@
+package tenant
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/17 上午9:30
+* @Package:
+ */
+
+// DefaultHeader 默认的租户请求头
+const DefaultHeader = "X-Tenant-ID"
+
+// Config 多租户配置
+type Config struct {
+	// Enabled 开启后注册 GORM 租户插件，并按请求解析租户
+	Enabled bool `mapstructure:"enabled"`
+	// Header 未登录请求（登录、注册等）指定租户的请求头，平台管理员也通过它切换租户，默认 X-Tenant-ID
+	Header string `mapstructure:"header" validate:"omitempty"`
+}
+
+// HeaderName 租户请求头
+func (c Config) HeaderName() string {
+	if c.Header == "" {
+		return DefaultHeader
+	}
+	return c.Header
+}
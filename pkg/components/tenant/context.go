@@ -0,0 +1,75 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/17 上午9:30
+* @Package: 租户上下文
+ */
+
+const (
+	// PlatformID 平台租户，未指定租户的请求和升级前的存量数据都属于平台租户
+	PlatformID uint = 0
+	// Field 租户隔离的模型通过该字段（列 tenant_id）声明所属租户
+	Field = "TenantID"
+)
+
+type ctxKey struct{}
+
+type skipKey struct{}
+
+// WithID 在上下文中设置当前租户，之后经 GORM 的读写都限定在该租户内
+func WithID(ctx context.Context, id uint) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext 当前租户，上下文中没有租户时 ok=false
+func FromContext(ctx context.Context) (id uint, ok bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	id, ok = ctx.Value(ctxKey{}).(uint)
+	return id, ok
+}
+
+// SkipScope 跳过租户隔离，用于按全局唯一凭证（API Key 哈希等）查找记录，查到后应立即切换到记录所属租户
+func SkipScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipKey{}, true)
+}
+
+// Skipped 是否跳过租户隔离
+func Skipped(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	skip, _ := ctx.Value(skipKey{}).(bool)
+	return skip
+}
+
+// Namespace 缓存键的租户命名空间，上下文中没有租户或跳过隔离时为空
+func Namespace(ctx context.Context) string {
+	id, ok := FromContext(ctx)
+	if !ok || Skipped(ctx) {
+		return ""
+	}
+	return fmt.Sprintf("t%d:", id)
+}
+
+// Scoped 模型是否按租户隔离（包含 TenantID 字段）
+func Scoped(model interface{}) bool {
+	t := reflect.TypeOf(model)
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return false
+	}
+	_, ok := t.FieldByName(Field)
+	return ok
+}
@@ -0,0 +1,102 @@
+package tenant
+
+import (
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/17 上午9:30
+* @Package: GORM 租户插件
+ */
+
+// appliedKey 同一个 Statement 只追加一次租户条件（Count 后复用同一个 Statement 查询列表时）
+const appliedKey = "tenant:applied"
+
+// Plugin GORM 多租户插件
+// 模型包含 TenantID 字段时，查询、更新、删除自动追加当前租户条件，创建时自动写入当前租户
+// 上下文中没有租户（启动初始化、后台任务）或调用了 SkipScope 时不做处理；没有模型的 Table、Raw 查询不受影响
+type Plugin struct{}
+
+func (Plugin) Name() string {
+	return "tenant"
+}
+
+func (Plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().Before("gorm:create").Register("tenant:create", assignTenant); err != nil {
+		return err
+	}
+	if err := cb.Query().Before("gorm:query").Register("tenant:query", scopeTenant); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("tenant:update", scopeTenant); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("tenant:delete", scopeTenant); err != nil {
+		return err
+	}
+	return cb.Row().Before("gorm:row").Register("tenant:row", scopeTenant)
+}
+
+// tenantField 当前语句需要隔离时返回租户字段和租户ID
+func tenantField(db *gorm.DB) (*schema.Field, uint, bool) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return nil, 0, false
+	}
+	ctx := db.Statement.Context
+	id, ok := FromContext(ctx)
+	if !ok || Skipped(ctx) {
+		return nil, 0, false
+	}
+	field := db.Statement.Schema.LookUpField(Field)
+	if field == nil {
+		return nil, 0, false
+	}
+	return field, id, true
+}
+
+// scopeTenant 追加 tenant_id = 当前租户
+func scopeTenant(db *gorm.DB) {
+	field, id, ok := tenantField(db)
+	if !ok {
+		return
+	}
+	if _, applied := db.Statement.Settings.Load(appliedKey); applied {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: id},
+	}})
+	db.Statement.Settings.Store(appliedKey, true)
+}
+
+// assignTenant 创建时未指定租户的记录写入当前租户
+func assignTenant(db *gorm.DB) {
+	field, id, ok := tenantField(db)
+	if !ok {
+		return
+	}
+	ctx := db.Statement.Context
+	assign := func(rv reflect.Value) {
+		if _, zero := field.ValueOf(ctx, rv); zero {
+			_ = field.Set(ctx, rv, id)
+		}
+	}
+	rv := db.Statement.ReflectValue
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if elem := reflect.Indirect(rv.Index(i)); elem.Kind() == reflect.Struct {
+				assign(elem)
+			}
+		}
+	case reflect.Struct:
+		assign(rv)
+	}
+}
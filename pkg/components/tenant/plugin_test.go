@@ -0,0 +1,102 @@
+package tenant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type scopedModel struct {
+	ID       uint
+	TenantID uint
+	Name     string
+}
+
+type globalModel struct {
+	ID   uint
+	Name string
+}
+
+func setupDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(Plugin{}))
+	require.NoError(t, db.AutoMigrate(&scopedModel{}, &globalModel{}))
+	return db
+}
+
+func TestPlugin_CreateAssignsTenant(t *testing.T) {
+	db := setupDB(t)
+	ctx := WithID(context.Background(), 5)
+
+	one := scopedModel{Name: "a"}
+	require.NoError(t, db.WithContext(ctx).Create(&one).Error)
+	assert.Equal(t, uint(5), one.TenantID)
+
+	batch := []scopedModel{{Name: "b"}, {Name: "c", TenantID: 7}}
+	require.NoError(t, db.WithContext(ctx).Create(&batch).Error)
+	assert.Equal(t, uint(5), batch[0].TenantID)
+	assert.Equal(t, uint(7), batch[1].TenantID, "显式指定的租户不覆盖")
+}
+
+func TestPlugin_QueryUpdateDeleteScoped(t *testing.T) {
+	db := setupDB(t)
+	require.NoError(t, db.Create(&[]scopedModel{{TenantID: 1, Name: "a"}, {TenantID: 2, Name: "a"}}).Error)
+	ctx1 := WithID(context.Background(), 1)
+
+	var list []scopedModel
+	require.NoError(t, db.WithContext(ctx1).Where("name = ?", "a").Find(&list).Error)
+	require.Len(t, list, 1)
+	assert.Equal(t, uint(1), list[0].TenantID)
+
+	// Count 后复用同一个 Statement 查询列表
+	var total int64
+	q := db.WithContext(ctx1).Model(&scopedModel{})
+	require.NoError(t, q.Count(&total).Error)
+	require.NoError(t, q.Find(&list).Error)
+	assert.Equal(t, int64(1), total)
+	assert.Len(t, list, 1)
+
+	var exists bool
+	require.NoError(t, db.WithContext(ctx1).Model(&scopedModel{}).Select("1").Where("id = ?", 2).Limit(1).Scan(&exists).Error)
+	assert.False(t, exists, "其他租户的记录不可见")
+
+	res := db.WithContext(ctx1).Model(&scopedModel{}).Where("id = ?", 2).Update("name", "x")
+	require.NoError(t, res.Error)
+	assert.Zero(t, res.RowsAffected)
+
+	res = db.WithContext(ctx1).Where("id = ?", 2).Delete(&scopedModel{})
+	require.NoError(t, res.Error)
+	assert.Zero(t, res.RowsAffected)
+
+	var all int64
+	require.NoError(t, db.Model(&scopedModel{}).Count(&all).Error)
+	assert.Equal(t, int64(2), all, "没有租户的上下文不做隔离")
+
+	require.NoError(t, db.WithContext(SkipScope(ctx1)).Model(&scopedModel{}).Count(&all).Error)
+	assert.Equal(t, int64(2), all, "SkipScope 跨租户查询")
+}
+
+func TestPlugin_GlobalModelUnaffected(t *testing.T) {
+	db := setupDB(t)
+	require.NoError(t, db.Create(&globalModel{Name: "g"}).Error)
+	var list []globalModel
+	require.NoError(t, db.WithContext(WithID(context.Background(), 3)).Find(&list).Error)
+	assert.Len(t, list, 1)
+}
+
+func TestNamespaceAndScoped(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, "", Namespace(ctx))
+	assert.Equal(t, "t0:", Namespace(WithID(ctx, PlatformID)))
+	assert.Equal(t, "t9:", Namespace(WithID(ctx, 9)))
+	assert.Equal(t, "", Namespace(SkipScope(WithID(ctx, 9))))
+
+	assert.True(t, Scoped(scopedModel{}))
+	assert.True(t, Scoped(&[]scopedModel{}))
+	assert.False(t, Scoped(&globalModel{}))
+}
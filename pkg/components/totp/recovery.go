@@ -0,0 +1,48 @@
+package totp
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/11 下午2:30
+* @Package: 恢复码 - 丢失验证器时的一次性备用口令
+ */
+
+// recoveryAlphabet 去掉了容易混淆的 0/o、1/l/i
+const recoveryAlphabet = "23456789abcdefghjkmnpqrstuvwxyz"
+
+// GenerateRecoveryCodes 生成 n 个 xxxxx-xxxxx 格式的恢复码
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	// 丢弃超出字母表整数倍的字节，避免取模偏差
+	limit := byte(256 - 256%len(recoveryAlphabet))
+	codes := make([]string, 0, n)
+	buf := make([]byte, 1)
+	for i := 0; i < n; i++ {
+		var sb strings.Builder
+		for sb.Len() < 11 {
+			if sb.Len() == 5 {
+				sb.WriteByte('-')
+				continue
+			}
+			if _, err := rand.Read(buf); err != nil {
+				return nil, err
+			}
+			if buf[0] >= limit {
+				continue
+			}
+			sb.WriteByte(recoveryAlphabet[int(buf[0])%len(recoveryAlphabet)])
+		}
+		codes = append(codes, sb.String())
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode 统一用户输入的恢复码格式（忽略大小写、空格和连字符）
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,84 @@
+package totp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/19 上午10:00
+* @Package: TOTP 密钥加密存储（AES-256-GCM）
+ */
+
+// sealedPrefix 加密后密钥的前缀，没有该前缀的视为未加密的历史数据
+const sealedPrefix = "enc:v1:"
+
+var ErrSealedSecret = errors.New("totp: cannot decrypt secret")
+
+// SecretBox 加解密数据库中保存的 TOTP 密钥
+// 未配置加密密钥时原样保存，读取时兼容加密前写入的明文密钥
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox 由配置的密钥派生 AES-256 密钥，key 为空时不加密
+func NewSecretBox(key string) (*SecretBox, error) {
+	if key == "" {
+		return &SecretBox{}, nil
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+// Enabled 是否配置了加密密钥
+func (b *SecretBox) Enabled() bool {
+	return b.aead != nil
+}
+
+// Seal 加密密钥，未配置加密密钥时原样返回
+func (b *SecretBox) Seal(secret string) (string, error) {
+	if b.aead == nil {
+		return secret, nil
+	}
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(secret), nil)
+	return sealedPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open 解密密钥，未加密的密钥原样返回
+func (b *SecretBox) Open(stored string) (string, error) {
+	if !strings.HasPrefix(stored, sealedPrefix) {
+		return stored, nil
+	}
+	if b.aead == nil {
+		return "", ErrSealedSecret
+	}
+	data, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(stored, sealedPrefix))
+	if err != nil || len(data) < b.aead.NonceSize() {
+		return "", ErrSealedSecret
+	}
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	plain, err := b.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrSealedSecret
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,117 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 6238 附录 B 的 SHA1 测试向量
+func TestCodeRFC6238(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	opts := Options{Digits: 8, Period: 30 * time.Second}
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		got, err := opts.Code(secret, opts.Counter(time.Unix(tt.unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got, "time %d", tt.unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	now := time.Now()
+	opts := DefaultOptions
+
+	code, err := opts.Code(secret, opts.Counter(now))
+	require.NoError(t, err)
+	counter, ok := opts.Validate(secret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, opts.Counter(now), counter)
+
+	// 允许一个时间步的时钟误差
+	prev, err := opts.Code(secret, opts.Counter(now)-1)
+	require.NoError(t, err)
+	_, ok = opts.Validate(secret, prev, now)
+	assert.True(t, ok)
+
+	old, err := opts.Code(secret, opts.Counter(now)-3)
+	require.NoError(t, err)
+	_, ok = opts.Validate(secret, old, now)
+	assert.False(t, ok)
+
+	_, ok = opts.Validate(secret, "12345", now)
+	assert.False(t, ok)
+	_, ok = opts.Validate("!invalid!", "123456", now)
+	assert.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	uri := DefaultOptions.URI("gin admin", "john@example.com", "JBSWY3DPEHPK3PXP")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/gin%20admin:john@example.com?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=gin+admin")
+	assert.Contains(t, uri, "digits=6")
+	assert.Contains(t, uri, "period=30")
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	require.NoError(t, err)
+	assert.Len(t, codes, 10)
+	seen := map[string]bool{}
+	for _, code := range codes {
+		assert.Len(t, code, 11)
+		assert.Equal(t, byte('-'), code[5])
+		assert.False(t, seen[code])
+		seen[code] = true
+	}
+	assert.Equal(t, "abcde23456", NormalizeRecoveryCode(" ABCDE-23456 "))
+}
+
+func TestSecretBox(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+
+	box, err := NewSecretBox("mfa-encryption-key")
+	require.NoError(t, err)
+	sealed, err := box.Seal(secret)
+	require.NoError(t, err)
+	assert.NotContains(t, sealed, secret)
+	got, err := box.Open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, secret, got)
+
+	// 加密前写入的明文密钥仍可读取
+	got, err = box.Open(secret)
+	require.NoError(t, err)
+	assert.Equal(t, secret, got)
+
+	// 密钥错误或未配置密钥时无法解密
+	other, err := NewSecretBox("another-key")
+	require.NoError(t, err)
+	_, err = other.Open(sealed)
+	assert.ErrorIs(t, err, ErrSealedSecret)
+	plain, err := NewSecretBox("")
+	require.NoError(t, err)
+	_, err = plain.Open(sealed)
+	assert.ErrorIs(t, err, ErrSealedSecret)
+	stored, err := plain.Seal(secret)
+	require.NoError(t, err)
+	assert.Equal(t, secret, stored)
+}
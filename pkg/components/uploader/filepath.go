@@ -44,7 +44,11 @@ func (fp FilePath) String() string {
 }
 
 // url 使用全局上传器实例，支持不同类型的上传器（local/OSS/S3等）
+// 未初始化上传器时原样返回相对路径
 func (fp FilePath) url() string {
+	if uploader == nil {
+		return string(fp)
+	}
 	return uploader.GetURL(string(fp))
 }
 
@@ -0,0 +1,34 @@
+package consts
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/10 上午10:05
+* @Package: 安全事件枚举
+ */
+
+// SecurityEventType 安全事件类型
+type SecurityEventType string
+
+const (
+	SecurityEventRefreshTokenReuse  SecurityEventType = "refresh_token_reuse"  // refresh token 重用（疑似被盗用）
+	SecurityEventImpersonationStart SecurityEventType = "impersonation_start"  // 管理员开始模拟登录
+	SecurityEventImpersonatedAccess SecurityEventType = "impersonated_request" // 模拟登录期间发起的请求
+)
+
+func (t SecurityEventType) String() string {
+	switch t {
+	case SecurityEventRefreshTokenReuse:
+		return "刷新令牌重用"
+	case SecurityEventImpersonationStart:
+		return "模拟登录"
+	case SecurityEventImpersonatedAccess:
+		return "模拟登录操作"
+	default:
+		return "未知"
+	}
+}
+
+func AllSecurityEventTypes() []SecurityEventType {
+	return []SecurityEventType{SecurityEventRefreshTokenReuse, SecurityEventImpersonationStart, SecurityEventImpersonatedAccess}
+}
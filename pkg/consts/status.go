@@ -29,6 +29,84 @@ func AllRoleStatus() []RoleStatus {
 	return []RoleStatus{ROLESTATUS_ACTIVE, ROLESTATUS_INACTIVE}
 }
 
+// DataScope 角色的数据权限范围，用户拥有多个角色时取并集
+type DataScope uint8
+
+const (
+	DataScopeUnknown         DataScope = iota
+	DataScopeAll                       // 全部数据
+	DataScopeCustom                    // 自定义部门
+	DataScopeDeptAndChildren           // 本部门及下级部门
+	DataScopeDept                      // 本部门
+	DataScopeSelf                      // 仅本人
+)
+
+func (s DataScope) String() string {
+	switch s {
+	case DataScopeAll:
+		return "全部数据"
+	case DataScopeCustom:
+		return "自定义部门"
+	case DataScopeDeptAndChildren:
+		return "本部门及下级部门"
+	case DataScopeDept:
+		return "本部门"
+	case DataScopeSelf:
+		return "仅本人"
+	}
+	return "未知"
+}
+
+func AllDataScope() []DataScope {
+	return []DataScope{DataScopeAll, DataScopeCustom, DataScopeDeptAndChildren, DataScopeDept, DataScopeSelf}
+}
+
+// DeptStatus 部门状态
+type DeptStatus uint8
+
+const (
+	DeptStatusUnknown  DeptStatus = iota
+	DeptStatusActive              // 启用
+	DeptStatusDisabled            // 禁用
+)
+
+func (s DeptStatus) String() string {
+	switch s {
+	case DeptStatusActive:
+		return "启用"
+	case DeptStatusDisabled:
+		return "禁用"
+	}
+	return "invalid"
+}
+
+func AllDeptStatus() []DeptStatus {
+	return []DeptStatus{DeptStatusActive, DeptStatusDisabled}
+}
+
+// TenantStatus 租户状态
+type TenantStatus uint8
+
+const (
+	TenantStatusUnknown  TenantStatus = iota
+	TenantStatusActive                // 启用
+	TenantStatusDisabled              // 禁用：租户下的所有账号无法访问
+)
+
+func (s TenantStatus) String() string {
+	switch s {
+	case TenantStatusActive:
+		return "启用"
+	case TenantStatusDisabled:
+		return "禁用"
+	}
+	return "invalid"
+}
+
+func AllTenantStatus() []TenantStatus {
+	return []TenantStatus{TenantStatusActive, TenantStatusDisabled}
+}
+
 // 用户状态
 type UserStatus uint8
 
@@ -37,6 +115,7 @@ const (
 	UserStatusActive              // 启用：允许登录
 	UserStatusDisabled            // 禁用：禁止登录
 	UserStatusLocked              // 锁定：异常登录或安全封禁
+	UserStatusPending             // 待验证：注册后需验证邮箱才能登录
 )
 
 func (s UserStatus) String() string {
@@ -47,11 +126,37 @@ func (s UserStatus) String() string {
 		return "禁用登录"
 	case UserStatusLocked:
 		return "锁定"
+	case UserStatusPending:
+		return "待验证邮箱"
 	default:
 		return "未知"
 	}
 }
 
 func AllUserStatus() []UserStatus {
-	return []UserStatus{UserStatusActive, UserStatusDisabled, UserStatusLocked}
+	return []UserStatus{UserStatusActive, UserStatusDisabled, UserStatusLocked, UserStatusPending}
+}
+
+// MenuStatus 菜单状态
+type MenuStatus uint8
+
+const (
+	MenuStatusUnknown  MenuStatus = iota
+	MenuStatusActive              // 启用
+	MenuStatusDisabled            // 禁用：菜单及其下级菜单不再返回给前端
+)
+
+func (s MenuStatus) String() string {
+	switch s {
+	case MenuStatusActive:
+		return "启用"
+	case MenuStatusDisabled:
+		return "禁用"
+	default:
+		return "未知"
+	}
+}
+
+func AllMenuStatus() []MenuStatus {
+	return []MenuStatus{MenuStatusActive, MenuStatusDisabled}
 }
@@ -20,26 +20,38 @@ const (
 	DatabaseConnFailed = 2003 // 数据库连接失败
 
 	// 缓存相关错误 (3000-3999)
-	CacheError     = 3000 // 缓存错误
+	CacheError       = 3000 // 缓存错误
 	CacheKeyNotFound = 3001 // 缓存键不存在
-	CacheSetFailed = 3002 // 缓存设置失败
+	CacheSetFailed   = 3002 // 缓存设置失败
 
 	// 认证授权相关错误 (4000-4999)
-	TokenExpired        = 4000 // Token过期
-	TokenInvalid        = 4001 // Token无效
-	TokenMissing        = 4002 // Token缺失
-	PermissionDenied    = 4003 // 权限不足
-	LoginFailed         = 4004 // 登录失败
-	UserNotFound        = 4005 // 用户不存在
-	UserAlreadyExist    = 4006 // 用户已存在
-	PasswordWrong       = 4007 // 密码错误
-	UserDisabled        = 4008 // 用户已被禁用
+	TokenExpired         = 4000 // Token过期
+	TokenInvalid         = 4001 // Token无效
+	TokenMissing         = 4002 // Token缺失
+	PermissionDenied     = 4003 // 权限不足
+	LoginFailed          = 4004 // 登录失败
+	UserNotFound         = 4005 // 用户不存在
+	UserAlreadyExist     = 4006 // 用户已存在
+	PasswordWrong        = 4007 // 密码错误
+	UserDisabled         = 4008 // 用户已被禁用
+	SessionEvicted       = 4009 // 账号已在其他设备登录，当前会话被挤下线
+	SessionLimitExceeded = 4010 // 在线设备数已达上限
+	UserLocked           = 4011 // 用户已被锁定
+	SessionIdleTimeout   = 4012 // 会话空闲超时
+	MFACodeInvalid       = 4013 // 两步验证码错误
+	MFAChallengeExpired  = 4014 // 两步验证凭证失效（过期或错误次数过多）
+	APIKeyInvalid        = 4015 // API Key 无效或已过期
+	CSRFTokenInvalid     = 4016 // CSRF Token 校验失败
+	EmailNotVerified     = 4017 // 邮箱未验证
+	TenantInvalid        = 4018 // 租户不存在
+	TenantDisabled       = 4019 // 租户已被禁用
+	TenantForbidden      = 4020 // 无权访问该租户
 
 	// 业务相关错误 (10000+)
-	RoleNotFound        = 10000 // 角色不存在
-	RoleAlreadyExist    = 10001 // 角色已存在
-	PermissionNotFound  = 10002 // 权限不存在
-	ResourceNotFound    = 10003 // 资源不存在
+	RoleNotFound       = 10000 // 角色不存在
+	RoleAlreadyExist   = 10001 // 角色已存在
+	PermissionNotFound = 10002 // 权限不存在
+	ResourceNotFound   = 10003 // 资源不存在
 )
 
 // Error 错误结构
@@ -70,26 +82,38 @@ var messages = map[int]string{
 	NotFound:           "资源不存在",
 	TooManyRequests:    "请求过于频繁",
 	ServiceUnavailable: "服务不可用",
-	
+
 	DatabaseError:      "数据库错误",
 	RecordNotFound:     "记录不存在",
 	RecordAlreadyExist: "记录已存在",
 	DatabaseConnFailed: "数据库连接失败",
-	
+
 	CacheError:       "缓存错误",
 	CacheKeyNotFound: "缓存键不存在",
 	CacheSetFailed:   "缓存设置失败",
-	
-	TokenExpired:     "Token已过期",
-	TokenInvalid:     "Token无效",
-	TokenMissing:     "缺少Token",
-	PermissionDenied: "权限不足",
-	LoginFailed:      "登录失败",
-	UserNotFound:     "用户不存在",
-	UserAlreadyExist: "用户已存在",
-	PasswordWrong:    "密码错误",
-	UserDisabled:     "用户已被禁用",
-	
+
+	TokenExpired:         "Token已过期",
+	TokenInvalid:         "Token无效",
+	TokenMissing:         "缺少Token",
+	PermissionDenied:     "权限不足",
+	LoginFailed:          "登录失败",
+	UserNotFound:         "用户不存在",
+	UserAlreadyExist:     "用户已存在",
+	PasswordWrong:        "密码错误",
+	UserDisabled:         "用户已被禁用",
+	SessionEvicted:       "账号已在其他设备登录",
+	SessionLimitExceeded: "登录设备数已达上限",
+	UserLocked:           "账号已被锁定",
+	SessionIdleTimeout:   "长时间未操作，请重新登录",
+	MFACodeInvalid:       "两步验证码错误",
+	MFAChallengeExpired:  "两步验证已失效，请重新登录",
+	APIKeyInvalid:        "API Key 无效或已过期",
+	CSRFTokenInvalid:     "请求校验失败，请刷新页面后重试",
+	EmailNotVerified:     "邮箱未验证，请先完成邮箱验证",
+	TenantInvalid:        "租户不存在",
+	TenantDisabled:       "租户已被禁用",
+	TenantForbidden:      "无权访问该租户",
+
 	RoleNotFound:       "角色不存在",
 	RoleAlreadyExist:   "角色已存在",
 	PermissionNotFound: "权限不存在",
@@ -119,4 +143,3 @@ func NewCustomError(code int, message string) *Error {
 		Message: message,
 	}
 }
-
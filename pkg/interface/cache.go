@@ -25,6 +25,9 @@ type ICache interface {
 	// 警告：如果 value 是指针/切片/map 等引用类型，外部修改会影响缓存！
 	// 传入的须是值类型，不要是指针
 	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	// SetNX 键不存在（或已过期）时存储缓存值，返回是否存储成功
+	// 判断与写入是原子的，过期时间随值一起写入，可用作带自动过期的互斥锁
+	SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error)
 	Delete(ctx context.Context, keys ...string) error
 	Exists(ctx context.Context, key string) (bool, error)
 
@@ -53,6 +56,15 @@ type ICache interface {
 	DeletePrefix(ctx context.Context, prefix string) error
 }
 
+// IncrWithTTL 递增计数器，计数器不存在时先以 ttl 创建
+// 过期时间与计数器同时写入，不会因为进程在 Incr 与 Expire 之间退出而留下永不过期的计数器
+func IncrWithTTL(ctx context.Context, cache ICache, key string, ttl time.Duration) (int64, error) {
+	if _, err := cache.SetNX(ctx, key, int64(0), ttl); err != nil {
+		return 0, err
+	}
+	return cache.Incr(ctx, key)
+}
+
 // Pipeline 管道操作接口
 type Pipeline interface {
 	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) StatusCmd
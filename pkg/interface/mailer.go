@@ -0,0 +1,25 @@
+package _interface
+
+import "context"
+
+/*
+* @Author: zouyx
+* @Email: 1003941268@qq.com
+* @Date:   2025/12/12 上午10:20
+* @Package: 邮件发送
+ */
+
+// MailMessage 邮件内容
+type MailMessage struct {
+	To      []string
+	Subject string
+	// Body 正文，HTML 为 true 时按 text/html 发送
+	Body string
+	HTML bool
+}
+
+// IMailer 邮件发送接口
+type IMailer interface {
+	// Send 发送邮件
+	Send(ctx context.Context, msg *MailMessage) error
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/md5"
 	"encoding/json"
 	"fmt"
+	"gin-admin/pkg/components/tenant"
 
 	"gorm.io/gorm"
 	"time"
@@ -30,27 +31,44 @@ type Service[T IModel] struct {
 	DB       *gorm.DB
 	cache    ICache
 	cacheTTL time.Duration // 缓存过期时间
+	// tenantScoped 模型按租户隔离（包含 TenantID 且注册了租户插件），缓存键按租户划分命名空间
+	tenantScoped bool
 }
 
 // NewService 创建 Service 实例
 func NewService[T IModel](db *gorm.DB, cache ICache) *Service[T] {
 	return &Service[T]{
-		Repo:     NewRepo[T](db),
-		cache:    cache,
-		DB:       db,
-		cacheTTL: 5 * time.Minute,
+		Repo:         NewRepo[T](db),
+		cache:        cache,
+		DB:           db,
+		cacheTTL:     5 * time.Minute,
+		tenantScoped: tenant.Scoped(new(T)) && tenantPluginEnabled(db),
 	}
 }
 
-// cacheKeyPrefix 缓存键前缀
-func (s *Service[T]) cacheKeyPrefix() string {
+// tenantPluginEnabled 是否注册了 GORM 租户插件
+func tenantPluginEnabled(db *gorm.DB) bool {
+	if db == nil || db.Config == nil {
+		return false
+	}
+	_, ok := db.Config.Plugins[tenant.Plugin{}.Name()]
+	return ok
+}
+
+// cacheKeyPrefix 缓存键前缀，租户隔离的模型带上当前租户：model:users:t1:
+// 没有租户的上下文前缀覆盖所有租户，只用于清理缓存
+func (s *Service[T]) cacheKeyPrefix(ctx context.Context) string {
 	model := new(T)
-	return fmt.Sprintf("model:%s:", (*model).TableName())
+	prefix := fmt.Sprintf("model:%s:", (*model).TableName())
+	if s.tenantScoped {
+		prefix += tenant.Namespace(ctx)
+	}
+	return prefix
 }
 
 // cacheKey 生成缓存键
-func (s *Service[T]) cacheKey(suffix string) string {
-	return fmt.Sprintf("%s%s", s.cacheKeyPrefix(), suffix)
+func (s *Service[T]) cacheKey(ctx context.Context, suffix string) string {
+	return fmt.Sprintf("%s%s", s.cacheKeyPrefix(ctx), suffix)
 }
 
 // getFromCache 从缓存获取数据
@@ -76,13 +94,23 @@ func (s *Service[T]) setToCache(ctx context.Context, key string, value interface
 	_ = s.cache.Set(ctx, key, value, s.cacheTTL)
 }
 
+// allTenants 租户隔离的模型在没有确定租户的上下文中写入（如平台后台任务），记录可能缓存在任意租户的命名空间下
+// 此时不带租户的前缀 model:users: 覆盖所有租户，失效时整体清除该模型的缓存
+func (s *Service[T]) allTenants(ctx context.Context) bool {
+	return s.tenantScoped && tenant.Namespace(ctx) == ""
+}
+
 // invalidateIDCache 使单个ID的缓存失效
 func (s *Service[T]) invalidateIDCache(ctx context.Context, id uint) {
 	if s.cache == nil {
 		return
 	}
+	if s.allTenants(ctx) {
+		_ = s.cache.DeletePrefix(ctx, s.cacheKeyPrefix(ctx))
+		return
+	}
 	// 删除该ID的所有查询缓存（不同选项可能有多个缓存键）
-	_ = s.cache.DeletePrefix(ctx, s.cacheKey(fmt.Sprintf("id:%d:", id)))
+	_ = s.cache.DeletePrefix(ctx, s.cacheKey(ctx, fmt.Sprintf("id:%d:", id)))
 }
 
 // invalidateListCache 使列表和分页缓存失效
@@ -90,9 +118,13 @@ func (s *Service[T]) invalidateListCache(ctx context.Context) {
 	if s.cache == nil {
 		return
 	}
-	_ = s.cache.DeletePrefix(ctx, s.cacheKey("list:"))
-	_ = s.cache.DeletePrefix(ctx, s.cacheKey("page:"))
-	_ = s.cache.DeletePrefix(ctx, s.cacheKey("one:"))
+	if s.allTenants(ctx) {
+		_ = s.cache.DeletePrefix(ctx, s.cacheKeyPrefix(ctx))
+		return
+	}
+	_ = s.cache.DeletePrefix(ctx, s.cacheKey(ctx, "list:"))
+	_ = s.cache.DeletePrefix(ctx, s.cacheKey(ctx, "page:"))
+	_ = s.cache.DeletePrefix(ctx, s.cacheKey(ctx, "one:"))
 }
 
 // ClearCache 清空该模型的所有缓存
@@ -100,27 +132,41 @@ func (s *Service[T]) ClearCache(ctx context.Context) error {
 	if s.cache == nil {
 		return nil
 	}
-	return s.cache.DeletePrefix(ctx, s.cacheKeyPrefix())
+	return s.cache.DeletePrefix(ctx, s.cacheKeyPrefix(ctx))
 }
 
 // serializeOpts 序列化查询选项为字符串
-func (s *Service[T]) serializeOpts(opts ...QueryOption) string {
+// Scopes 是函数，无法参与序列化，带 Scopes 的查询直接走数据库（返回 false），避免不同条件命中同一个缓存键
+// 租户隔离的模型在没有确定租户的上下文中查询时同样直接走数据库
+func (s *Service[T]) serializeOpts(ctx context.Context, opts ...QueryOption) (string, bool) {
+	if s.allTenants(ctx) {
+		return "", false
+	}
 	if len(opts) == 0 {
-		return "default"
+		return "default", true
 	}
 
 	options := ApplyQueryOptions(opts...)
-	data, _ := json.Marshal(options)
+	if len(options.Scopes) > 0 {
+		return "", false
+	}
+	data, err := json.Marshal(options)
+	if err != nil {
+		return "", false
+	}
 	hash := md5.Sum(data)
-	return fmt.Sprintf("%x", hash)
+	return fmt.Sprintf("%x", hash), true
 }
 
 // ==================== 查询操作（按需缓存）====================
 
 // FindByID 通过ID查询 - 缓存
 func (s *Service[T]) FindByID(ctx context.Context, id uint, opts ...QueryOption) (*T, error) {
-	optsKey := s.serializeOpts(opts...)
-	cacheKey := s.cacheKey(fmt.Sprintf("id:%d:%s", id, optsKey))
+	optsKey, cacheable := s.serializeOpts(ctx, opts...)
+	if !cacheable {
+		return s.Repo.FindByID(ctx, id, opts...)
+	}
+	cacheKey := s.cacheKey(ctx, fmt.Sprintf("id:%d:%s", id, optsKey))
 
 	// 尝试从缓存获取
 	var entity T
@@ -146,8 +192,11 @@ func (s *Service[T]) FindByIDs(ctx context.Context, ids []uint, opts ...QueryOpt
 
 // FindOne 条件查询单条
 func (s *Service[T]) FindOne(ctx context.Context, opts ...QueryOption) (*T, error) {
-	optsKey := s.serializeOpts(opts...)
-	cacheKey := s.cacheKey(fmt.Sprintf("one:%s", optsKey))
+	optsKey, cacheable := s.serializeOpts(ctx, opts...)
+	if !cacheable {
+		return s.Repo.FindOne(ctx, opts...)
+	}
+	cacheKey := s.cacheKey(ctx, fmt.Sprintf("one:%s", optsKey))
 
 	var entity T
 	if s.getFromCache(ctx, cacheKey, &entity) {
@@ -165,8 +214,11 @@ func (s *Service[T]) FindOne(ctx context.Context, opts ...QueryOption) (*T, erro
 
 // List 列表查询
 func (s *Service[T]) List(ctx context.Context, opts ...QueryOption) ([]T, error) {
-	optsKey := s.serializeOpts(opts...)
-	cacheKey := s.cacheKey(fmt.Sprintf("list:%s", optsKey))
+	optsKey, cacheable := s.serializeOpts(ctx, opts...)
+	if !cacheable {
+		return s.Repo.List(ctx, opts...)
+	}
+	cacheKey := s.cacheKey(ctx, fmt.Sprintf("list:%s", optsKey))
 
 	var list []T
 	if s.getFromCache(ctx, cacheKey, &list) {
@@ -184,8 +236,11 @@ func (s *Service[T]) List(ctx context.Context, opts ...QueryOption) ([]T, error)
 
 // FindPage 分页查询
 func (s *Service[T]) FindPage(ctx context.Context, opts ...QueryOption) (*PageResult[T], error) {
-	optsKey := s.serializeOpts(opts...)
-	cacheKey := s.cacheKey(fmt.Sprintf("page:%s", optsKey))
+	optsKey, cacheable := s.serializeOpts(ctx, opts...)
+	if !cacheable {
+		return s.Repo.FindPage(ctx, opts...)
+	}
+	cacheKey := s.cacheKey(ctx, fmt.Sprintf("page:%s", optsKey))
 
 	var pageResult PageResult[T]
 	if s.getFromCache(ctx, cacheKey, &pageResult) {
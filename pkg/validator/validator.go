@@ -1,17 +1,45 @@
 package validator
 
 import (
+	"errors"
 	"fmt"
+	"gin-admin/pkg/components/password"
 	"gin-admin/pkg/response"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 	"strings"
+	"sync/atomic"
 )
 
 var validate *validator.Validate
 
+// passwordPolicy password 标签使用的密码策略
+var passwordPolicy atomic.Pointer[password.Policy]
+
 func init() {
 	validate = validator.New()
+	passwordPolicy.Store(password.NewPolicy(password.PolicyConfig{}))
+	// 同时注册到 gin 的校验器，binding 标签也可以使用
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		_ = v.RegisterValidation("password", validatePassword)
+	}
+	_ = validate.RegisterValidation("password", validatePassword)
+}
+
+// SetPasswordPolicy 设置 password 标签使用的密码策略，启动时根据配置调用
+func SetPasswordPolicy(policy *password.Policy) {
+	passwordPolicy.Store(policy)
+}
+
+// PasswordPolicy 当前的密码策略
+func PasswordPolicy() *password.Policy {
+	return passwordPolicy.Load()
+}
+
+// validatePassword 密码需符合密码策略
+func validatePassword(fl validator.FieldLevel) bool {
+	return PasswordPolicy().Validate(fl.Field().String()) == nil
 }
 
 // ValidateStruct 验证结构体
@@ -46,6 +74,15 @@ func BindAndValidateWithResponse(c *gin.Context, obj interface{}) bool {
 	return true
 }
 
+// ErrorMessage 将参数校验错误转换为可读的提示，其他错误原样返回
+func ErrorMessage(err error) string {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		return formatValidationErrors(validationErrors)
+	}
+	return err.Error()
+}
+
 // formatValidationErrors 格式化验证错误信息
 func formatValidationErrors(errs validator.ValidationErrors) string {
 	var messages []string
@@ -71,6 +108,11 @@ func formatFieldError(err validator.FieldError) string {
 		return fmt.Sprintf("%s长度必须为%s", field, err.Param())
 	case "oneof":
 		return fmt.Sprintf("%s必须是以下值之一: %s", field, err.Param())
+	case "password":
+		if e := PasswordPolicy().Validate(fmt.Sprint(err.Value())); e != nil {
+			return e.Error()
+		}
+		return fmt.Sprintf("%s不符合密码策略", field)
 	default:
 		return fmt.Sprintf("%s验证失败: %s", field, err.Tag())
 	}
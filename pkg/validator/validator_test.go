@@ -7,6 +7,8 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"gin-admin/pkg/components/password"
+
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
@@ -88,3 +90,28 @@ func TestBindAndValidate(t *testing.T) {
 	}
 }
 
+type passwordRequest struct {
+	Password string `json:"password" binding:"required,password"`
+}
+
+func TestPasswordTag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetPasswordPolicy(password.NewPolicy(password.PolicyConfig{MinLength: 10, RequireDigit: true}))
+	defer SetPasswordPolicy(password.NewPolicy(password.PolicyConfig{}))
+
+	bind := func(pw string) error {
+		jsonData, _ := json.Marshal(passwordRequest{Password: pw})
+		req, _ := http.NewRequest("POST", "/test", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = req
+		var data passwordRequest
+		return c.ShouldBindJSON(&data)
+	}
+
+	assert.NoError(t, bind("long enough 1"))
+	err := bind("short")
+	assert.Error(t, err)
+	assert.Equal(t, "密码长度不能少于 10 位，需包含数字", ErrorMessage(err))
+	assert.Error(t, bind("password123"), "常见密码")
+}